@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+const (
+	// AddrPolicyAllow 允许该类地址
+	AddrPolicyAllow = "allow"
+	// AddrPolicyReject 拒绝该类地址
+	AddrPolicyReject = "reject"
+)
+
+// externalGetTypes 地址来自外部的获取方式, 返回私有地址通常是被劫持或代理导致的, 默认拒绝
+var externalGetTypes = map[string]bool{"url": true, "stun": true, "dns": true}
+
+// bogonNets 不应出现在公网DNS中的保留地址, 私有地址、CGNAT地址单独判断
+// 不包含 ::ffff:0:0/96, net.IP 中的IPv4地址均为该形式
+var bogonNets = parseCIDRList(
+	"0.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "192.0.0.0/24", "192.0.2.0/24",
+	"198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "100::/64", "2001:db8::/32", "fe80::/10", "fec0::/10", "ff00::/8",
+)
+
+func parseCIDRList(cidrs ...string) (nets []*net.IPNet) {
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return
+}
+
+// parseCIDRs 解析逗号分隔的网段, 单个地址视为 /32 或 /128
+func parseCIDRs(s string) (nets []*net.IPNet, err error) {
+	for _, item := range util.SplitComma(s) {
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid CIDR %q", item)
+			}
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			n = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		}
+		nets = append(nets, n)
+	}
+	return
+}
+
+// CheckAddrFilter 检查地址校验规则是否有效
+func (conf *DnsConfig) CheckAddrFilter() error {
+	for _, policy := range []string{conf.AddrFilter.Private, conf.AddrFilter.CGNAT} {
+		if policy != "" && policy != AddrPolicyAllow && policy != AddrPolicyReject {
+			return fmt.Errorf("invalid policy %q", policy)
+		}
+	}
+	if _, err := parseCIDRs(conf.AddrFilter.Allow); err != nil {
+		return err
+	}
+	_, err := parseCIDRs(conf.AddrFilter.Deny)
+	return err
+}
+
+// addrClass 地址的类型, 不属于以下类型时为空
+func addrClass(ip net.IP) string {
+	switch {
+	case util.IsCGNAT(ip):
+		return "CGNAT"
+	case ip.IsPrivate():
+		return "private"
+	case containsIP(bogonNets, ip):
+		return "bogon"
+	}
+	return ""
+}
+
+// filterAddrs 按校验规则过滤获取到的地址, 并返回其中的运营商级NAT地址
+func (conf *DnsConfig) filterAddrs(addrType, getType string, addrs []string) (result []string, cgnatAddr string) {
+	allow, _ := parseCIDRs(conf.AddrFilter.Allow)
+	deny, _ := parseCIDRs(conf.AddrFilter.Deny)
+	rejectByDefault := externalGetTypes[getType]
+	rejects := func(policy string) bool {
+		return policy == AddrPolicyReject || (policy == "" && rejectByDefault)
+	}
+
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		class := addrClass(ip)
+		if class == "CGNAT" && cgnatAddr == "" {
+			cgnatAddr = addr
+		}
+
+		switch {
+		case containsIP(deny, ip):
+			util.Log("%s地址 %s 在拒绝的网段中, 已忽略", addrType, addr)
+		case containsIP(allow, ip):
+			result = append(result, addr)
+		case class == "CGNAT" && rejects(conf.AddrFilter.CGNAT):
+			util.Log("%s地址 %s 是运营商级NAT地址, 已忽略. 可在地址校验中允许", addrType, addr)
+		case (class == "private" || class == "bogon") && rejects(conf.AddrFilter.Private):
+			util.Log("%s地址 %s 是私有或保留地址, 已忽略. 可在地址校验中允许", addrType, addr)
+		default:
+			result = append(result, addr)
+		}
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterAddrs(t *testing.T) {
+	addrs := []string{"10.0.0.1", "100.64.1.1", "192.0.2.1", "1.1.1.1", "fd00::1", "fe80::1", "2001:4860::1"}
+	tests := []struct {
+		name      string
+		getType   string
+		private   string
+		cgnat     string
+		allow     string
+		deny      string
+		want      []string
+		wantCGNAT string
+	}{
+		{"external rejects by default", "url", "", "", "", "", []string{"1.1.1.1", "2001:4860::1"}, "100.64.1.1"},
+		{"local allows by default", "netInterface", "", "", "", "", addrs, "100.64.1.1"},
+		{"reject local", "netInterface", "reject", "reject", "", "", []string{"1.1.1.1", "2001:4860::1"}, "100.64.1.1"},
+		{"allow cgnat only", "stun", "", "allow", "", "", []string{"100.64.1.1", "1.1.1.1", "2001:4860::1"}, "100.64.1.1"},
+		{"allow list", "url", "", "", "10.0.0.0/8, fd00::1", "", []string{"10.0.0.1", "1.1.1.1", "fd00::1", "2001:4860::1"}, "100.64.1.1"},
+		{"deny wins", "netInterface", "", "", "1.1.1.1", "1.1.1.0/24,2001:4860::/32", []string{"10.0.0.1", "100.64.1.1", "192.0.2.1", "fd00::1", "fe80::1"}, "100.64.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &DnsConfig{}
+			conf.AddrFilter.Private, conf.AddrFilter.CGNAT = tt.private, tt.cgnat
+			conf.AddrFilter.Allow, conf.AddrFilter.Deny = tt.allow, tt.deny
+			if err := conf.CheckAddrFilter(); err != nil {
+				t.Fatal(err)
+			}
+			got, cgnat := conf.filterAddrs("IP", tt.getType, addrs)
+			if !reflect.DeepEqual(got, tt.want) || cgnat != tt.wantCGNAT {
+				t.Errorf("got %v %q, want %v %q", got, cgnat, tt.want, tt.wantCGNAT)
+			}
+		})
+	}
+
+	conf := &DnsConfig{}
+	conf.AddrFilter.Deny = "10.0.0.0/33"
+	if conf.CheckAddrFilter() == nil {
+		t.Error("invalid CIDR should be rejected")
+	}
+	conf.AddrFilter.Deny, conf.AddrFilter.CGNAT = "", "maybe"
+	if conf.CheckAddrFilter() == nil {
+		t.Error("invalid policy should be rejected")
+	}
+}
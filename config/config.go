@@ -1,11 +1,15 @@
 package config
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
-	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"regexp"
@@ -13,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jeessy2/ddns-go/v6/util"
 	passwordvalidator "github.com/wagslane/go-password-validator"
@@ -33,29 +38,97 @@ type DnsConfig struct {
 	Name string
 	Ipv4 struct {
 		Enable bool
-		// 获取IP类型 url/netInterface
-		GetType      string
-		URL          string
+		// 获取IP类型 url/netInterface/cmd/stun/dns/router/fritzbox
+		GetType string
+		URL     string
+		// 同时请求全部接口, 至少多少个接口返回相同的地址才采用, 为空或1则使用第一个响应的接口
+		URLQuorum    string
 		NetInterface string
 		Cmd          string
-		Domains      []string
+		// STUN服务器, 多个用逗号分隔
+		Stun string
+		// 通过DNS查询公网IP的模板 name[/type[/class]]@server, 多个用逗号分隔
+		DNSQuery string
+		Domains  []string
+		// 获取IP失败时的备用地址, StaleAction 为 fallback 时使用
+		Fallback string
+		// 使用获取到的全部地址, DNS服务商中的记录与之保持一致
+		MultiAddr bool
 	}
 	Ipv6 struct {
 		Enable bool
-		// 获取IP类型 url/netInterface
-		GetType      string
-		URL          string
+		// 获取IP类型 url/netInterface/cmd/stun/dns/fritzbox
+		GetType string
+		URL     string
+		// 同时请求全部接口, 至少多少个接口返回相同的地址才采用, 为空或1则使用第一个响应的接口
+		URLQuorum    string
 		NetInterface string
 		Cmd          string
-		Ipv6Reg      string // ipv6匹配正则表达式
-		Domains      []string
+		// STUN服务器, 多个用逗号分隔
+		Stun string
+		// 通过DNS查询公网IP的模板 name[/type[/class]]@server, 多个用逗号分隔
+		DNSQuery string
+		Ipv6Reg  string // ipv6匹配正则表达式
+		// 网卡IPv6地址的选择策略, 多个用逗号分隔: stable, longest, eui64, noula, cidr=<前缀>
+		Select  string
+		Domains []string
+		// 获取IP失败时的备用地址, StaleAction 为 fallback 时使用
+		Fallback string
+		// 使用获取到的全部地址, DNS服务商中的记录与之保持一致
+		MultiAddr bool
 	}
 	DNS DNS
 	TTL string
+	// FRITZ!Box TR-064 的地址和账号, 获取IP类型为 fritzbox 时使用
+	Fritzbox struct {
+		// 为空则使用 http://fritz.box:49000
+		URL      string
+		Username string
+		Password string
+	}
 	// 发送HTTP请求时使用的网卡名称，为空则使用默认网卡
 	HttpInterface string
+	// 单次更新的超时时间(秒)，为空则使用默认值
+	Timeout string
+	// 连续多少次获取IP失败后处理旧记录，为空或0则不处理
+	StaleTimes string
+	// 旧记录的处理方式 delete/fallback，为空则删除
+	StaleAction string
+	// 新地址连续获取到多少次后才更新，为空或不大于1则立即更新
+	StableTimes string
+	// 新地址持续多少秒后才更新，为空或0则不等待；与 StableTimes 满足其一即可
+	StableSeconds string
+	// 更新计划，间隔(如 60s、1h)或 cron 表达式，为空则使用 -f 的同步间隔
+	Schedule string
+	// 更新成功后在权威DNS中验证记录是否生效
+	VerifyPublish bool
+	// 请求DNS服务商前先查询域名当前解析的地址, 一致时跳过
+	Precheck bool
+	// 预检查使用的DNS服务器，为空则查询域名的权威DNS
+	PrecheckResolver string
+	// 获取到的地址的校验规则
+	AddrFilter struct {
+		// 私有地址和保留地址 allow/reject，为空则拒绝来自接口/STUN/DNS的地址，升级前的配置为 allow
+		Private string
+		// 运营商级NAT地址 100.64.0.0/10 allow/reject，为空则拒绝来自接口/STUN/DNS的地址，升级前的配置为 allow
+		CGNAT string
+		// 允许的网段，多个用逗号分隔，不受以上规则限制
+		Allow string
+		// 拒绝的网段，多个用逗号分隔，优先于允许的网段
+		Deny string
+	}
 }
 
+const (
+	// StaleActionDelete 删除旧记录
+	StaleActionDelete = "delete"
+	// StaleActionFallback 将旧记录修改为备用地址
+	StaleActionFallback = "fallback"
+)
+
+// DefaultTimeout 单次更新的默认超时时间
+const DefaultTimeout = 2 * time.Minute
+
 // DNS DNS配置
 type DNS struct {
 	// 名称。如：alidns,webhook
@@ -130,9 +203,17 @@ func GetConfigCached() (conf Config, err error) {
 
 // CompatibleConfig 兼容之前的配置文件
 func (conf *Config) CompatibleConfig() {
+	conf.compatibleConfig(true)
+}
 
+// CompatibleConfigInMemory 与 CompatibleConfig 相同, 但只修改内存中的配置, 不保存配置文件, 用于 dry-run
+func (conf *Config) CompatibleConfigInMemory() {
+	conf.compatibleConfig(false)
+}
+
+func (conf *Config) compatibleConfig(save bool) {
 	// 如果之前密码不为空且不是bcrypt加密后的密码, 把密码加密并保存
-	if conf.Password != "" && !util.IsHashedPassword(conf.Password) {
+	if save && conf.Password != "" && !util.IsHashedPassword(conf.Password) {
 		hashedPwd, err := util.HashPassword(conf.Password)
 		if err == nil {
 			conf.Password = hashedPwd
@@ -140,6 +221,18 @@ func (conf *Config) CompatibleConfig() {
 		}
 	}
 
+	// 地址校验加入前的配置, 保持之前不校验地址的行为
+	if conf.compatibleAddrFilter() {
+		util.Log("已为升级前的配置设置为允许私有地址和运营商级NAT地址, 可在地址校验中修改")
+		if save {
+			conf.SaveConfig()
+		} else {
+			cache.Lock.Lock()
+			cache.ConfigSingle = conf
+			cache.Lock.Unlock()
+		}
+	}
+
 	// 兼容v5.0.0之前的配置文件
 	if len(conf.DnsConf) > 0 {
 		return
@@ -161,6 +254,7 @@ func (conf *Config) CompatibleConfig() {
 		return
 	}
 	if len(dnsConf.DNS.Name) > 0 {
+		dnsConf.AddrFilter.Private, dnsConf.AddrFilter.CGNAT = AddrPolicyAllow, AddrPolicyAllow
 		cache.Lock.Lock()
 		defer cache.Lock.Unlock()
 		conf.DnsConf = append(conf.DnsConf, *dnsConf)
@@ -168,6 +262,31 @@ func (conf *Config) CompatibleConfig() {
 	}
 }
 
+// compatibleAddrFilter 配置文件中没有 AddrFilter 的配置是地址校验加入前保存的, 将私有地址和CGNAT地址设置为允许
+func (conf *Config) compatibleAddrFilter() bool {
+	byt, err := os.ReadFile(util.GetConfigFilePath())
+	if err != nil {
+		return false
+	}
+	var raw struct {
+		DnsConf []map[string]any `yaml:"dnsconf"`
+	}
+	if yaml.Unmarshal(byt, &raw) != nil || len(raw.DnsConf) != len(conf.DnsConf) {
+		return false
+	}
+
+	changed := false
+	for i := range conf.DnsConf {
+		if _, ok := raw.DnsConf[i]["addrfilter"]; ok {
+			continue
+		}
+		conf.DnsConf[i].AddrFilter.Private = AddrPolicyAllow
+		conf.DnsConf[i].AddrFilter.CGNAT = AddrPolicyAllow
+		changed = true
+	}
+	return changed
+}
+
 // SaveConfig 保存配置
 func (conf *Config) SaveConfig() (err error) {
 	cache.Lock.Lock()
@@ -231,60 +350,52 @@ func (conf *Config) CheckPassword(newPassword string) (hashedPwd string, err err
 	return
 }
 
-func (conf *DnsConfig) getIpv4AddrFromInterface() string {
+// Key 配置的摘要, 配置被修改后随之改变
+func (conf *DnsConfig) Key() string {
+	byt, _ := json.Marshal(conf)
+	sum := sha256.Sum256(byt)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (conf *DnsConfig) getIpv4AddrsFromInterface() []string {
 	ipv4, _, err := GetNetInterface()
 	if err != nil {
 		util.Log("从网卡获得IPv4失败")
-		return ""
+		return nil
 	}
 
 	for _, netInterface := range ipv4 {
 		if netInterface.Name == conf.Ipv4.NetInterface && len(netInterface.Address) > 0 {
-			return netInterface.Address[0]
+			return netInterface.Address
 		}
 	}
 
 	util.Log("从网卡中获得IPv4失败! 网卡名: %s", conf.Ipv4.NetInterface)
-	return ""
+	return nil
 }
 
-func (conf *DnsConfig) getIpv4AddrFromUrl() string {
-	client := util.CreateBoundNoProxyHTTPClient("tcp4", conf.HttpInterface)
-	urls := strings.Split(conf.Ipv4.URL, ",")
-	for _, url := range urls {
-		url = strings.TrimSpace(url)
-		resp, err := client.Get(url)
-		if err != nil {
-			util.Log("通过接口获取IPv4失败! 接口地址: %s", url)
-			util.Log("异常信息: %s", err)
-			continue
-		}
-		defer resp.Body.Close()
-		lr := io.LimitReader(resp.Body, 1024000)
-		body, err := io.ReadAll(lr)
-		if err != nil {
-			util.Log("异常信息: %s", err)
-			continue
-		}
-		result := Ipv4Reg.FindString(string(body))
-		if result == "" {
-			util.Log("获取IPv4结果失败! 接口: %s ,返回值: %s", url, string(body))
-		}
-		return result
+func (conf *DnsConfig) getIpv4AddrFromUrl(ctx context.Context) string {
+	return conf.getAddrFromUrls(ctx, "IPv4", conf.Ipv4.URL, conf.Ipv4.URLQuorum)
+}
+
+func findIPv6InText(text string) string {
+	if all := findAllIPv6InText(text); len(all) > 0 {
+		return all[0]
 	}
 	return ""
 }
 
-func findIPv6InText(text string) string {
+// findAllIPv6InText 返回文本中全部的IPv6地址
+func findAllIPv6InText(text string) (result []string) {
 	for _, candidate := range Ipv6Reg.FindAllString(text, -1) {
 		if ip := net.ParseIP(candidate); ip != nil && ip.To4() == nil {
-			return candidate
+			result = append(result, candidate)
 		}
 	}
-	return ""
+	return
 }
 
-func (conf *DnsConfig) getAddrFromCmd(addrType string) string {
+func (conf *DnsConfig) getAddrsFromCmd(ctx context.Context, addrType string) []string {
 	var cmd string
 	var comp *regexp.Regexp
 	if addrType == "IPv4" {
@@ -296,149 +407,479 @@ func (conf *DnsConfig) getAddrFromCmd(addrType string) string {
 	}
 	// cmd is empty
 	if cmd == "" {
-		return ""
+		return nil
 	}
 	// run cmd with proper shell
 	var execCmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		execCmd = exec.Command("powershell", "-Command", cmd)
+		execCmd = exec.CommandContext(ctx, "powershell", "-Command", cmd)
 	} else {
 		// If Bash does not exist, use sh
 		_, err := exec.LookPath("bash")
 		if err != nil {
-			execCmd = exec.Command("sh", "-c", cmd)
+			execCmd = exec.CommandContext(ctx, "sh", "-c", cmd)
 		} else {
-			execCmd = exec.Command("bash", "-c", cmd)
+			execCmd = exec.CommandContext(ctx, "bash", "-c", cmd)
 		}
 	}
 	// run cmd
 	out, err := execCmd.CombinedOutput()
 	if err != nil {
 		util.Log("获取%s结果失败! 未能成功执行命令：%s, 错误：%q, 退出状态码：%s", addrType, execCmd.String(), out, err)
-		return ""
+		return nil
 	}
 	str := string(out)
 	// get result
-	result := ""
+	var result []string
 	if addrType == "IPv4" {
-		result = comp.FindString(str)
+		result = comp.FindAllString(str, -1)
 	} else {
-		result = findIPv6InText(str)
+		result = findAllIPv6InText(str)
 	}
-	if result == "" {
+	if len(result) == 0 {
 		util.Log("获取%s结果失败! 命令: %s, 标准输出: %q", addrType, execCmd.String(), str)
 	}
 	return result
 }
 
 // GetIpv4Addr 获得IPv4地址
-func (conf *DnsConfig) GetIpv4Addr() string {
+func (conf *DnsConfig) GetIpv4Addr(ctx context.Context) string {
+	if addrs := conf.GetIpv4Addrs(ctx); len(addrs) > 0 {
+		return addrs[0]
+	}
+	return ""
+}
+
+// GetIpv4Addrs 获得IPv4地址, 未启用 MultiAddr 时最多返回一个
+func (conf *DnsConfig) GetIpv4Addrs(ctx context.Context) []string {
+	addrs, _ := conf.getIpv4Addrs(ctx)
+	return addrs
+}
+
+// getIpv4Addrs 获得通过校验的IPv4地址, 并返回获取到的运营商级NAT地址
+func (conf *DnsConfig) getIpv4Addrs(ctx context.Context) (addrs []string, cgnatAddr string) {
+	addrs = conf.detectAddrs(ctx, "IPv4", conf.fetchIpv4Addrs)
+	addrs, cgnatAddr = conf.filterAddrs("IPv4", conf.Ipv4.GetType, addrs)
+	return limitAddrs(addrs, conf.Ipv4.MultiAddr), cgnatAddr
+}
+
+// fetchIpv4Addrs 按获取类型获取IPv4地址, 未经过地址校验
+func (conf *DnsConfig) fetchIpv4Addrs(ctx context.Context) (addrs []string) {
 	// 判断从哪里获取IP
 	switch conf.Ipv4.GetType {
 	case "netInterface":
 		// 从网卡获取 IP
-		return conf.getIpv4AddrFromInterface()
+		addrs = conf.getIpv4AddrsFromInterface()
 	case "url":
 		// 从 URL 获取 IP
-		return conf.getIpv4AddrFromUrl()
+		addrs = singleAddr(conf.getIpv4AddrFromUrl(ctx))
 	case "cmd":
 		// 从命令行获取 IP
-		return conf.getAddrFromCmd("IPv4")
+		addrs = conf.getAddrsFromCmd(ctx, "IPv4")
+	case "stun":
+		// 从 STUN 服务器获取 IP
+		addrs = singleAddr(conf.getAddrFromStun(ctx, "IPv4", conf.Ipv4.Stun))
+	case "dns":
+		// 通过 DNS 查询 IP
+		addrs = singleAddr(conf.getAddrFromDNS(ctx, "IPv4", conf.Ipv4.DNSQuery))
+	case "router":
+		// 从路由器获取 WAN 口 IP
+		addrs = singleAddr(conf.getIpv4AddrFromRouter(ctx))
+	case "fritzbox":
+		// 通过 TR-064 从 FRITZ!Box 获取 IP
+		addrs = singleAddr(conf.getIpv4AddrFromFritzbox(ctx))
 	default:
 		log.Println("IPv4's get IP method is unknown")
-		return "" // unknown type
+		return nil // unknown type
+	}
+	return addrs
+}
+
+// singleAddr 将单个地址转为地址集合, 为空返回 nil
+func singleAddr(addr string) []string {
+	if addr == "" {
+		return nil
 	}
+	return []string{addr}
 }
 
-func (conf *DnsConfig) getIpv6AddrFromInterface() string {
+// limitAddrs 去除重复的地址, multi 为 false 时只保留第一个
+func limitAddrs(addrs []string, multi bool) (result []string) {
+	seen := map[string]bool{}
+	for _, addr := range addrs {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		result = append(result, addr)
+		if !multi {
+			break
+		}
+	}
+	return
+}
+
+func (conf *DnsConfig) getIpv6AddrsFromInterface() []string {
+	addrs := conf.ipv6InterfaceAddrs()
+	if len(addrs) == 0 {
+		return nil
+	}
+	if conf.Ipv6.Ipv6Reg == "" {
+		return addrs
+	}
+
+	// 匹配第几个IPv6
+	if match, err := regexp.MatchString("@\\d", conf.Ipv6.Ipv6Reg); err == nil && match {
+		num, err := strconv.Atoi(conf.Ipv6.Ipv6Reg[1:])
+		if err == nil {
+			if num > 0 {
+				if num <= len(addrs) {
+					return addrs[num-1 : num]
+				}
+				util.Log("未找到第 %d 个IPv6地址! 将使用第一个IPv6地址", num)
+				return addrs[:1]
+			}
+			util.Log("IPv6匹配表达式 %s 不正确! 最小从1开始", conf.Ipv6.Ipv6Reg)
+			return nil
+		}
+	}
+	// 正则表达式匹配, 启用 MultiAddr 时使用全部匹配到的地址
+	util.Log("IPv6将使用正则表达式 %s 进行匹配", conf.Ipv6.Ipv6Reg)
+	var matchedAddrs []string
+	for _, addr := range addrs {
+		matched, err := regexp.MatchString(conf.Ipv6.Ipv6Reg, addr)
+		if matched && err == nil {
+			util.Log("匹配成功! 匹配到地址: %s", addr)
+			matchedAddrs = append(matchedAddrs, addr)
+			if !conf.Ipv6.MultiAddr {
+				break
+			}
+		}
+	}
+	if len(matchedAddrs) > 0 {
+		return matchedAddrs
+	}
+	util.Log("没有匹配到任何一个IPv6地址, 将使用第一个地址")
+	return addrs[:1]
+}
+
+// ipv6InterfaceAddrs 获取网卡上的IPv6地址, 设置了 Select 时按选择策略筛选和排序
+func (conf *DnsConfig) ipv6InterfaceAddrs() []string {
+	if conf.Ipv6.Select != "" {
+		addrs, err := conf.selectIpv6Addrs()
+		if err != nil {
+			util.Log("按选择策略 %s 从网卡 %s 获得IPv6失败! 错误: %s", conf.Ipv6.Select, conf.Ipv6.NetInterface, err)
+			return nil
+		}
+		return addrs
+	}
+
 	_, ipv6, err := GetNetInterface()
 	if err != nil {
 		util.Log("从网卡获得IPv6失败")
-		return ""
+		return nil
 	}
-
 	for _, netInterface := range ipv6 {
 		if netInterface.Name == conf.Ipv6.NetInterface && len(netInterface.Address) > 0 {
-			if conf.Ipv6.Ipv6Reg != "" {
-				// 匹配第几个IPv6
-				if match, err := regexp.MatchString("@\\d", conf.Ipv6.Ipv6Reg); err == nil && match {
-					num, err := strconv.Atoi(conf.Ipv6.Ipv6Reg[1:])
-					if err == nil {
-						if num > 0 {
-							if num <= len(netInterface.Address) {
-								return netInterface.Address[num-1]
-							}
-							util.Log("未找到第 %d 个IPv6地址! 将使用第一个IPv6地址", num)
-							return netInterface.Address[0]
-						}
-						util.Log("IPv6匹配表达式 %s 不正确! 最小从1开始", conf.Ipv6.Ipv6Reg)
-						return ""
-					}
-				}
-				// 正则表达式匹配
-				util.Log("IPv6将使用正则表达式 %s 进行匹配", conf.Ipv6.Ipv6Reg)
-				for i := 0; i < len(netInterface.Address); i++ {
-					matched, err := regexp.MatchString(conf.Ipv6.Ipv6Reg, netInterface.Address[i])
-					if matched && err == nil {
-						util.Log("匹配成功! 匹配到地址: %s", netInterface.Address[i])
-						return netInterface.Address[i]
-					}
-				}
-				util.Log("没有匹配到任何一个IPv6地址, 将使用第一个地址")
-			}
-			return netInterface.Address[0]
+			return netInterface.Address
 		}
 	}
 
 	util.Log("从网卡中获得IPv6失败! 网卡名: %s", conf.Ipv6.NetInterface)
+	return nil
+}
+
+func (conf *DnsConfig) getIpv6AddrFromUrl(ctx context.Context) string {
+	return conf.getAddrFromUrls(ctx, "IPv6", conf.Ipv6.URL, conf.Ipv6.URLQuorum)
+}
+
+// getAddrFromUrls 依次请求接口, 返回第一个响应的接口的结果; 设置了 quorum 时同时请求全部接口, 按多数结果采用
+func (conf *DnsConfig) getAddrFromUrls(ctx context.Context, addrType, urls, quorum string) string {
+	network := "tcp4"
+	if addrType == "IPv6" {
+		network = "tcp6"
+	}
+	client := util.CreateBoundNoProxyHTTPClient(network, conf.HttpInterface)
+
+	var sources []urlSource
+	for _, raw := range SplitURLSources(urls) {
+		src, err := parseURLSource(raw)
+		if err != nil {
+			util.Log("接口 %s 的配置不正确: %s", raw, err)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	if n := GetURLQuorum(quorum); n > 1 {
+		return consensusAddr(ctx, client, sources, addrType, n)
+	}
+	for _, src := range sources {
+		if result, ok := getAddrFromUrl(ctx, client, src, addrType); ok {
+			return result
+		}
+	}
 	return ""
 }
 
-func (conf *DnsConfig) getIpv6AddrFromUrl() string {
-	client := util.CreateBoundNoProxyHTTPClient("tcp6", conf.HttpInterface)
-	urls := strings.Split(conf.Ipv6.URL, ",")
-	for _, url := range urls {
-		url = strings.TrimSpace(url)
-		resp, err := client.Get(url)
+// getAddrFromUrl 请求接口并从返回内容中提取地址, 接口未响应时 ok 为 false
+func getAddrFromUrl(ctx context.Context, client *http.Client, src urlSource, addrType string) (result string, ok bool) {
+	body, err := src.request(ctx, client)
+	if err != nil {
+		util.Log("通过接口获取%s失败! 接口地址: %s", addrType, src.redacted)
+		util.Log("异常信息: %s", err)
+		return "", false
+	}
+	text := string(body)
+	if src.extract != nil {
+		if text, err = src.extract(body); err != nil {
+			util.Log("获取%s结果失败! 接口: %s ,返回值: %s", addrType, src.redacted, string(body))
+			util.Log("异常信息: %s", err)
+			return "", true
+		}
+	}
+	if addrType == "IPv4" {
+		result = Ipv4Reg.FindString(text)
+	} else {
+		result = findIPv6InText(text)
+	}
+	if result == "" {
+		util.Log("获取%s结果失败! 接口: %s ,返回值: %s", addrType, src.redacted, string(body))
+	}
+	return result, true
+}
+
+// consensusAddr 同时请求全部接口, 至少 quorum 个接口返回相同的地址时才采用, 并记录结果不一致的接口
+func consensusAddr(ctx context.Context, client *http.Client, sources []urlSource, addrType string, quorum int) string {
+	results := make([]string, len(sources))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Go(func() {
+			result, _ := getAddrFromUrl(ctx, client, src, addrType)
+			// 统一地址格式, 如IPv6的缩写
+			if ip := net.ParseIP(result); ip != nil {
+				results[i] = ip.String()
+			}
+		})
+	}
+	wg.Wait()
+
+	counts := map[string]int{}
+	for _, result := range results {
+		if result != "" {
+			counts[result]++
+		}
+	}
+	best, tie := "", false
+	for addr, n := range counts {
+		switch {
+		case n > counts[best]:
+			best, tie = addr, false
+		case n == counts[best]:
+			tie = true
+		}
+	}
+
+	for i, result := range results {
+		if result != best {
+			if result == "" {
+				result = "-"
+			}
+			util.Log("接口 %s 返回的%s %s 与多数结果 %s 不一致", sources[i].redacted, addrType, result, best)
+		}
+	}
+	if best == "" || tie || counts[best] < quorum {
+		util.Log("获取%s失败! 需要至少 %d 个接口返回相同的地址, 实际最多 %d 个", addrType, quorum, counts[best])
+		return ""
+	}
+	return best
+}
+
+// getAddrFromStun 依次请求STUN服务器, 返回第一个成功的结果
+func (conf *DnsConfig) getAddrFromStun(ctx context.Context, addrType, servers string) string {
+	network := "udp4"
+	if addrType == "IPv6" {
+		network = "udp6"
+	}
+	for _, server := range util.SplitComma(servers) {
+		ip, err := util.StunAddr(ctx, network, conf.HttpInterface, server)
 		if err != nil {
-			util.Log("通过接口获取IPv6失败! 接口地址: %s", url)
+			util.Log("通过STUN获取%s失败! 服务器: %s", addrType, server)
 			util.Log("异常信息: %s", err)
 			continue
 		}
+		return ip.String()
+	}
+	return ""
+}
 
-		defer resp.Body.Close()
-		lr := io.LimitReader(resp.Body, 1024000)
-		body, err := io.ReadAll(lr)
+// getAddrFromDNS 依次按模板通过DNS查询, 返回第一个成功的结果
+func (conf *DnsConfig) getAddrFromDNS(ctx context.Context, addrType, queries string) string {
+	for _, query := range util.SplitComma(queries) {
+		ip, err := util.LookupMyIP(ctx, query, addrType == "IPv6", conf.HttpInterface)
 		if err != nil {
+			util.Log("通过DNS获取%s失败! 查询: %s", addrType, query)
 			util.Log("异常信息: %s", err)
 			continue
 		}
-		result := findIPv6InText(string(body))
-		if result == "" {
-			util.Log("获取IPv6结果失败! 接口: %s ,返回值: %s", url, string(body))
-		}
-		return result
+		return ip.String()
 	}
 	return ""
 }
 
+// getIpv4AddrFromRouter 通过 UPnP IGD 或 NAT-PMP/PCP 从路由器获取WAN口地址
+func (conf *DnsConfig) getIpv4AddrFromRouter(ctx context.Context) string {
+	ip, err := util.RouterAddr(ctx, conf.HttpInterface)
+	if err != nil {
+		util.Log("从路由器获取IPv4失败! 异常信息: %s", err)
+		return ""
+	}
+	if ip.IsPrivate() || util.IsCGNAT(ip) {
+		util.Log("路由器的WAN口地址 %s 是私有地址或运营商级NAT地址, 路由器可能没有公网IP", ip)
+	}
+	return ip.String()
+}
+
+// getIpv4AddrFromFritzbox 通过 TR-064 获取 FRITZ!Box 的公网IPv4地址
+func (conf *DnsConfig) getIpv4AddrFromFritzbox(ctx context.Context) string {
+	ip, err := util.FritzboxIPv4(ctx, conf.Fritzbox.URL, conf.Fritzbox.Username, conf.Fritzbox.Password, conf.HttpInterface)
+	if err != nil {
+		util.Log("从FRITZ!Box获取%s失败! 异常信息: %s", "IPv4", err)
+		return ""
+	}
+	return ip.String()
+}
+
+// getIpv6AddrFromFritzbox 通过 TR-064 获取 FRITZ!Box 的公网IPv6地址
+// 全部域名都设置了 Ipv6Suffix 时返回分配给局域网的前缀, 由域名的后缀组成主机地址, FRITZ!Box 自身的地址通常不在该前缀中
+func (conf *DnsConfig) getIpv6AddrFromFritzbox(ctx context.Context) string {
+	result, err := util.FritzboxIPv6Addr(ctx, conf.Fritzbox.URL, conf.Fritzbox.Username, conf.Fritzbox.Password, conf.HttpInterface)
+	if err != nil {
+		util.Log("从FRITZ!Box获取%s失败! 异常信息: %s", "IPv6", err)
+		return ""
+	}
+	if result.Prefix != nil && conf.ipv6HostDomainsOnly() {
+		util.Log("使用 FRITZ!Box 的IPv6前缀 %s 组成局域网主机的地址", result.Prefix)
+		return result.Prefix.IP.String()
+	}
+	if result.Addr == nil {
+		if result.Prefix != nil {
+			util.Log("FRITZ!Box 没有公网IPv6地址, 为域名设置 Ipv6Suffix 后可使用前缀 %s", result.Prefix)
+		} else {
+			util.Log("FRITZ!Box 没有公网IPv6地址")
+		}
+		return ""
+	}
+	return result.Addr.String()
+}
+
+// ipv6HostDomainsOnly 全部IPv6域名都设置了 Ipv6Suffix
+func (conf *DnsConfig) ipv6HostDomainsOnly() bool {
+	found := false
+	for _, line := range conf.Ipv6.Domains {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		_, query, _ := strings.Cut(line, "?")
+		params, err := url.ParseQuery(query)
+		if err != nil || params.Get(ipv6SuffixParam) == "" {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// GetURLQuorum 获得多个接口达成一致所需的数量, 为空或无效时返回 0
+func GetURLQuorum(quorum string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(quorum))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
 // GetIpv6Addr 获得IPv6地址
-func (conf *DnsConfig) GetIpv6Addr() (result string) {
+func (conf *DnsConfig) GetIpv6Addr(ctx context.Context) (result string) {
+	if addrs := conf.GetIpv6Addrs(ctx); len(addrs) > 0 {
+		return addrs[0]
+	}
+	return ""
+}
+
+// GetIpv6Addrs 获得IPv6地址, 未启用 MultiAddr 时最多返回一个
+func (conf *DnsConfig) GetIpv6Addrs(ctx context.Context) []string {
+	addrs := conf.detectAddrs(ctx, "IPv6", conf.fetchIpv6Addrs)
+	addrs, _ = conf.filterAddrs("IPv6", conf.Ipv6.GetType, addrs)
+	return limitAddrs(addrs, conf.Ipv6.MultiAddr)
+}
+
+// fetchIpv6Addrs 按获取类型获取IPv6地址, 未经过地址校验
+func (conf *DnsConfig) fetchIpv6Addrs(ctx context.Context) (addrs []string) {
 	// 判断从哪里获取IP
 	switch conf.Ipv6.GetType {
 	case "netInterface":
 		// 从网卡获取 IP
-		return conf.getIpv6AddrFromInterface()
+		addrs = conf.getIpv6AddrsFromInterface()
 	case "url":
 		// 从 URL 获取 IP
-		return conf.getIpv6AddrFromUrl()
+		addrs = singleAddr(conf.getIpv6AddrFromUrl(ctx))
 	case "cmd":
 		// 从命令行获取 IP
-		return conf.getAddrFromCmd("IPv6")
+		addrs = conf.getAddrsFromCmd(ctx, "IPv6")
+	case "stun":
+		// 从 STUN 服务器获取 IP
+		addrs = singleAddr(conf.getAddrFromStun(ctx, "IPv6", conf.Ipv6.Stun))
+	case "dns":
+		// 通过 DNS 查询 IP
+		addrs = singleAddr(conf.getAddrFromDNS(ctx, "IPv6", conf.Ipv6.DNSQuery))
+	case "fritzbox":
+		// 通过 TR-064 从 FRITZ!Box 获取 IP
+		addrs = singleAddr(conf.getIpv6AddrFromFritzbox(ctx))
 	default:
 		log.Println("IPv6's get IP method is unknown")
-		return "" // unknown type
+		return nil // unknown type
+	}
+	return addrs
+}
+
+// GetTimeout 获得单次更新的超时时间
+func (conf *DnsConfig) GetTimeout() time.Duration {
+	timeout, err := strconv.Atoi(conf.Timeout)
+	if err != nil || timeout <= 0 {
+		return DefaultTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// GetStaleTimes 获得处理旧记录前连续获取IP失败的次数, 0 表示不处理
+func (conf *DnsConfig) GetStaleTimes() int {
+	times, err := strconv.Atoi(conf.StaleTimes)
+	if err != nil || times < 0 {
+		return 0
+	}
+	return times
+}
+
+// GetStable 获得新地址需要连续获取到的次数和持续的时间, 都为 0 时立即更新
+func (conf *DnsConfig) GetStable() (times int, window time.Duration) {
+	times, err := strconv.Atoi(conf.StableTimes)
+	if err != nil || times < 0 {
+		times = 0
+	}
+	seconds, err := strconv.Atoi(conf.StableSeconds)
+	if err != nil || seconds < 0 {
+		seconds = 0
+	}
+	return times, time.Duration(seconds) * time.Second
+}
+
+// GetSchedule 获得更新计划, 未设置时返回 nil
+func (conf *DnsConfig) GetSchedule() (util.Schedule, error) {
+	if strings.TrimSpace(conf.Schedule) == "" {
+		return nil, nil
 	}
+	return util.ParseSchedule(conf.Schedule)
 }
 
 // GetHTTPClient 获得HTTP客户端，如果配置了HttpInterface则绑定到指定网卡
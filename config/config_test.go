@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestConsensusAddr(t *testing.T) {
+	newServer := func(body string) *httptest.Server {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(s.Close)
+		return s
+	}
+	a1 := newServer("1.1.1.1")
+	a2 := newServer("ip: 1.1.1.1\n")
+	b := newServer("2.2.2.2")
+	bad := newServer("error")
+
+	tests := []struct {
+		name   string
+		urls   []string
+		quorum int
+		want   string
+	}{
+		{"majority", []string{a1.URL, b.URL, a2.URL}, 2, "1.1.1.1"},
+		{"not enough", []string{a1.URL, b.URL, bad.URL}, 2, ""},
+		{"tie", []string{a1.URL, a2.URL, b.URL, b.URL}, 2, ""},
+		{"unreachable", []string{a1.URL, a2.URL, "http://127.0.0.1:1"}, 2, "1.1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []urlSource
+			for _, u := range tt.urls {
+				src, _ := parseURLSource(u)
+				sources = append(sources, src)
+			}
+			if got := consensusAddr(context.Background(), http.DefaultClient, sources, "IPv4", tt.quorum); got != tt.want {
+				t.Errorf("consensusAddr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetIpv4AddrFromUrl(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.1.1.1"))
+	}))
+	defer s.Close()
+
+	conf := &DnsConfig{}
+	conf.Ipv4.URL = "http://127.0.0.1:1, " + s.URL
+	// 第一个接口无响应时使用下一个接口
+	if got := conf.getIpv4AddrFromUrl(context.Background()); got != "1.1.1.1" {
+		t.Errorf("getIpv4AddrFromUrl() = %q, want %q", got, "1.1.1.1")
+	}
+	conf.Ipv4.URLQuorum = "2"
+	if got := conf.getIpv4AddrFromUrl(context.Background()); got != "" {
+		t.Errorf("getIpv4AddrFromUrl() with quorum = %q, want empty", got)
+	}
+}
+
+// TestCompatibleAddrFilter 测试地址校验加入前的配置保持之前的行为
+func TestCompatibleAddrFilter(t *testing.T) {
+	t.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	t.Cleanup(func() { cache.ConfigSingle = nil })
+
+	old := "dnsconf:\n  - name: old\n    dns:\n      name: cloudflare\n"
+	if err := os.WriteFile(util.GetConfigFilePath(), []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := GetConfigCached()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CompatibleConfig()
+
+	conf, _ = GetConfigCached()
+	if f := conf.DnsConf[0].AddrFilter; f.Private != AddrPolicyAllow || f.CGNAT != AddrPolicyAllow {
+		t.Fatalf("AddrFilter = %+v, want allow for old configs", f)
+	}
+
+	// 保存后的配置包含 AddrFilter, 不再修改
+	conf.DnsConf[0].AddrFilter.Private, conf.DnsConf[0].AddrFilter.CGNAT = "", ""
+	if err := conf.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	conf, _ = GetConfigCached()
+	if conf.compatibleAddrFilter() {
+		t.Errorf("configs saved with AddrFilter should not be changed")
+	}
+}
+
+// TestCompatibleConfigInMemory 测试 dry-run 时只修改内存中的配置
+func TestCompatibleConfigInMemory(t *testing.T) {
+	t.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	t.Cleanup(func() { cache.ConfigSingle = nil })
+
+	old := "dnsconf:\n  - name: old\n    dns:\n      name: cloudflare\n"
+	if err := os.WriteFile(util.GetConfigFilePath(), []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := GetConfigCached()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CompatibleConfigInMemory()
+
+	conf, _ = GetConfigCached()
+	if f := conf.DnsConf[0].AddrFilter; f.Private != AddrPolicyAllow || f.CGNAT != AddrPolicyAllow {
+		t.Errorf("AddrFilter = %+v, want allow for old configs", f)
+	}
+	if byt, _ := os.ReadFile(util.GetConfigFilePath()); string(byt) != old {
+		t.Errorf("config file should not be changed, got:\n%s", byt)
+	}
+}
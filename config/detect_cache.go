@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// detectCache 一轮更新中各获取方式的结果, 使用相同获取方式的配置只获取一次
+type detectCache struct {
+	mu      sync.Mutex
+	entries map[string]*detectEntry
+}
+
+type detectEntry struct {
+	done  chan struct{}
+	addrs []string
+	// canceled 获取时 context 已取消, 结果不共享
+	canceled bool
+}
+
+type detectCacheKey struct{}
+
+// WithDetectCache 返回带有本轮获取结果缓存的 context, 在一轮更新开始时调用
+func WithDetectCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, detectCacheKey{}, &detectCache{entries: map[string]*detectEntry{}})
+}
+
+// addrSource 获取地址的方式, 只包含所用获取类型需要的字段
+type addrSource struct {
+	AddrType      string
+	GetType       string
+	Source        string
+	Options       []string `json:",omitempty"`
+	HttpInterface string
+}
+
+// addrSourceKey 获取地址的方式在缓存中的键
+func (conf *DnsConfig) addrSourceKey(addrType string) string {
+	s := addrSource{AddrType: addrType, HttpInterface: conf.HttpInterface}
+	if addrType == "IPv4" {
+		s.GetType = conf.Ipv4.GetType
+		switch s.GetType {
+		case "netInterface":
+			s.Source = conf.Ipv4.NetInterface
+		case "url":
+			s.Source, s.Options = conf.Ipv4.URL, []string{conf.Ipv4.URLQuorum}
+		case "cmd":
+			s.Source = conf.Ipv4.Cmd
+		case "stun":
+			s.Source = conf.Ipv4.Stun
+		case "dns":
+			s.Source = conf.Ipv4.DNSQuery
+		case "fritzbox":
+			s.Source, s.Options = conf.Fritzbox.URL, []string{conf.Fritzbox.Username, conf.Fritzbox.Password}
+		}
+	} else {
+		s.GetType = conf.Ipv6.GetType
+		switch s.GetType {
+		case "netInterface":
+			// 匹配表达式和 MultiAddr 影响从网卡中选出的地址
+			s.Source = conf.Ipv6.NetInterface
+			s.Options = []string{conf.Ipv6.Ipv6Reg, conf.Ipv6.Select, strconv.FormatBool(conf.Ipv6.MultiAddr)}
+		case "url":
+			s.Source, s.Options = conf.Ipv6.URL, []string{conf.Ipv6.URLQuorum}
+		case "cmd":
+			s.Source = conf.Ipv6.Cmd
+		case "stun":
+			s.Source = conf.Ipv6.Stun
+		case "dns":
+			s.Source = conf.Ipv6.DNSQuery
+		case "fritzbox":
+			// 全部域名都设置了 Ipv6Suffix 时获取的是前缀
+			s.Source = conf.Fritzbox.URL
+			s.Options = []string{conf.Fritzbox.Username, conf.Fritzbox.Password, strconv.FormatBool(conf.ipv6HostDomainsOnly())}
+		}
+	}
+	byt, _ := json.Marshal(s)
+	return string(byt)
+}
+
+// detectAddrs 获取地址, context 中有本轮的缓存时, 相同的获取方式只调用一次 fetch
+// 其他配置等待正在进行的获取完成后使用其结果; 获取时 context 被取消则由下一个配置重新获取
+func (conf *DnsConfig) detectAddrs(ctx context.Context, addrType string, fetch func(ctx context.Context) []string) []string {
+	cache, ok := ctx.Value(detectCacheKey{}).(*detectCache)
+	if !ok {
+		return fetch(ctx)
+	}
+
+	key := conf.addrSourceKey(addrType)
+	for {
+		cache.mu.Lock()
+		e, ok := cache.entries[key]
+		if !ok {
+			e = &detectEntry{done: make(chan struct{})}
+			cache.entries[key] = e
+			cache.mu.Unlock()
+
+			e.addrs = fetch(ctx)
+			if ctx.Err() != nil {
+				e.canceled = true
+				cache.mu.Lock()
+				delete(cache.entries, key)
+				cache.mu.Unlock()
+			}
+			close(e.done)
+			return slices.Clone(e.addrs)
+		}
+		cache.mu.Unlock()
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil
+		}
+		if !e.canceled {
+			if len(e.addrs) > 0 {
+				util.Log("%s的获取方式与其他配置相同, 使用本轮已获取的地址: %s", addrType, strings.Join(e.addrs, ","))
+			}
+			return slices.Clone(e.addrs)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDetectAddrs 测试获取方式相同的配置在同一轮中只获取一次
+func TestDetectAddrs(t *testing.T) {
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) []string {
+		calls.Add(1)
+		return []string{"192.0.2.1"}
+	}
+	newConf := func(url string) *DnsConfig {
+		conf := &DnsConfig{}
+		conf.Ipv4.GetType = "url"
+		conf.Ipv4.URL = url
+		// 与获取类型无关的字段不影响缓存
+		conf.Ipv4.NetInterface = url
+		return conf
+	}
+
+	ctx := WithDetectCache(context.Background())
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if addrs := newConf("https://example.com/ip").detectAddrs(ctx, "IPv4", fetch); len(addrs) != 1 {
+				t.Errorf("addrs = %v", addrs)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("fetch called %d times, want 1", calls.Load())
+	}
+
+	// 接口或地址类型不同时分别获取
+	newConf("https://example.org/ip").detectAddrs(ctx, "IPv4", fetch)
+	newConf("https://example.com/ip").detectAddrs(ctx, "IPv6", fetch)
+	if calls.Load() != 3 {
+		t.Errorf("fetch called %d times, want 3", calls.Load())
+	}
+
+	// 新的一轮重新获取
+	newConf("https://example.com/ip").detectAddrs(WithDetectCache(context.Background()), "IPv4", fetch)
+	if calls.Load() != 4 {
+		t.Errorf("fetch called %d times, want 4", calls.Load())
+	}
+}
+
+// TestDetectAddrsCanceled 测试获取时被取消的结果不共享
+func TestDetectAddrsCanceled(t *testing.T) {
+	conf := &DnsConfig{}
+	conf.Ipv4.GetType = "cmd"
+	conf.Ipv4.Cmd = "echo 192.0.2.1"
+	ctx := WithDetectCache(context.Background())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	conf.detectAddrs(canceled, "IPv4", func(ctx context.Context) []string { return nil })
+
+	addrs := conf.detectAddrs(ctx, "IPv4", func(ctx context.Context) []string { return []string{"192.0.2.1"} })
+	if len(addrs) != 1 {
+		t.Errorf("addrs = %v, want the address to be fetched again", addrs)
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"context"
+	"net"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jeessy2/ddns-go/v6/util"
 	"golang.org/x/net/idna"
@@ -11,12 +15,32 @@ import (
 
 // Domains Ipv4/Ipv6 domains
 type Domains struct {
-	Ipv4Addr    string
+	Ipv4Addr string
+	// Ipv4Addrs 启用 MultiAddr 时的全部IPv4地址, Ipv4Addr 为其中第一个; 未启用时为空
+	Ipv4Addrs   []string
 	Ipv4Cache   *util.IpCache
 	Ipv4Domains []*Domain
-	Ipv6Addr    string
+	// Ipv4Stability 本次获取到的IPv4地址的稳定情况
+	Ipv4Stability AddrStability
+	// CGNATAddr 获取IPv4时发现的运营商级NAT地址 (100.64.0.0/10), 未发现时为空
+	CGNATAddr string
+	Ipv6Addr  string
+	// Ipv6Addrs 启用 MultiAddr 时的全部IPv6地址, Ipv6Addr 为其中第一个; 未启用时为空
+	Ipv6Addrs   []string
 	Ipv6Cache   *util.IpCache
 	Ipv6Domains []*Domain
+	// Ipv6Stability 本次获取到的IPv6地址的稳定情况
+	Ipv6Stability AddrStability
+}
+
+// AddrStability 获取到的地址相对于上次更新的地址的变化
+type AddrStability struct {
+	// Changed 地址已改变且已稳定, 将会更新
+	Changed bool
+	// Waiting 地址已改变但还未稳定, 本次不更新
+	Waiting bool
+	// Flapped 等待稳定的地址在稳定前又发生了变化
+	Flapped bool
 }
 
 // Domain 域名实体
@@ -27,6 +51,18 @@ type Domain struct {
 	SubDomain    string
 	CustomParams string
 	UpdateStatus updateStatusType // 更新状态
+	// ErrorKind 更新失败的错误类型, 未分类时为空
+	ErrorKind util.ErrorKind
+	// PublishStatus 更新后在权威DNS中的验证结果, 未验证时为空
+	PublishStatus publishStatusType
+	// precheckMatched 预检查时已解析到获取的地址, 无需请求DNS服务商
+	precheckMatched bool
+	// ipv6Suffix 和 ipv6PrefixLen 来自参数 Ipv6Suffix/Ipv6PrefixLen, 用于组成局域网主机的IPv6地址
+	ipv6Suffix    net.IP
+	ipv6PrefixLen int
+	// neighborMac 来自参数 NeighborMac, neighborAddrs 为本次从邻居表中找到的地址
+	neighborMac   net.HardwareAddr
+	neighborAddrs []string
 }
 
 // DomainTuples 域名元组映射 key: Domain.String()
@@ -36,11 +72,13 @@ type DomainTuples map[string]*DomainTuple
 type DomainTuple struct {
 	RecordType string
 	// Primary 首要域名 Domains[-1] = Primary
-	Primary  *Domain
-	Domains  []*Domain
-	IpAddrs  []string
-	Ipv4Addr string
-	Ipv6Addr string
+	Primary *Domain
+	Domains []*Domain
+	IpAddrs []string
+	// IpAddrSets 与 Domains 对应的全部地址, 未启用 MultiAddr 的地址族为 nil
+	IpAddrSets [][]string
+	Ipv4Addr   string
+	Ipv6Addr   string
 }
 
 // nontransitionalLookup implements the nontransitional processing as specified in
@@ -68,6 +106,15 @@ func (d Domain) GetFullDomain() string {
 	return "@" + "." + d.DomainName
 }
 
+// LookupName 查询DNS时使用的域名, 泛解析使用任意子域名
+func (d Domain) LookupName() string {
+	name := d.ToASCII()
+	if rest, ok := strings.CutPrefix(name, "*."); ok {
+		return "ddns-go-check." + rest
+	}
+	return name
+}
+
 // GetSubDomain 获得子域名，为空返回@
 // 阿里云/腾讯云/dnspod/GoDaddy/namecheap 需要
 func (d Domain) GetSubDomain() string {
@@ -99,15 +146,19 @@ func (d Domain) ToASCII() string {
 }
 
 // GetNewIp 接口/网卡/命令获得 ip 并校验用户输入的域名
-func (domains *Domains) GetNewIp(dnsConf *DnsConfig) {
+func (domains *Domains) GetNewIp(ctx context.Context, dnsConf *DnsConfig) {
 	domains.Ipv4Domains = checkParseDomains(dnsConf.Ipv4.Domains)
 	domains.Ipv6Domains = checkParseDomains(dnsConf.Ipv6.Domains)
 
 	// IPv4
 	if dnsConf.Ipv4.Enable && len(domains.Ipv4Domains) > 0 {
-		ipv4Addr := dnsConf.GetIpv4Addr()
-		if ipv4Addr != "" {
-			domains.Ipv4Addr = ipv4Addr
+		ipv4Addrs, cgnatAddr := dnsConf.getIpv4Addrs(ctx)
+		domains.CGNATAddr = cgnatAddr
+		if len(ipv4Addrs) > 0 {
+			domains.Ipv4Addr = ipv4Addrs[0]
+			if dnsConf.Ipv4.MultiAddr {
+				domains.Ipv4Addrs = ipv4Addrs
+			}
 			domains.Ipv4Cache.TimesFailedIP = 0
 		} else {
 			// 启用IPv4 & 未获取到IP & 填写了域名 & 失败刚好3次，防止偶尔的网络连接失败，并且只发一次
@@ -121,9 +172,12 @@ func (domains *Domains) GetNewIp(dnsConf *DnsConfig) {
 
 	// IPv6
 	if dnsConf.Ipv6.Enable && len(domains.Ipv6Domains) > 0 {
-		ipv6Addr := dnsConf.GetIpv6Addr()
-		if ipv6Addr != "" {
-			domains.Ipv6Addr = ipv6Addr
+		ipv6Addrs := dnsConf.GetIpv6Addrs(ctx)
+		if len(ipv6Addrs) > 0 {
+			domains.Ipv6Addr = ipv6Addrs[0]
+			if dnsConf.Ipv6.MultiAddr {
+				domains.Ipv6Addrs = ipv6Addrs
+			}
 			domains.Ipv6Cache.TimesFailedIP = 0
 		} else {
 			// 启用IPv6 & 未获取到IP & 填写了域名 & 失败刚好3次，防止偶尔的网络连接失败，并且只发一次
@@ -135,6 +189,14 @@ func (domains *Domains) GetNewIp(dnsConf *DnsConfig) {
 		}
 	}
 
+	if dnsConf.Ipv4.Enable {
+		resolveNeighbors(domains.Ipv4Domains, false)
+	}
+	if dnsConf.Ipv6.Enable {
+		resolveNeighbors(domains.Ipv6Domains, true)
+	}
+	domains.checkStable(dnsConf, time.Now())
+	domains.precheck(ctx, dnsConf)
 }
 
 // checkParseDomains 校验并解析用户输入的域名
@@ -188,7 +250,16 @@ func checkParseDomains(domainArr []string) (domains []*Domain) {
 				util.Log("域名: %s 解析失败", domainStr)
 				continue
 			}
-			domain.CustomParams = u.Query().Encode()
+			params := u.Query()
+			domain.ipv6Suffix, domain.ipv6PrefixLen, err = parseIpv6Host(params)
+			if err == nil {
+				domain.neighborMac, err = parseNeighborMac(params)
+			}
+			if err != nil {
+				util.Log("域名: %s 的参数不正确: %s", domainStr, err)
+				continue
+			}
+			domain.CustomParams = params.Encode()
 		}
 		domains = append(domains, domain)
 	}
@@ -197,66 +268,150 @@ func checkParseDomains(domainArr []string) (domains []*Domain) {
 
 // GetNewIpResult 获得GetNewIp结果
 func (domains *Domains) GetNewIpResult(recordType string) (ipAddr string, retDomains []*Domain) {
+	ipAddrs, retDomains := domains.getNewIpsResult(recordType)
+	if len(ipAddrs) > 0 {
+		ipAddr = ipAddrs[0]
+	}
+	return
+}
+
+// getNewIpsResult 获得GetNewIp结果, 未启用 MultiAddr 时只有一个地址
+func (domains *Domains) getNewIpsResult(recordType string) (ipAddrs []string, retDomains []*Domain) {
 	if recordType == "AAAA" {
-		if domains.Ipv6Cache.Check(domains.Ipv6Addr) {
-			return domains.Ipv6Addr, domains.Ipv6Domains
+		ipAddrs = addrSet(domains.Ipv6Addr, domains.Ipv6Addrs)
+		if domains.Ipv6Stability.Waiting {
+			return nil, domains.Ipv6Domains
+		}
+		if domains.Ipv6Cache.Check(cacheKey(ipAddrs, domains.Ipv6Domains)) {
+			return ipAddrs, pendingDomains(domains.Ipv6Domains)
 		} else {
 			util.Log("IPv6未改变, 将等待 %d 次后与DNS服务商进行比对", domains.Ipv6Cache.Times)
-			return "", domains.Ipv6Domains
+			return nil, domains.Ipv6Domains
 		}
 	}
 	// IPv4
-	if domains.Ipv4Cache.Check(domains.Ipv4Addr) {
-		return domains.Ipv4Addr, domains.Ipv4Domains
+	ipAddrs = addrSet(domains.Ipv4Addr, domains.Ipv4Addrs)
+	if domains.Ipv4Stability.Waiting {
+		return nil, domains.Ipv4Domains
+	}
+	if domains.Ipv4Cache.Check(cacheKey(ipAddrs, domains.Ipv4Domains)) {
+		return ipAddrs, pendingDomains(domains.Ipv4Domains)
 	} else {
 		util.Log("IPv4未改变, 将等待 %d 次后与DNS服务商进行比对", domains.Ipv4Cache.Times)
-		return "", domains.Ipv4Domains
+		return nil, domains.Ipv4Domains
+	}
+}
+
+// pendingDomains 排除预检查时已解析到获取的地址的域名, 以及邻居表中未找到地址的域名
+func pendingDomains(domains []*Domain) []*Domain {
+	var result []*Domain
+	for _, d := range domains {
+		if !d.precheckMatched && (d.neighborMac == nil || len(d.neighborAddrs) > 0) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// addrSet 返回全部地址, 未启用 MultiAddr 时为 ipAddr
+func addrSet(ipAddr string, ipAddrs []string) []string {
+	if len(ipAddrs) > 0 {
+		return ipAddrs
 	}
+	if ipAddr == "" {
+		return nil
+	}
+	return []string{ipAddr}
+}
+
+// addrCacheKey 地址集合在 IpCache 中的键, 与顺序无关
+func addrCacheKey(ipAddrs []string) string {
+	sorted := append([]string(nil), ipAddrs...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// cacheKey 地址和邻居域名的地址在 IpCache 中的键
+func cacheKey(ipAddrs []string, ds []*Domain) string {
+	return addrCacheKey(ipAddrs) + neighborCacheKey(ds)
+}
+
+// checkStable 地址改变后需稳定一段时间才更新, 未稳定时清空本次获取到的地址
+func (domains *Domains) checkStable(dnsConf *DnsConfig, now time.Time) {
+	times, window := dnsConf.GetStable()
+	check := func(name string, cache *util.IpCache, ipAddr *string, ipAddrs *[]string, ds []*Domain) (s AddrStability) {
+		if cache == nil || *ipAddr == "" {
+			return
+		}
+		key := cacheKey(addrSet(*ipAddr, *ipAddrs), ds)
+		changed := cache.StableAddr != "" && cache.StableAddr != key
+		stable, flapped := cache.Stable(key, times, window, now)
+		s = AddrStability{Changed: stable && changed, Waiting: !stable, Flapped: flapped}
+		if flapped {
+			util.Log("%s地址在稳定前再次改变", name)
+		}
+		if !stable {
+			util.Log("%s地址 %s 尚未稳定, 已连续获取到 %d 次, 将不会更新", name, *ipAddr, cache.PendingTimes)
+			*ipAddr, *ipAddrs = "", nil
+		}
+		return
+	}
+	domains.Ipv4Stability = check("IPv4", domains.Ipv4Cache, &domains.Ipv4Addr, &domains.Ipv4Addrs, domains.Ipv4Domains)
+	domains.Ipv6Stability = check("IPv6", domains.Ipv6Cache, &domains.Ipv6Addr, &domains.Ipv6Addrs, domains.Ipv6Domains)
 }
 
 // GetAllNewIpResult 获得getNewIp结果
 func (domains *Domains) GetAllNewIpResult(multiRecordType string) (results DomainTuples) {
-	ipv4Addr, ipv4Domains := domains.GetNewIpResult("A")
-	ipv6Addr, ipv6Domains := domains.GetNewIpResult("AAAA")
-	if ipv4Addr == "" && ipv6Addr == "" {
+	ipv4Addrs, ipv4Domains := domains.getNewIpsResult("A")
+	ipv6Addrs, ipv6Domains := domains.getNewIpsResult("AAAA")
+	if len(ipv4Addrs) == 0 && len(ipv6Addrs) == 0 {
 		return
 	}
 	cap := 0
-	if ipv4Addr != "" {
+	if len(ipv4Addrs) > 0 {
 		cap += len(ipv4Domains)
 	}
-	if ipv6Addr != "" {
+	if len(ipv6Addrs) > 0 {
 		cap += len(ipv6Domains)
 	}
 
 	results = make(DomainTuples, cap)
-	results.append(ipv4Addr, ipv4Domains, multiRecordType, DomainTuple{RecordType: "A", Ipv4Addr: domains.Ipv4Addr, Ipv6Addr: domains.Ipv6Addr})
-	results.append(ipv6Addr, ipv6Domains, multiRecordType, DomainTuple{RecordType: "AAAA", Ipv4Addr: domains.Ipv4Addr, Ipv6Addr: domains.Ipv6Addr})
+	results.append(ipv4Addrs, domains.Ipv4Addrs != nil, ipv4Domains, multiRecordType, DomainTuple{RecordType: "A", Ipv4Addr: domains.Ipv4Addr, Ipv6Addr: domains.Ipv6Addr})
+	results.append(ipv6Addrs, domains.Ipv6Addrs != nil, ipv6Domains, multiRecordType, DomainTuple{RecordType: "AAAA", Ipv4Addr: domains.Ipv4Addr, Ipv6Addr: domains.Ipv6Addr})
 	return
 }
 
-// append 添加域名到域名元组映射
-func (domains DomainTuples) append(ipAddr string, retDomains []*Domain, multiRecordType string, template DomainTuple) {
-	if ipAddr == "" {
+// append 添加域名到域名元组映射, multi 为 true 时记录全部地址
+func (domains DomainTuples) append(ipAddrs []string, multi bool, retDomains []*Domain, multiRecordType string, template DomainTuple) {
+	if len(ipAddrs) == 0 {
 		return
 	}
 
+	var ipAddrSet []string
+	if multi {
+		ipAddrSet = ipAddrs
+	}
 	for _, domain := range retDomains {
 		domainStr := domain.String()
-		if tuple, ok := domains[domainStr]; ok {
+		tuple, ok := domains[domainStr]
+		if ok {
 			if tuple.RecordType != template.RecordType {
 				tuple.RecordType = multiRecordType
 			}
-			tuple.Primary = domain
-			tuple.Domains = append(tuple.Domains, domain)
-			tuple.IpAddrs = append(tuple.IpAddrs, ipAddr)
 		} else {
-			tuple := template
-			domains[domainStr] = &tuple
-			tuple.Primary = domain
-			tuple.Domains = []*Domain{domain}
-			tuple.IpAddrs = []string{ipAddr}
+			tuple = &DomainTuple{}
+			*tuple = template
+			domains[domainStr] = tuple
+		}
+		if template.RecordType == "A" {
+			tuple.Ipv4Addr = domain.HostAddr(template.Ipv4Addr)
+		} else {
+			tuple.Ipv6Addr = domain.HostAddr(template.Ipv6Addr)
 		}
+		tuple.Primary = domain
+		tuple.Domains = append(tuple.Domains, domain)
+		tuple.IpAddrs = append(tuple.IpAddrs, domain.HostAddr(ipAddrs[0]))
+		tuple.IpAddrSets = append(tuple.IpAddrSets, domain.HostAddrs(ipAddrSet))
 	}
 }
 
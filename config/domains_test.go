@@ -1,6 +1,11 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
 
 // TestToASCII test converts the name of [Domain] to its ASCII form.
 //
@@ -83,3 +88,73 @@ func TestParseDomainArr(t *testing.T) {
 	}
 
 }
+
+// TestGetAllNewIpResultMultiAddr 测试多地址的域名元组及缓存
+func TestGetAllNewIpResultMultiAddr(t *testing.T) {
+	domain := &Domain{DomainName: "example.com", SubDomain: "www"}
+	domains := Domains{
+		Ipv4Addr:    "192.0.2.1",
+		Ipv4Addrs:   []string{"192.0.2.1", "192.0.2.2"},
+		Ipv4Cache:   &util.IpCache{},
+		Ipv4Domains: []*Domain{domain},
+		Ipv6Cache:   &util.IpCache{},
+	}
+
+	tuple := domains.GetAllNewIpResult("A/AAAA")[domain.String()]
+	if tuple == nil || len(tuple.IpAddrSets) != 1 {
+		t.Fatalf("tuple = %+v, want one address set", tuple)
+	}
+	if tuple.IpAddrs[0] != "192.0.2.1" || len(tuple.IpAddrSets[0]) != 2 {
+		t.Errorf("IpAddrs = %v, IpAddrSets = %v", tuple.IpAddrs, tuple.IpAddrSets)
+	}
+
+	// 顺序变化不视为地址改变
+	domains.Ipv4Addrs = []string{"192.0.2.2", "192.0.2.1"}
+	if ipAddr, _ := domains.GetNewIpResult("A"); ipAddr != "" {
+		t.Errorf("reordered addresses should hit the cache, got %q", ipAddr)
+	}
+}
+
+// TestLimitAddrs 测试地址去重及 MultiAddr
+func TestLimitAddrs(t *testing.T) {
+	addrs := []string{"192.0.2.1", "", "192.0.2.1", "192.0.2.2"}
+	if got := limitAddrs(addrs, true); len(got) != 2 || got[0] != "192.0.2.1" || got[1] != "192.0.2.2" {
+		t.Errorf("limitAddrs(multi) = %v", got)
+	}
+	if got := limitAddrs(addrs, false); len(got) != 1 || got[0] != "192.0.2.1" {
+		t.Errorf("limitAddrs(single) = %v", got)
+	}
+}
+
+// TestCheckStable 测试地址稳定前不更新
+func TestCheckStable(t *testing.T) {
+	domain := &Domain{DomainName: "example.com"}
+	cache := &util.IpCache{}
+	conf := &DnsConfig{StableTimes: "2"}
+	now := time.Now()
+	run := func(addr string) *Domains {
+		domains := &Domains{Ipv4Addr: addr, Ipv4Cache: cache, Ipv4Domains: []*Domain{domain}, Ipv6Cache: &util.IpCache{}}
+		domains.checkStable(conf, now)
+		return domains
+	}
+
+	if ipAddr, _ := run("192.0.2.1").GetNewIpResult("A"); ipAddr != "192.0.2.1" {
+		t.Fatalf("first address = %q, want it to be updated", ipAddr)
+	}
+
+	domains := run("192.0.2.2")
+	if !domains.Ipv4Stability.Waiting || domains.Ipv4Addr != "" {
+		t.Fatalf("unstable address: %+v, Ipv4Addr = %q", domains.Ipv4Stability, domains.Ipv4Addr)
+	}
+	if ipAddr, _ := domains.GetNewIpResult("A"); ipAddr != "" || cache.Addr != "192.0.2.1" {
+		t.Errorf("unstable address should not be updated, got %q, cache %q", ipAddr, cache.Addr)
+	}
+
+	domains = run("192.0.2.2")
+	if !domains.Ipv4Stability.Changed {
+		t.Fatalf("stable address: %+v", domains.Ipv4Stability)
+	}
+	if ipAddr, _ := domains.GetNewIpResult("A"); ipAddr != "192.0.2.2" {
+		t.Errorf("stable address = %q, want it to be updated", ipAddr)
+	}
+}
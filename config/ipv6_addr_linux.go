@@ -0,0 +1,76 @@
+//go:build linux
+
+package config
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ipv6AddrInfos 通过 netlink 获取网卡的IPv6地址及其标志和生存期
+func ipv6AddrInfos(ifaceName string) ([]IPv6AddrInfo, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETADDR, syscall.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, err
+	}
+	return parseIfAddrMessages(msgs, iface.Index), nil
+}
+
+// parseIfAddrMessages 解析 RTM_NEWADDR 消息, 只返回指定网卡的IPv6地址
+func parseIfAddrMessages(msgs []syscall.NetlinkMessage, index int) (infos []IPv6AddrInfo) {
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Header.Type != unix.RTM_NEWADDR || len(m.Data) < unix.SizeofIfAddrmsg {
+			continue
+		}
+		family, ifaFlags := m.Data[0], uint32(m.Data[2])
+		if family != unix.AF_INET6 || int(binary.NativeEndian.Uint32(m.Data[4:8])) != index {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(m)
+		if err != nil {
+			continue
+		}
+
+		info := IPv6AddrInfo{PreferredLft: LifetimeForever, ValidLft: LifetimeForever}
+		for _, attr := range attrs {
+			switch attr.Attr.Type {
+			case unix.IFA_ADDRESS:
+				if len(attr.Value) == net.IPv6len {
+					info.IP = net.IP(attr.Value)
+				}
+			case unix.IFA_FLAGS:
+				// 扩展的标志, 包含 IFA_F_MANAGETEMPADDR 等超过8位的标志
+				if len(attr.Value) >= 4 {
+					ifaFlags = binary.NativeEndian.Uint32(attr.Value)
+				}
+			case unix.IFA_CACHEINFO:
+				if len(attr.Value) >= 8 {
+					info.PreferredLft = binary.NativeEndian.Uint32(attr.Value[0:4])
+					info.ValidLft = binary.NativeEndian.Uint32(attr.Value[4:8])
+				}
+			}
+		}
+		if info.IP == nil {
+			continue
+		}
+		info.Temporary = ifaFlags&unix.IFA_F_TEMPORARY != 0
+		info.Deprecated = ifaFlags&unix.IFA_F_DEPRECATED != 0
+		info.Tentative = ifaFlags&unix.IFA_F_TENTATIVE != 0
+		info.DadFailed = ifaFlags&unix.IFA_F_DADFAILED != 0
+		info.MngTmpAddr = ifaFlags&unix.IFA_F_MANAGETEMPADDR != 0
+		infos = append(infos, info)
+	}
+	return
+}
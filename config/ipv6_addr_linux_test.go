@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// ifAddrMessage 构造 RTM_NEWADDR 消息
+func ifAddrMessage(family byte, index uint32, flags byte, attrs map[uint16][]byte) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofIfAddrmsg)
+	data[0] = family
+	data[2] = flags
+	binary.NativeEndian.PutUint32(data[4:], index)
+	for _, typ := range []uint16{unix.IFA_ADDRESS, unix.IFA_CACHEINFO, unix.IFA_FLAGS} {
+		value, ok := attrs[typ]
+		if !ok {
+			continue
+		}
+		attr := make([]byte, unix.SizeofRtAttr+len(value))
+		binary.NativeEndian.PutUint16(attr[0:], uint16(len(attr)))
+		binary.NativeEndian.PutUint16(attr[2:], typ)
+		copy(attr[unix.SizeofRtAttr:], value)
+		for len(attr)%4 != 0 {
+			attr = append(attr, 0)
+		}
+		data = append(data, attr...)
+	}
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: unix.RTM_NEWADDR}, Data: data}
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	return b
+}
+
+func TestParseIfAddrMessages(t *testing.T) {
+	cacheInfo := make([]byte, unix.SizeofIfaCacheinfo)
+	binary.NativeEndian.PutUint32(cacheInfo[0:], 1800)
+	binary.NativeEndian.PutUint32(cacheInfo[4:], 3600)
+
+	msgs := []syscall.NetlinkMessage{
+		ifAddrMessage(unix.AF_INET6, 2, unix.IFA_F_TEMPORARY, map[uint16][]byte{
+			unix.IFA_ADDRESS:   net.ParseIP("2001:db8::1"),
+			unix.IFA_CACHEINFO: cacheInfo,
+		}),
+		// IFA_FLAGS 优先于消息头中的标志
+		ifAddrMessage(unix.AF_INET6, 2, 0, map[uint16][]byte{
+			unix.IFA_ADDRESS: net.ParseIP("2001:db8::2"),
+			unix.IFA_FLAGS:   u32(unix.IFA_F_MANAGETEMPADDR | unix.IFA_F_DEPRECATED),
+		}),
+		// 其他网卡
+		ifAddrMessage(unix.AF_INET6, 3, 0, map[uint16][]byte{
+			unix.IFA_ADDRESS: net.ParseIP("2001:db8::3"),
+		}),
+		ifAddrMessage(unix.AF_INET, 2, 0, map[uint16][]byte{
+			unix.IFA_ADDRESS: net.ParseIP("192.0.2.1").To4(),
+		}),
+	}
+
+	infos := parseIfAddrMessages(msgs, 2)
+	if len(infos) != 2 {
+		t.Fatalf("got %d addresses, want 2: %+v", len(infos), infos)
+	}
+	if !infos[0].IP.Equal(net.ParseIP("2001:db8::1")) || !infos[0].Temporary ||
+		infos[0].PreferredLft != 1800 || infos[0].ValidLft != 3600 {
+		t.Errorf("unexpected first address %+v", infos[0])
+	}
+	if !infos[1].IP.Equal(net.ParseIP("2001:db8::2")) || infos[1].Temporary || !infos[1].MngTmpAddr ||
+		!infos[1].Deprecated || infos[1].PreferredLft != LifetimeForever {
+		t.Errorf("unexpected second address %+v", infos[1])
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package config
+
+import "net"
+
+// ipv6AddrInfos 非Linux系统无法获取地址的标志和生存期, 只返回地址
+func ipv6AddrInfos(ifaceName string) ([]IPv6AddrInfo, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var infos []IPv6AddrInfo
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() == nil {
+			infos = append(infos, IPv6AddrInfo{IP: ipnet.IP, PreferredLft: LifetimeForever, ValidLft: LifetimeForever})
+		}
+	}
+	return infos, nil
+}
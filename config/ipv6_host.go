@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// ipv6SuffixParam 域名参数, 局域网主机的接口标识, 可填写IPv6形式的后缀或MAC地址(按EUI-64生成)
+	ipv6SuffixParam = "Ipv6Suffix"
+	// ipv6PrefixLenParam 域名参数, 从获取到的地址中保留的前缀长度, 默认为 64
+	ipv6PrefixLenParam   = "Ipv6PrefixLen"
+	defaultIpv6PrefixLen = 64
+)
+
+// parseIpv6Host 从自定义参数中取出局域网主机的IPv6后缀和前缀长度
+// 这两个参数只由 ddns-go 使用, 取出后不再传给DNS服务商
+func parseIpv6Host(params url.Values) (suffix net.IP, prefixLen int, err error) {
+	s := params.Get(ipv6SuffixParam)
+	l := params.Get(ipv6PrefixLenParam)
+	params.Del(ipv6SuffixParam)
+	params.Del(ipv6PrefixLenParam)
+	if s == "" {
+		if l != "" {
+			return nil, 0, fmt.Errorf("%s requires %s", ipv6PrefixLenParam, ipv6SuffixParam)
+		}
+		return nil, 0, nil
+	}
+
+	prefixLen = defaultIpv6PrefixLen
+	if l != "" {
+		prefixLen, err = strconv.Atoi(l)
+		if err != nil || prefixLen <= 0 || prefixLen >= 128 {
+			return nil, 0, fmt.Errorf("invalid %s %q", ipv6PrefixLenParam, l)
+		}
+	}
+
+	if mac, err := net.ParseMAC(s); err == nil && len(mac) == 6 {
+		return eui64Suffix(mac), prefixLen, nil
+	}
+	suffix = net.ParseIP(s)
+	if suffix == nil || suffix.To4() != nil {
+		return nil, 0, fmt.Errorf("invalid %s %q", ipv6SuffixParam, s)
+	}
+	return suffix, prefixLen, nil
+}
+
+// eui64Suffix 由MAC地址生成 EUI-64 接口标识 (RFC 4291 附录A)
+func eui64Suffix(mac net.HardwareAddr) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	ip[8] = mac[0] ^ 0x02
+	ip[9], ip[10] = mac[1], mac[2]
+	ip[11], ip[12] = 0xff, 0xfe
+	ip[13], ip[14], ip[15] = mac[3], mac[4], mac[5]
+	return ip
+}
+
+// HostAddr 设置了 NeighborMac 时返回从邻居表中找到的地址
+// 设置了 Ipv6Suffix 时, 将 ipAddr 的前缀与域名的后缀组成局域网主机的地址, 否则返回 ipAddr
+func (d Domain) HostAddr(ipAddr string) string {
+	if d.neighborMac != nil {
+		if len(d.neighborAddrs) == 0 {
+			return ""
+		}
+		return d.neighborAddrs[0]
+	}
+	if d.ipv6Suffix == nil {
+		return ipAddr
+	}
+	ip := net.ParseIP(ipAddr)
+	if ip == nil || ip.To4() != nil {
+		return ipAddr
+	}
+	mask := net.CIDRMask(d.ipv6PrefixLen, 128)
+	host := make(net.IP, net.IPv6len)
+	for i := range host {
+		host[i] = ip[i]&mask[i] | d.ipv6Suffix[i]&^mask[i]
+	}
+	return host.String()
+}
+
+// HostAddrs 对每个地址使用 HostAddr, 并去除前缀相同而得到的重复地址
+// 设置了 NeighborMac 时返回从邻居表中找到的全部地址, 设备有多个地址时即使未启用 MultiAddr 也全部使用
+func (d Domain) HostAddrs(ipAddrs []string) []string {
+	if d.neighborMac != nil {
+		if ipAddrs != nil || len(d.neighborAddrs) > 1 {
+			return d.neighborAddrs
+		}
+		return nil
+	}
+	if d.ipv6Suffix == nil || ipAddrs == nil {
+		return ipAddrs
+	}
+	result := make([]string, 0, len(ipAddrs))
+	seen := map[string]bool{}
+	for _, ipAddr := range ipAddrs {
+		host := d.HostAddr(ipAddr)
+		if !seen[host] {
+			seen[host] = true
+			result = append(result, host)
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// TestParseIpv6Host 测试解析 Ipv6Suffix/Ipv6PrefixLen 参数
+func TestParseIpv6Host(t *testing.T) {
+	domains := checkParseDomains([]string{
+		"nas.example.com?Ipv6Suffix=::1234&record_line=默认",
+		"pc.example.com?Ipv6Suffix=00:11:22:33:44:55&Ipv6PrefixLen=56",
+		"bad.example.com?Ipv6Suffix=1.2.3.4",
+		"bad2.example.com?Ipv6Suffix=::1&Ipv6PrefixLen=128",
+		"bad3.example.com?Ipv6PrefixLen=56",
+	})
+	if len(domains) != 2 {
+		t.Fatalf("got %d domains, want 2", len(domains))
+	}
+	// 参数不再传给DNS服务商
+	if domains[0].CustomParams != "record_line=%E9%BB%98%E8%AE%A4" {
+		t.Errorf("CustomParams = %q", domains[0].CustomParams)
+	}
+	if domains[0].ipv6PrefixLen != 64 || domains[1].ipv6PrefixLen != 56 {
+		t.Errorf("prefix lengths = %d, %d", domains[0].ipv6PrefixLen, domains[1].ipv6PrefixLen)
+	}
+
+	tests := []struct {
+		domain *Domain
+		ipAddr string
+		want   string
+	}{
+		{domains[0], "2001:db8:1:2:aaaa:bbbb:cccc:dddd", "2001:db8:1:2::1234"},
+		{domains[1], "2001:db8:1:2:aaaa:bbbb:cccc:dddd", "2001:db8:1:0:211:22ff:fe33:4455"},
+		{domains[0], "192.0.2.1", "192.0.2.1"},
+		{&Domain{DomainName: "example.com"}, "2001:db8::1", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		if got := tt.domain.HostAddr(tt.ipAddr); got != tt.want {
+			t.Errorf("%s HostAddr(%s) = %s, want %s", tt.domain, tt.ipAddr, got, tt.want)
+		}
+	}
+
+	got := domains[0].HostAddrs([]string{"2001:db8:1:2::1", "2001:db8:1:2::2", "2001:db8:1:3::1"})
+	if want := []string{"2001:db8:1:2::1234", "2001:db8:1:3::1234"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HostAddrs = %v, want %v", got, want)
+	}
+}
+
+// TestGetAllNewIpResultIpv6Suffix 测试域名元组使用局域网主机的地址
+func TestGetAllNewIpResultIpv6Suffix(t *testing.T) {
+	ds := checkParseDomains([]string{"router.example.com", "nas.example.com?Ipv6Suffix=::1234"})
+	domains := Domains{
+		Ipv4Cache:   &util.IpCache{},
+		Ipv6Addr:    "2001:db8::1",
+		Ipv6Cache:   &util.IpCache{},
+		Ipv6Domains: ds,
+	}
+
+	tuples := domains.GetAllNewIpResult("A/AAAA")
+	if got := tuples["router.example.com"].IpAddrs[0]; got != "2001:db8::1" {
+		t.Errorf("router address = %s", got)
+	}
+	nas := tuples["nas.example.com"]
+	if nas.IpAddrs[0] != "2001:db8::1234" || nas.GetIpAddrPool(",") != "2001:db8::1234" {
+		t.Errorf("nas address = %s, pool = %s", nas.IpAddrs[0], nas.GetIpAddrPool(","))
+	}
+}
+
+// TestIpv6HostDomainsOnly 测试全部域名都设置了 Ipv6Suffix 时才使用 FRITZ!Box 的前缀
+func TestIpv6HostDomainsOnly(t *testing.T) {
+	tests := []struct {
+		domains []string
+		want    bool
+	}{
+		{nil, false},
+		{[]string{"nas.example.com?Ipv6Suffix=::1234", "", "pc.example.com?TTL=60&Ipv6Suffix=00:11:22:33:44:55"}, true},
+		{[]string{"nas.example.com?Ipv6Suffix=::1234", "router.example.com"}, false},
+	}
+	for _, tt := range tests {
+		conf := &DnsConfig{}
+		conf.Ipv6.Domains = tt.domains
+		if got := conf.ipv6HostDomainsOnly(); got != tt.want {
+			t.Errorf("ipv6HostDomainsOnly(%v) = %v, want %v", tt.domains, got, tt.want)
+		}
+	}
+}
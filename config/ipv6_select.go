@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+)
+
+// LifetimeForever 永久有效的地址的生存期
+const LifetimeForever = math.MaxUint32
+
+// IPv6AddrInfo 网卡上的IPv6地址及其状态, 非Linux系统无法获取状态
+type IPv6AddrInfo struct {
+	IP net.IP
+	// Temporary 隐私扩展生成的临时地址
+	Temporary bool
+	// Deprecated 首选生存期已过, 不应再用于新连接
+	Deprecated bool
+	// Tentative 正在进行重复地址检测
+	Tentative bool
+	// DadFailed 重复地址检测失败
+	DadFailed bool
+	// MngTmpAddr 用于生成临时地址的稳定地址
+	MngTmpAddr bool
+	// PreferredLft 和 ValidLft 首选和有效生存期(秒), LifetimeForever 表示永久
+	PreferredLft uint32
+	ValidLft     uint32
+}
+
+// ipv6Selector 网卡IPv6地址的选择策略
+type ipv6Selector struct {
+	// stable 排除临时地址
+	stable bool
+	// longest 按首选生存期从长到短排序
+	longest bool
+	// eui64 只使用由MAC地址生成的地址
+	eui64 bool
+	// noULA 排除唯一本地地址 fc00::/7
+	noULA bool
+	// cidrs 只使用其中任意一个网段内的地址
+	cidrs []*net.IPNet
+}
+
+// parseIpv6Select 解析选择策略, 多个用逗号分隔: stable, longest, eui64, noula, cidr=2001:db8::/48
+func parseIpv6Select(s string) (sel ipv6Selector, err error) {
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		name, value, _ := strings.Cut(item, "=")
+		switch strings.ToLower(name) {
+		case "":
+		case "stable":
+			sel.stable = true
+		case "longest":
+			sel.longest = true
+		case "eui64":
+			sel.eui64 = true
+		case "noula":
+			sel.noULA = true
+		case "cidr":
+			_, cidr, err := net.ParseCIDR(strings.TrimSpace(value))
+			if err != nil || cidr.IP.To4() != nil {
+				return sel, fmt.Errorf("invalid IPv6 CIDR %q", value)
+			}
+			sel.cidrs = append(sel.cidrs, cidr)
+		default:
+			return sel, fmt.Errorf("unknown IPv6 selection %q", item)
+		}
+	}
+	return sel, nil
+}
+
+// CheckIpv6Select 检查选择策略是否有效
+func CheckIpv6Select(s string) error {
+	_, err := parseIpv6Select(s)
+	return err
+}
+
+// ulaNet 唯一本地地址 fc00::/7
+var ulaNet = &net.IPNet{IP: net.ParseIP("fc00::"), Mask: net.CIDRMask(7, 128)}
+
+// selectAddrs 按策略筛选并排序地址
+// 始终排除链路本地地址、已弃用的地址以及未通过或正在进行重复地址检测的地址
+func (sel ipv6Selector) selectAddrs(infos []IPv6AddrInfo) (result []IPv6AddrInfo) {
+	for _, info := range infos {
+		if !info.IP.IsGlobalUnicast() || info.IP.To4() != nil ||
+			info.Deprecated || info.Tentative || info.DadFailed {
+			continue
+		}
+		if sel.stable && info.Temporary {
+			continue
+		}
+		if sel.eui64 && !isEUI64(info.IP) {
+			continue
+		}
+		if sel.noULA && ulaNet.Contains(info.IP) {
+			continue
+		}
+		if len(sel.cidrs) > 0 && !containsIP(sel.cidrs, info.IP) {
+			continue
+		}
+		result = append(result, info)
+	}
+	if sel.longest {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].PreferredLft > result[j].PreferredLft
+		})
+	}
+	return
+}
+
+// isEUI64 接口标识是否由MAC地址生成, 即第 12、13 字节为 ff:fe
+func isEUI64(ip net.IP) bool {
+	ip = ip.To16()
+	return ip != nil && ip[11] == 0xff && ip[12] == 0xfe
+}
+
+func containsIP(cidrs []*net.IPNet, ip net.IP) bool {
+	for _, cidr := range cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectIpv6Addrs 按选择策略获取网卡上的IPv6地址
+func (conf *DnsConfig) selectIpv6Addrs() ([]string, error) {
+	sel, err := parseIpv6Select(conf.Ipv6.Select)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ipv6AddrInfos(conf.Ipv6.NetInterface)
+	if err != nil {
+		return nil, err
+	}
+	selected := sel.selectAddrs(infos)
+	if len(selected) == 0 {
+		return nil, errors.New("no IPv6 address matches the selection")
+	}
+	addrs := make([]string, 0, len(selected))
+	for _, info := range selected {
+		addrs = append(addrs, info.IP.String())
+	}
+	return addrs, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseIpv6Select(t *testing.T) {
+	sel, err := parseIpv6Select(" stable, LONGEST,noula, cidr=2001:db8::/32 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sel.stable || !sel.longest || !sel.noULA || sel.eui64 || len(sel.cidrs) != 1 {
+		t.Errorf("unexpected selector %+v", sel)
+	}
+
+	for _, s := range []string{"unknown", "cidr=", "cidr=10.0.0.0/8", "cidr=2001:db8::"} {
+		if _, err := parseIpv6Select(s); err == nil {
+			t.Errorf("%q should be invalid", s)
+		}
+	}
+}
+
+func TestSelectAddrs(t *testing.T) {
+	infos := []IPv6AddrInfo{
+		{IP: net.ParseIP("fe80::1"), PreferredLft: LifetimeForever},
+		{IP: net.ParseIP("2001:db8::1234:5678"), Temporary: true, PreferredLft: 3600},
+		{IP: net.ParseIP("2001:db8::211:22ff:fe33:4455"), MngTmpAddr: true, PreferredLft: 1800},
+		{IP: net.ParseIP("2001:db8::dead"), Deprecated: true, PreferredLft: 0},
+		{IP: net.ParseIP("2001:db8::beef"), Tentative: true, PreferredLft: LifetimeForever},
+		{IP: net.ParseIP("2001:db8::bad"), DadFailed: true, PreferredLft: LifetimeForever},
+		{IP: net.ParseIP("fd00::1"), PreferredLft: LifetimeForever},
+		{IP: net.ParseIP("2001:db9::1"), PreferredLft: 7200},
+	}
+
+	tests := []struct {
+		sel  string
+		want []string
+	}{
+		{"", []string{"2001:db8::1234:5678", "2001:db8::211:22ff:fe33:4455", "fd00::1", "2001:db9::1"}},
+		{"stable", []string{"2001:db8::211:22ff:fe33:4455", "fd00::1", "2001:db9::1"}},
+		{"stable,noula,longest", []string{"2001:db9::1", "2001:db8::211:22ff:fe33:4455"}},
+		{"longest", []string{"fd00::1", "2001:db9::1", "2001:db8::1234:5678", "2001:db8::211:22ff:fe33:4455"}},
+		{"eui64", []string{"2001:db8::211:22ff:fe33:4455"}},
+		{"cidr=2001:db8::/32", []string{"2001:db8::1234:5678", "2001:db8::211:22ff:fe33:4455"}},
+		{"cidr=2001:db9::/32,cidr=fd00::/8", []string{"fd00::1", "2001:db9::1"}},
+		{"cidr=2001:db7::/32", nil},
+	}
+	for _, tt := range tests {
+		sel, err := parseIpv6Select(tt.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, info := range sel.selectAddrs(infos) {
+			got = append(got, info.IP.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.sel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// neighborMacParam 域名参数, 从邻居表中查找该MAC地址使用的地址作为域名的地址
+const neighborMacParam = "NeighborMac"
+
+var (
+	// neighborTable 可在测试中替换
+	neighborTable = util.Neighbors
+	// neighborSeen 设备最后一次在邻居表中出现时的地址, key 为 MAC 地址和地址类型
+	neighborSeen   = map[string]neighborSeenAddrs{}
+	neighborSeenMu sync.Mutex
+)
+
+type neighborSeenAddrs struct {
+	addrs []string
+	at    time.Time
+}
+
+// parseNeighborMac 从自定义参数中取出 NeighborMac, 取出后不再传给DNS服务商
+func parseNeighborMac(params url.Values) (net.HardwareAddr, error) {
+	s := params.Get(neighborMacParam)
+	params.Del(neighborMacParam)
+	if s == "" {
+		return nil, nil
+	}
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", neighborMacParam, s)
+	}
+	return mac, nil
+}
+
+// resolveNeighbors 从邻居表中查找设置了 NeighborMac 的域名的地址
+// 设备暂时不在邻居表中时沿用上次的地址, 超过 util.NeighborStaleAge 未出现则视为离线, 不再更新该域名
+func resolveNeighbors(ds []*Domain, ipv6 bool) {
+	var table []util.Neighbor
+	var tableErr error
+	loaded := false
+	addrType := "IPv4"
+	if ipv6 {
+		addrType = "IPv6"
+	}
+
+	neighborSeenMu.Lock()
+	defer neighborSeenMu.Unlock()
+	now := time.Now()
+	for _, d := range ds {
+		if d.neighborMac == nil {
+			continue
+		}
+		if !loaded {
+			table, tableErr = neighborTable()
+			loaded = true
+			if tableErr != nil {
+				util.Log("读取邻居表失败! 异常信息: %s", tableErr)
+			}
+		}
+
+		key := d.neighborMac.String() + " " + addrType
+		d.neighborAddrs = util.NeighborAddrs(table, d.neighborMac, ipv6)
+		if len(d.neighborAddrs) > 0 {
+			neighborSeen[key] = neighborSeenAddrs{addrs: d.neighborAddrs, at: now}
+			continue
+		}
+		seen, ok := neighborSeen[key]
+		if ok && now.Sub(seen.at) <= util.NeighborStaleAge {
+			d.neighborAddrs = seen.addrs
+			util.Log("邻居表中未找到 %s 的%s地址, 将沿用上次的地址 %s", d.neighborMac, addrType, strings.Join(seen.addrs, ","))
+			continue
+		}
+		util.Log("邻居表中未找到 %s 的%s地址, 将不会更新域名 %s", d.neighborMac, addrType, d)
+	}
+}
+
+// neighborCacheKey 邻居域名的地址, 加入 IpCache 的键中, 使设备地址改变时也会更新
+func neighborCacheKey(ds []*Domain) string {
+	var b strings.Builder
+	for _, d := range ds {
+		if d.neighborMac != nil {
+			b.WriteString(" " + d.String() + "=" + addrCacheKey(d.neighborAddrs))
+		}
+	}
+	return b.String()
+}
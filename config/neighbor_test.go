@@ -0,0 +1,89 @@
+package config
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// TestResolveNeighbors 测试从邻居表查找地址, 及设备离线后沿用上次的地址
+func TestResolveNeighbors(t *testing.T) {
+	origTable := neighborTable
+	defer func() {
+		neighborTable = origTable
+		neighborSeen = map[string]neighborSeenAddrs{}
+	}()
+
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	table := []util.Neighbor{
+		{IP: net.ParseIP("2001:db8::10"), MAC: mac},
+		{IP: net.ParseIP("2001:db8::11"), MAC: mac},
+	}
+	neighborTable = func() ([]util.Neighbor, error) { return table, nil }
+
+	ds := checkParseDomains([]string{"nas.example.com?NeighborMac=aa:bb:cc:dd:ee:ff&ttl=600", "router.example.com"})
+	if len(ds) != 2 || ds[0].CustomParams != "ttl=600" {
+		t.Fatalf("unexpected domains %+v", ds)
+	}
+	domains := Domains{Ipv4Cache: &util.IpCache{}, Ipv6Addr: "2001:db8::1", Ipv6Cache: &util.IpCache{}, Ipv6Domains: ds}
+
+	resolveNeighbors(ds, true)
+	tuples := domains.GetAllNewIpResult("A/AAAA")
+	nas := tuples["nas.example.com"]
+	if nas == nil || nas.IpAddrs[0] != "2001:db8::10" || len(nas.IpAddrSets[0]) != 2 {
+		t.Fatalf("nas tuple = %+v", nas)
+	}
+	if tuples["router.example.com"].IpAddrs[0] != "2001:db8::1" {
+		t.Errorf("router address = %s", tuples["router.example.com"].IpAddrs[0])
+	}
+
+	// 设备地址改变时, 即使本机地址未变也会更新
+	table = table[1:]
+	resolveNeighbors(ds, true)
+	if ipAddr, retDomains := domains.GetNewIpResult("AAAA"); ipAddr == "" || len(retDomains) != 2 {
+		t.Errorf("neighbor change should bypass the cache, got %q %v", ipAddr, retDomains)
+	}
+
+	// 暂时不在邻居表中时沿用上次的地址
+	table = nil
+	resolveNeighbors(ds, true)
+	if got := ds[0].HostAddr(""); got != "2001:db8::11" {
+		t.Errorf("HostAddr = %q, want the last seen address", got)
+	}
+
+	// 超过 NeighborStaleAge 后不再更新该域名
+	key := mac.String() + " IPv6"
+	neighborSeen[key] = neighborSeenAddrs{addrs: neighborSeen[key].addrs, at: time.Now().Add(-util.NeighborStaleAge - time.Minute)}
+	resolveNeighbors(ds, true)
+	if got := pendingDomains(ds); len(got) != 1 || got[0] != ds[1] {
+		t.Errorf("pendingDomains = %v, want only router.example.com", got)
+	}
+}
+
+// TestGetAllNewIpResultNeighborIpv4 测试A记录的域名元组使用邻居表中的IPv4地址
+func TestGetAllNewIpResultNeighborIpv4(t *testing.T) {
+	origTable := neighborTable
+	defer func() {
+		neighborTable = origTable
+		neighborSeen = map[string]neighborSeenAddrs{}
+	}()
+
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	neighborTable = func() ([]util.Neighbor, error) {
+		return []util.Neighbor{{IP: net.ParseIP("192.168.1.10"), MAC: mac}}, nil
+	}
+
+	ds := checkParseDomains([]string{"nas.example.com?NeighborMac=aa:bb:cc:dd:ee:ff", "router.example.com"})
+	domains := Domains{Ipv4Addr: "203.0.113.1", Ipv4Cache: &util.IpCache{}, Ipv4Domains: ds, Ipv6Cache: &util.IpCache{}}
+
+	resolveNeighbors(ds, false)
+	tuples := domains.GetAllNewIpResult("A/AAAA")
+	if got := tuples["nas.example.com"].GetIpAddrPool(","); got != "192.168.1.10" {
+		t.Errorf("nas address = %q, want 192.168.1.10", got)
+	}
+	if got := tuples["router.example.com"].GetIpAddrPool(","); got != "203.0.113.1" {
+		t.Errorf("router address = %q, want 203.0.113.1", got)
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// newAuthoritativeDNS 可在测试中替换
+var newAuthoritativeDNS = util.NewAuthoritativeDNS
+
+// precheck 请求DNS服务商前先查询域名当前解析的地址, 与获取到的地址一致的域名不再请求DNS服务商
+func (domains *Domains) precheck(ctx context.Context, dnsConf *DnsConfig) {
+	if !dnsConf.Precheck {
+		return
+	}
+
+	// 同一区域的权威DNS只查询一次
+	auth := newAuthoritativeDNS()
+	check := func(ds []*Domain, network string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		for _, d := range ds {
+			values := d.HostAddrs(values)
+			answer, err := precheckLookup(ctx, auth, strings.TrimSpace(dnsConf.PrecheckResolver), network, d.LookupName())
+			switch {
+			case err != nil:
+				util.Log("预检查: 查询域名 %s 失败, 将请求DNS服务商! 异常信息: %s", d, err)
+			case util.SameAddrs(answer, values):
+				d.precheckMatched = true
+				util.Log("预检查: 域名 %s 已解析到 %s, 跳过请求DNS服务商", d, strings.Join(values, ","))
+			default:
+				util.Log("预检查: 域名 %s 解析到 %s, 与 %s 不一致, 将请求DNS服务商", d, ipsString(answer), strings.Join(values, ","))
+			}
+		}
+	}
+	check(domains.Ipv4Domains, "ip4", addrSet(domains.Ipv4Addr, domains.Ipv4Addrs))
+	check(domains.Ipv6Domains, "ip6", addrSet(domains.Ipv6Addr, domains.Ipv6Addrs))
+}
+
+// precheckLookup 向指定的DNS服务器或域名的权威DNS查询地址
+// 查询权威DNS时, 各权威DNS的结果不一致则返回第一个与其他不同的结果, 以便请求DNS服务商
+func precheckLookup(ctx context.Context, auth *util.AuthoritativeDNS, resolver, network, name string) ([]net.IP, error) {
+	if resolver != "" {
+		return auth.LookupIP(ctx, resolver, network, name)
+	}
+
+	servers, err := auth.Servers(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var first []net.IP
+	var lastErr error
+	answered := false
+	for _, server := range servers {
+		answer, err := auth.LookupIP(ctx, server, network, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if answered && !util.SameIPs(first, answer) {
+			return answer, nil
+		}
+		first, answered = answer, true
+	}
+	if !answered {
+		return nil, lastErr
+	}
+	return first, nil
+}
+
+func ipsString(ips []net.IP) string {
+	if len(ips) == 0 {
+		return "-"
+	}
+	s := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+	return strings.Join(s, ",")
+}
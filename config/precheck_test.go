@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestPrecheck(t *testing.T) {
+	t.Cleanup(func() { newAuthoritativeDNS = util.NewAuthoritativeDNS })
+
+	answers := map[string]map[string][]net.IP{
+		"ns1": {
+			"a.example.com":             {net.ParseIP("1.1.1.1")},
+			"b.example.com":             {net.ParseIP("2.2.2.2")},
+			"ddns-go-check.example.com": {net.ParseIP("1.1.1.1")},
+		},
+		"ns2": {
+			"a.example.com":             {net.ParseIP("1.1.1.1")},
+			"b.example.com":             {net.ParseIP("2.2.2.2")},
+			"ddns-go-check.example.com": {net.ParseIP("3.3.3.3")},
+		},
+	}
+	nsQueries := 0
+	newAuthoritativeDNS = func() *util.AuthoritativeDNS {
+		return &util.AuthoritativeDNS{
+			LookupNS: func(ctx context.Context, name string) (string, []string, error) {
+				nsQueries++
+				return "example.com", []string{"ns1", "ns2"}, nil
+			},
+			LookupIPAt: func(ctx context.Context, server, network, name string) ([]net.IP, error) {
+				if ips, ok := answers[server][name]; ok {
+					return ips, nil
+				}
+				return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+			},
+		}
+	}
+
+	domains := &Domains{
+		Ipv4Addr:  "1.1.1.1",
+		Ipv4Cache: &util.IpCache{},
+		Ipv4Domains: []*Domain{
+			{DomainName: "example.com", SubDomain: "a"},
+			{DomainName: "example.com", SubDomain: "b"},
+			{DomainName: "example.com", SubDomain: "*"},
+			{DomainName: "example.com", SubDomain: "c"},
+		},
+	}
+	domains.precheck(context.Background(), &DnsConfig{Precheck: true})
+	if nsQueries != 1 {
+		t.Errorf("NS queries = %d, want 1 for a single zone", nsQueries)
+	}
+
+	_, got := domains.getNewIpsResult("A")
+	var names []string
+	for _, d := range got {
+		names = append(names, d.String())
+	}
+	want := []string{"b.example.com", "*.example.com", "c.example.com"}
+	if len(names) != len(want) {
+		t.Fatalf("getNewIpsResult() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("getNewIpsResult() = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestPrecheckDisabled(t *testing.T) {
+	domains := &Domains{
+		Ipv4Addr:    "1.1.1.1",
+		Ipv4Domains: []*Domain{{DomainName: "example.com"}},
+	}
+	// 未启用时不会查询
+	domains.precheck(context.Background(), &DnsConfig{})
+	if domains.Ipv4Domains[0].precheckMatched {
+		t.Error("precheck() matched with Precheck disabled")
+	}
+}
+
+func TestLookupName(t *testing.T) {
+	if got := (Domain{DomainName: "example.com", SubDomain: "*"}).LookupName(); got != "ddns-go-check.example.com" {
+		t.Errorf("LookupName() = %q", got)
+	}
+	if got := (Domain{DomainName: "example.com", SubDomain: "www"}).LookupName(); got != "www.example.com" {
+		t.Errorf("LookupName() = %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSplitURLSources(t *testing.T) {
+	tests := []struct {
+		urls string
+		want []string
+	}{
+		{"https://a/ip, https://b/ip,", []string{"https://a/ip", "https://b/ip"}},
+		{`https://a/ip#regex=\d{1,3}(\.\d{1,3}){3}, https://b/ip`, []string{`https://a/ip#regex=\d{1,3}(\.\d{1,3}){3}`, "https://b/ip"}},
+		{"https://a/ip#header=Accept:%20a,%20b,HTTP://b/ip#json=ip", []string{"https://a/ip#header=Accept:%20a,%20b", "HTTP://b/ip#json=ip"}},
+		{"https://a/ip?x=1,2", []string{"https://a/ip?x=1", "2"}},
+	}
+	for _, tt := range tests {
+		if got := SplitURLSources(tt.urls); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitURLSources(%q) = %q, want %q", tt.urls, got, tt.want)
+		}
+	}
+}
+
+func TestURLSourceExtract(t *testing.T) {
+	jsonBody := `{"proxy":"10.0.0.1","client":"1.2.3.4","data":{"addrs":["2001:db8::1","2001:db8::2"],"a.b":"5.6.7.8"}}`
+	xmlBody := `<response><proxy>10.0.0.1</proxy><client addr="9.9.9.9"><ip> 1.2.3.4 </ip></client></response>`
+
+	tests := []struct {
+		url  string
+		body string
+		want string
+	}{
+		{"http://x/#json=client", jsonBody, "1.2.3.4"},
+		{"http://x/#json=$.data.addrs[1]", jsonBody, "2001:db8::2"},
+		{"http://x/#json=data.addrs.0", jsonBody, "2001:db8::1"},
+		{"http://x/#json=data['a.b']", jsonBody, "5.6.7.8"},
+		{"http://x/#json=data.a\\.b", jsonBody, "5.6.7.8"},
+		{"http://x/#json=data", jsonBody, `{"a.b":"5.6.7.8","addrs":["2001:db8::1","2001:db8::2"]}`},
+		{"http://x/#xml=ip", xmlBody, "1.2.3.4"},
+		{"http://x/#xml=/response/client/@addr", xmlBody, "9.9.9.9"},
+		{"http://x/#regex=client.:.(?P<ip>[0-9.]+)", jsonBody, "1.2.3.4"},
+		{"http://x/#regex=\"client\":\"(\\d+\\.\\d+\\.\\d+\\.\\d+)", jsonBody, "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		src, err := parseURLSource(tt.url)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.url, err)
+		}
+		if src.url != "http://x/" || src.extract == nil {
+			t.Fatalf("%s: url = %s", tt.url, src.url)
+		}
+		got, err := src.extract([]byte(tt.body))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	for _, url := range []string{"http://x/#json=missing", "http://x/#json=data.addrs.5", "http://x/#xml=missing", "http://x/#regex=nomatch"} {
+		src, _ := parseURLSource(url)
+		if got, err := src.extract([]byte(jsonBody)); err == nil {
+			t.Errorf("%s: got %q, want error", url, got)
+		}
+	}
+
+	// 其他锚点视为URL的一部分
+	if src, _ := parseURLSource("http://x/#section"); src.url != "http://x/#section" || src.extract != nil {
+		t.Errorf("unexpected source %+v", src)
+	}
+	for _, url := range []string{"http://x/#json=a&xml=b", "http://x/#regex=(", "http://x/#header=novalue"} {
+		if _, err := parseURLSource(url); err == nil {
+			t.Errorf("%s should be invalid", url)
+		}
+	}
+}
+
+func TestGetAddrFromUrlHeaders(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.Header.Get("X-Token") != "a b+c" || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"proxy":"10.0.0.1","client":"1.2.3.4"}`))
+	}))
+	defer s.Close()
+
+	src, err := parseURLSource("http://admin:secret@" + s.Listener.Addr().String() + "/#json=client&header=X-Token:%20a%20b+c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.redacted != "http://admin:xxxxx@"+s.Listener.Addr().String()+"/" {
+		t.Errorf("redacted = %s", src.redacted)
+	}
+	if got, ok := getAddrFromUrl(context.Background(), http.DefaultClient, src, "IPv4"); !ok || got != "1.2.3.4" {
+		t.Errorf("getAddrFromUrl() = %q, %v", got, ok)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jeessy2/ddns-go/v6/util"
@@ -30,8 +32,28 @@ const (
 	UpdatedSuccess = "成功"
 )
 
-// 更新失败次数
-var updatedFailedTimes = 0
+// publishStatusType 更新后在权威DNS中的验证结果
+type publishStatusType string
+
+const (
+	// Published 已在权威DNS中生效
+	Published publishStatusType = "已生效"
+	// NotPropagated 超时仍未在权威DNS中生效
+	NotPropagated publishStatusType = "未生效"
+)
+
+// 各配置连续更新失败的次数, 以 ExecWebhook 的 key 为键, 多个配置会并发调用 ExecWebhook
+var (
+	updatedFailedTimes   = map[string]int{}
+	updatedFailedTimesMu sync.Mutex
+)
+
+const (
+	// WebhookEventUpdate 域名更新成功或失败
+	WebhookEventUpdate = "update"
+	// WebhookEventCGNAT 获取的IPv4地址是运营商级NAT地址
+	WebhookEventCGNAT = "cgnat"
+)
 
 // hasJSONPrefix returns true if the string starts with a JSON open brace.
 func hasJSONPrefix(s string) bool {
@@ -39,69 +61,110 @@ func hasJSONPrefix(s string) bool {
 }
 
 // ExecWebhook 添加或更新IPv4/IPv6记录, 返回是否有更新失败的
-func ExecWebhook(domains *Domains, conf *Config) (v4Status updateStatusType, v6Status updateStatusType) {
+// key 区分不同的配置, 每个配置单独计算失败次数
+func ExecWebhook(domains *Domains, conf *Config, key string) (v4Status updateStatusType, v6Status updateStatusType) {
 	v4Status = getDomainsStatus(domains.Ipv4Domains)
 	v6Status = getDomainsStatus(domains.Ipv6Domains)
 
+	pruneFailedTimes(conf, key)
+
 	if conf.WebhookURL != "" && (v4Status != UpdatedNothing || v6Status != UpdatedNothing) {
-		// 第3次失败才触发一次webhook
+		// 第3次失败才触发一次webhook, 认证失败等重试也不会成功的错误立即触发
 		if v4Status == UpdatedFailed || v6Status == UpdatedFailed {
-			updatedFailedTimes++
-			if updatedFailedTimes != 3 {
-				util.Log("将不会触发Webhook, 仅在第 3 次失败时触发一次Webhook, 当前失败次数：%d", updatedFailedTimes)
+			updatedFailedTimesMu.Lock()
+			updatedFailedTimes[key]++
+			failedTimes := updatedFailedTimes[key]
+			updatedFailedTimesMu.Unlock()
+			permanent := getDomainsError(domains.Ipv4Domains, true) != "" || getDomainsError(domains.Ipv6Domains, true) != ""
+			if failedTimes != 3 && !permanent {
+				util.Log("将不会触发Webhook, 仅在第 3 次失败时触发一次Webhook, 当前失败次数：%d", failedTimes)
 				return
 			}
 		} else {
-			updatedFailedTimes = 0
+			updatedFailedTimesMu.Lock()
+			delete(updatedFailedTimes, key)
+			updatedFailedTimesMu.Unlock()
 		}
 
 		// 成功和失败都要触发webhook
-		method := "GET"
-		postPara := ""
-		timestamp := strconv.FormatInt(time.Now().UTC().Unix(), 10)
-		contentType := "application/x-www-form-urlencoded"
-		if conf.WebhookRequestBody != "" {
-			method = "POST"
-			postPara = replacePara(domains, conf.WebhookRequestBody, v4Status, v6Status, timestamp)
-			if json.Valid([]byte(postPara)) {
-				contentType = "application/json"
-			} else if hasJSONPrefix(postPara) {
-				// 如果 RequestBody 的 JSON 无效但前缀为 JSON，提示无效
-				util.Log("Webhook中的 RequestBody JSON 无效")
-			}
-		}
-		requestURL := replacePara(domains, conf.WebhookURL, v4Status, v6Status, timestamp)
-		u, err := url.Parse(requestURL)
-		if err != nil {
-			util.Log("Webhook配置中的URL不正确")
-			return
-		}
+		sendWebhook(domains, conf, v4Status, v6Status, WebhookEventUpdate)
+	}
+	return
+}
 
-		q, _ := url.ParseQuery(u.RawQuery)
-		u.RawQuery = q.Encode()
+// pruneFailedTimes 清理已修改或删除的配置的失败次数
+// key 不属于 conf 时 (如测试Webhook) conf 不是完整的配置, 不清理
+func pruneFailedTimes(conf *Config, key string) {
+	keys := make(map[string]bool, len(conf.DnsConf))
+	for i := range conf.DnsConf {
+		keys[conf.DnsConf[i].Key()] = true
+	}
+	if !keys[key] {
+		return
+	}
 
-		req, err := http.NewRequest(method, u.String(), strings.NewReader(postPara))
-		if err != nil {
-			util.Log("Webhook调用失败! 异常信息：%s", err)
-			return
+	updatedFailedTimesMu.Lock()
+	defer updatedFailedTimesMu.Unlock()
+	for k := range updatedFailedTimes {
+		if !keys[k] {
+			delete(updatedFailedTimes, k)
 		}
+	}
+}
 
-		headers := extractHeaders(conf.WebhookHeaders)
-		for key, value := range headers {
-			req.Header.Add(key, value)
-		}
-		req.Header.Add("content-type", contentType)
+// ExecCGNATWebhook 发现获取的IPv4地址是运营商级NAT地址时触发webhook, #{event} 为 cgnat
+func ExecCGNATWebhook(domains *Domains, conf *Config) {
+	if conf.WebhookURL != "" {
+		sendWebhook(domains, conf, UpdatedNothing, UpdatedNothing, WebhookEventCGNAT)
+	}
+}
 
-		clt := util.CreateHTTPClient()
-		resp, err := clt.Do(req)
-		body, err := util.GetHTTPResponseOrg(resp, err)
-		if err == nil {
-			util.Log("Webhook调用成功! 返回数据：%s", string(body))
-		} else {
-			util.Log("Webhook调用失败! 异常信息：%s", err)
+// sendWebhook 替换变量并调用webhook
+func sendWebhook(domains *Domains, conf *Config, v4Status, v6Status updateStatusType, event string) {
+	method := "GET"
+	postPara := ""
+	timestamp := strconv.FormatInt(time.Now().UTC().Unix(), 10)
+	contentType := "application/x-www-form-urlencoded"
+	if conf.WebhookRequestBody != "" {
+		method = "POST"
+		postPara = replacePara(domains, conf.WebhookRequestBody, v4Status, v6Status, timestamp, event)
+		if json.Valid([]byte(postPara)) {
+			contentType = "application/json"
+		} else if hasJSONPrefix(postPara) {
+			// 如果 RequestBody 的 JSON 无效但前缀为 JSON，提示无效
+			util.Log("Webhook中的 RequestBody JSON 无效")
 		}
 	}
-	return
+	requestURL := replacePara(domains, conf.WebhookURL, v4Status, v6Status, timestamp, event)
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		util.Log("Webhook配置中的URL不正确")
+		return
+	}
+
+	q, _ := url.ParseQuery(u.RawQuery)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(method, u.String(), strings.NewReader(postPara))
+	if err != nil {
+		util.Log("Webhook调用失败! 异常信息：%s", err)
+		return
+	}
+
+	headers := extractHeaders(conf.WebhookHeaders)
+	for key, value := range headers {
+		req.Header.Add(key, value)
+	}
+	req.Header.Add("content-type", contentType)
+
+	clt := util.CreateHTTPClient()
+	resp, err := clt.Do(req)
+	body, err := util.GetHTTPResponseOrg(resp, err)
+	if err == nil {
+		util.Log("Webhook调用成功! 返回数据：%s", string(body))
+	} else {
+		util.Log("Webhook调用失败! 异常信息：%s", err)
+	}
 }
 
 // getDomainsStatus 获取域名状态
@@ -125,14 +188,20 @@ func getDomainsStatus(domains []*Domain) updateStatusType {
 }
 
 // replacePara 替换参数
-func replacePara(domains *Domains, orgPara string, ipv4Result updateStatusType, ipv6Result updateStatusType, timestamp string) string {
+func replacePara(domains *Domains, orgPara string, ipv4Result updateStatusType, ipv6Result updateStatusType, timestamp string, event string) string {
 	return strings.NewReplacer(
+		"#{event}", event,
+		"#{cgnatAddr}", domains.CGNATAddr,
 		"#{ipv4Addr}", domains.Ipv4Addr,
 		"#{ipv4Result}", util.LogStr(string(ipv4Result)), // i18n
 		"#{ipv4Domains}", getDomainsStr(domains.Ipv4Domains),
+		"#{ipv4Error}", getDomainsError(domains.Ipv4Domains, false),
+		"#{ipv4Publish}", util.LogStr(string(getDomainsPublish(domains.Ipv4Domains))), // i18n
 		"#{ipv6Addr}", domains.Ipv6Addr,
 		"#{ipv6Result}", util.LogStr(string(ipv6Result)), // i18n
 		"#{ipv6Domains}", getDomainsStr(domains.Ipv6Domains),
+		"#{ipv6Error}", getDomainsError(domains.Ipv6Domains, false),
+		"#{ipv6Publish}", util.LogStr(string(getDomainsPublish(domains.Ipv6Domains))), // i18n
 		"#{timestamp}", timestamp,
 	).Replace(orgPara)
 }
@@ -150,6 +219,37 @@ func getDomainsStr(domains []*Domain) string {
 	return str
 }
 
+// getDomainsPublish 获取域名的验证结果, 一个未生效则为未生效, 未验证时为空
+func getDomainsPublish(domains []*Domain) publishStatusType {
+	var status publishStatusType
+	for _, v46 := range domains {
+		switch v46.PublishStatus {
+		case NotPropagated:
+			return NotPropagated
+		case Published:
+			status = Published
+		}
+	}
+	return status
+}
+
+// getDomainsError 失败的域名的错误类型, 多个以逗号分割; permanentOnly 为 true 时只返回重试也不会成功的错误
+func getDomainsError(domains []*Domain, permanentOnly bool) string {
+	var kinds []string
+	for _, v46 := range domains {
+		if v46.UpdateStatus != UpdatedFailed || v46.ErrorKind == "" {
+			continue
+		}
+		if permanentOnly && !v46.ErrorKind.Permanent() {
+			continue
+		}
+		if !slices.Contains(kinds, string(v46.ErrorKind)) {
+			kinds = append(kinds, string(v46.ErrorKind))
+		}
+	}
+	return strings.Join(kinds, ",")
+}
+
 // extractHeaders converts s into a map of headers.
 //
 // See also: https://github.com/appleboy/gorush/blob/v1.17.0/notify/feedback.go#L15
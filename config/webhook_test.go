@@ -1,8 +1,13 @@
 package config
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync/atomic"
 	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/util"
 )
 
 // TestExtractHeaders 测试 parseHeaderArr
@@ -22,3 +27,85 @@ c: foo:bar`
 		t.Errorf("Expected %v, got %v", expected, parsedHeaders)
 	}
 }
+
+func TestGetDomainsError(t *testing.T) {
+	domains := []*Domain{
+		{DomainName: "a.com", UpdateStatus: UpdatedFailed, ErrorKind: util.ErrorTransient},
+		{DomainName: "b.com", UpdateStatus: UpdatedFailed, ErrorKind: util.ErrorAuth},
+		{DomainName: "c.com", UpdateStatus: UpdatedFailed, ErrorKind: util.ErrorAuth},
+		{DomainName: "d.com", UpdateStatus: UpdatedSuccess},
+	}
+	if got := getDomainsError(domains, false); got != "transient,auth" {
+		t.Errorf("getDomainsError() = %q, want %q", got, "transient,auth")
+	}
+	if got := getDomainsError(domains, true); got != "auth" {
+		t.Errorf("getDomainsError(permanentOnly) = %q, want %q", got, "auth")
+	}
+}
+
+func TestGetDomainsPublish(t *testing.T) {
+	tests := []struct {
+		domains []*Domain
+		want    publishStatusType
+	}{
+		{[]*Domain{{PublishStatus: Published}, {}}, Published},
+		{[]*Domain{{PublishStatus: Published}, {PublishStatus: NotPropagated}}, NotPropagated},
+		{[]*Domain{{}}, ""},
+	}
+	for _, tt := range tests {
+		if got := getDomainsPublish(tt.domains); got != tt.want {
+			t.Errorf("getDomainsPublish() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// TestExecWebhookFailedTimes 测试每个配置单独计算失败次数
+func TestExecWebhookFailedTimes(t *testing.T) {
+	t.Cleanup(func() { updatedFailedTimes = map[string]int{} })
+
+	var sent atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent.Add(1)
+	}))
+	defer server.Close()
+
+	conf := &Config{Webhook: Webhook{WebhookURL: server.URL}}
+	failed := func() *Domains {
+		return &Domains{Ipv4Domains: []*Domain{{DomainName: "example.com", UpdateStatus: UpdatedFailed}}}
+	}
+	// 两个配置交替失败, 各自第 3 次失败时触发
+	for i := 0; i < 3; i++ {
+		ExecWebhook(failed(), conf, "a")
+		ExecWebhook(failed(), conf, "b")
+	}
+	if sent.Load() != 2 {
+		t.Errorf("webhook sent %d times, want 2", sent.Load())
+	}
+
+	// 成功后重新计数
+	ExecWebhook(&Domains{Ipv4Domains: []*Domain{{DomainName: "example.com", UpdateStatus: UpdatedSuccess}}}, conf, "a")
+	if updatedFailedTimes["a"] != 0 || updatedFailedTimes["b"] != 3 {
+		t.Errorf("updatedFailedTimes = %v", updatedFailedTimes)
+	}
+}
+
+// TestExecWebhookPruneFailedTimes 测试清理已修改或删除的配置的失败次数
+func TestExecWebhookPruneFailedTimes(t *testing.T) {
+	t.Cleanup(func() { updatedFailedTimes = map[string]int{} })
+
+	conf := &Config{DnsConf: []DnsConfig{{Name: "a"}, {Name: "b"}}}
+	keyA, keyB := conf.DnsConf[0].Key(), conf.DnsConf[1].Key()
+	updatedFailedTimes = map[string]int{keyA: 1, keyB: 2, "removed": 2}
+	failed := &Domains{Ipv4Domains: []*Domain{{DomainName: "example.com", UpdateStatus: UpdatedFailed}}}
+
+	// 测试Webhook时的 key 不属于配置, 不清理
+	ExecWebhook(failed, &Config{}, "webhookTest")
+	if updatedFailedTimes["removed"] != 2 {
+		t.Errorf("updatedFailedTimes = %v, want unchanged", updatedFailedTimes)
+	}
+
+	ExecWebhook(failed, conf, keyA)
+	if _, ok := updatedFailedTimes["removed"]; ok || updatedFailedTimes[keyB] != 2 {
+		t.Errorf("updatedFailedTimes = %v, want only the removed config pruned", updatedFailedTimes)
+	}
+}
@@ -2,6 +2,8 @@ package dns
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"net/http"
 	"net/url"
 
@@ -20,6 +22,7 @@ type Alidns struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // AlidnsRecord record
@@ -43,12 +46,33 @@ type AlidnsResp struct {
 	RequestID string
 }
 
+func init() {
+	Register(Provider{
+		Key:   "alidns",
+		Order: 1,
+		Name: map[string]string{
+			"en":    "Aliyun",
+			"zh-cn": "阿里云",
+		},
+		IDLabel:     "AccessKey ID",
+		SecretLabel: "AccessKey Secret",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://ram.console.aliyun.com/manage/ak?spm=5176.12818093.nav-right.dak.488716d0mHaMgg'>Create AccessKey</a>",
+			"zh-cn": "<a target='_blank' href='https://ram.console.aliyun.com/manage/ak?spm=5176.12818093.nav-right.dak.488716d0mHaMgg'>创建 AccessKey</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{alidnsEndpoint},
+		New:        func() DNS { return &Alidns{} },
+	})
+}
+
 // Init 初始化
-func (ali *Alidns) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (ali *Alidns) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	ali.ctx = ctx
 	ali.Domains.Ipv4Cache = ipv4cache
 	ali.Domains.Ipv6Cache = ipv6cache
 	ali.DNS = dnsConf.DNS
-	ali.Domains.GetNewIp(dnsConf)
+	ali.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		ali.TTL = "600"
@@ -60,56 +84,31 @@ func (ali *Alidns) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (ali *Alidns) AddUpdateDomainRecords() config.Domains {
-	ali.addUpdateDomainRecords("A")
-	ali.addUpdateDomainRecords("AAAA")
+	Reconcile(ali.ctx, ali, ali.Domains.GetAllNewIpResult("A/AAAA"))
 	return ali.Domains
 }
 
-func (ali *Alidns) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := ali.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询记录
+func (ali *Alidns) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	var result AlidnsSubDomainRecords
+	params := domain.GetCustomParams()
+	params.Set("Action", "DescribeSubDomainRecords")
+	params.Set("DomainName", domain.DomainName)
+	params.Set("SubDomain", domain.GetFullDomain())
+	params.Set("Type", recordType)
+	err = ali.request(params, &result)
+	if err != nil {
 		return
 	}
 
-	for _, domain := range domains {
-		var records AlidnsSubDomainRecords
-		// 获取当前域名信息
-		params := domain.GetCustomParams()
-		params.Set("Action", "DescribeSubDomainRecords")
-		params.Set("DomainName", domain.DomainName)
-		params.Set("SubDomain", domain.GetFullDomain())
-		params.Set("Type", recordType)
-		err := ali.request(params, &records)
-
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		if records.TotalCount > 0 {
-			// 默认第一个
-			recordSelected := records.DomainRecords.Record[0]
-			if params.Has("RecordId") {
-				for i := 0; i < len(records.DomainRecords.Record); i++ {
-					if records.DomainRecords.Record[i].RecordID == params.Get("RecordId") {
-						recordSelected = records.DomainRecords.Record[i]
-					}
-				}
-			}
-			// 存在，更新
-			ali.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// 不存在，创建
-			ali.create(domain, recordType, ipAddr)
-		}
-
+	for _, r := range result.DomainRecords.Record {
+		records = append(records, Record{ID: r.RecordID, Type: recordType, Value: r.Value})
 	}
+	return
 }
 
-// 创建
-func (ali *Alidns) create(domain *config.Domain, recordType string, ipAddr string) {
+// CreateRecord 创建
+func (ali *Alidns) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	params := domain.GetCustomParams()
 	params.Set("Action", "AddDomainRecord")
 	params.Set("DomainName", domain.DomainName)
@@ -117,58 +116,40 @@ func (ali *Alidns) create(domain *config.Domain, recordType string, ipAddr strin
 	params.Set("Type", recordType)
 	params.Set("Value", ipAddr)
 	params.Set("TTL", ali.TTL)
-
-	var result AlidnsResp
-	err := ali.request(params, &result)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if result.RecordID != "" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, "返回RecordId为空")
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return ali.write(params)
 }
 
-// 修改
-func (ali *Alidns) modify(recordSelected AlidnsRecord, domain *config.Domain, recordType string, ipAddr string) {
-
-	// 相同不修改
-	if recordSelected.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
-
+// UpdateRecord 修改
+func (ali *Alidns) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
 	params := domain.GetCustomParams()
 	params.Set("Action", "UpdateDomainRecord")
 	params.Set("RR", domain.GetSubDomain())
-	params.Set("RecordId", recordSelected.RecordID)
-	params.Set("Type", recordType)
+	params.Set("RecordId", record.ID)
+	params.Set("Type", record.Type)
 	params.Set("Value", ipAddr)
 	params.Set("TTL", ali.TTL)
+	return ali.write(params)
+}
+
+// DeleteRecord 删除
+func (ali *Alidns) DeleteRecord(domain *config.Domain, record Record) error {
+	params := url.Values{}
+	params.Set("Action", "DeleteDomainRecord")
+	params.Set("RecordId", record.ID)
+	return ali.write(params)
+}
 
+// write 新增/修改/删除, 返回的 RecordId 为空时视为失败
+func (ali *Alidns) write(params url.Values) error {
 	var result AlidnsResp
 	err := ali.request(params, &result)
-
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
-
-	if result.RecordID != "" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, "返回RecordId为空")
-		domain.UpdateStatus = config.UpdatedFailed
+	if result.RecordID == "" {
+		return errors.New("返回RecordId为空")
 	}
+	return nil
 }
 
 // request 统一请求接口
@@ -176,7 +157,8 @@ func (ali *Alidns) request(params url.Values, result interface{}) (err error) {
 	method := http.MethodGet
 	util.AliyunSigner(ali.DNS.ID, ali.DNS.Secret, &params, method, "2015-01-09")
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ali.ctx,
 		method,
 		alidnsEndpoint,
 		bytes.NewBuffer(nil),
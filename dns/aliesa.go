@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -25,6 +27,7 @@ type Aliesa struct {
 	siteCache   map[string]AliesaSite
 	domainCache config.DomainTuples
 	httpClient  *http.Client
+	ctx         context.Context
 }
 
 // AliesaSiteResp 站点返回结果
@@ -62,12 +65,33 @@ type AliesaResp struct {
 	RequestID    string
 }
 
+func init() {
+	Register(Provider{
+		Key:   "aliesa",
+		Order: 2,
+		Name: map[string]string{
+			"en":    "Aliyun ESA",
+			"zh-cn": "阿里云 ESA",
+		},
+		IDLabel:     "AccessKey ID",
+		SecretLabel: "AccessKey Secret",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://ram.console.aliyun.com/manage/ak?spm=5176.12818093.nav-right.dak.488716d0mHaMgg'>Create AccessKey</a>",
+			"zh-cn": "<a target='_blank' href='https://ram.console.aliyun.com/manage/ak?spm=5176.12818093.nav-right.dak.488716d0mHaMgg'>创建 AccessKey</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{aliesaEndpoint},
+		New:        func() DNS { return &Aliesa{} },
+	})
+}
+
 // Init 初始化
-func (ali *Aliesa) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (ali *Aliesa) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	ali.ctx = ctx
 	ali.Domains.Ipv4Cache = ipv4cache
 	ali.Domains.Ipv6Cache = ipv6cache
 	ali.DNS = dnsConf.DNS
-	ali.Domains.GetNewIp(dnsConf)
+	ali.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		ali.TTL = "600"
@@ -141,6 +165,9 @@ func (ali *Aliesa) addUpdateDomainRecords(recordType string) {
 func (ali *Aliesa) create(site AliesaSite, domainTuple *config.DomainTuple, recordType string) {
 	domain := domainTuple.Primary
 	ipAddr := domainTuple.GetIpAddrPool(",")
+	if planRecord(ali.ctx, domain, recordType, PlanCreate, "", ipAddr) {
+		return
+	}
 
 	params := domain.GetCustomParams()
 	params.Set("Action", "CreateRecord")
@@ -184,10 +211,15 @@ func (ali *Aliesa) modify(record AliesaRecord, domainTuple *config.DomainTuple,
 	ipAddr := domainTuple.GetIpAddrPool(",")
 	// 相同不修改
 	if record.Data.Value == ipAddr {
+		planRecord(ali.ctx, domain, recordType, PlanNoop, record.Data.Value, ipAddr)
 		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
 		return
 	}
 
+	if planRecord(ali.ctx, domain, recordType, PlanUpdate, record.Data.Value, ipAddr) {
+		return
+	}
+
 	params := domain.GetCustomParams()
 	params.Set("Action", "UpdateRecord")
 	params.Set("RecordId", strconv.FormatInt(record.RecordId, 10))
@@ -314,6 +346,10 @@ func (ali *Aliesa) getOriginPool(site AliesaSite, domainTuple *config.DomainTupl
 func (ali *Aliesa) updateOriginPool(site AliesaSite, domainTuple *config.DomainTuple, id int64, origins []map[string]interface{}) {
 	needUpdate := false
 	count := len(domainTuple.Domains)
+	var currentAddrs []string
+	for _, origin := range origins {
+		currentAddrs = append(currentAddrs, fmt.Sprint(origin["Address"]))
+	}
 	for _, origin := range origins {
 		// 源地址池不能有多个相同地址，因此 Domain 更少放内层
 		for i, d := range domainTuple.Domains {
@@ -340,11 +376,17 @@ func (ali *Aliesa) updateOriginPool(site AliesaSite, domainTuple *config.DomainT
 		domainTuple.SetUpdateStatus(config.UpdatedFailed)
 		return
 	}
+	current := strings.Join(currentAddrs, ",")
 	if !needUpdate {
+		planRecord(ali.ctx, domain, domainTuple.RecordType, PlanNoop, current, ipAddr)
 		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
 		return
 	}
 
+	if planRecord(ali.ctx, domain, domainTuple.RecordType, PlanUpdate, current, ipAddr) {
+		return
+	}
+
 	originsData, _ := json.Marshal(origins)
 	params := url.Values{}
 	params.Set("Action", "UpdateOriginPool")
@@ -374,7 +416,8 @@ func (ali *Aliesa) updateOriginPool(site AliesaSite, domainTuple *config.DomainT
 func (ali *Aliesa) request(method string, params url.Values, result interface{}) (err error) {
 	util.AliyunSigner(ali.DNS.ID, ali.DNS.Secret, &params, method, "2024-09-10")
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ali.ctx,
 		method,
 		aliesaEndpoint,
 		bytes.NewBuffer(nil),
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -21,6 +22,7 @@ type BaiduCloud struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // BaiduRecord 单条解析记录
@@ -68,11 +70,32 @@ type BaiduCreateRequest struct {
 	ZoneName string `json:"zoneName"`
 }
 
-func (baidu *BaiduCloud) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "baiducloud",
+		Order: 8,
+		Name: map[string]string{
+			"en":    "Baidu",
+			"zh-cn": "百度云",
+		},
+		IDLabel:     "AccessKey ID",
+		SecretLabel: "AccessKey Secret",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.bce.baidu.com/iam/?_=1651763238057#/iam/accesslist'>Create AccessKey</a>",
+			"zh-cn": "<a target='_blank' href='https://console.bce.baidu.com/iam/?_=1651763238057#/iam/accesslist'>创建 AccessKey</a>",
+		},
+		DefaultTTL: 300,
+		Endpoints:  []string{baiduEndpoint},
+		New:        func() DNS { return &BaiduCloud{} },
+	})
+}
+
+func (baidu *BaiduCloud) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	baidu.ctx = ctx
 	baidu.Domains.Ipv4Cache = ipv4cache
 	baidu.Domains.Ipv6Cache = ipv6cache
 	baidu.DNS = dnsConf.DNS
-	baidu.Domains.GetNewIp(dnsConf)
+	baidu.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认300s
 		baidu.TTL = 300
@@ -89,51 +112,40 @@ func (baidu *BaiduCloud) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (baidu *BaiduCloud) AddUpdateDomainRecords() config.Domains {
-	baidu.addUpdateDomainRecords("A")
-	baidu.addUpdateDomainRecords("AAAA")
+	Reconcile(baidu.ctx, baidu, baidu.Domains.GetAllNewIpResult("A/AAAA"))
 	return baidu.Domains
 }
 
-func (baidu *BaiduCloud) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := baidu.Domains.GetNewIpResult(recordType)
-	if ipAddr == "" {
-		return
-	}
-
-	for _, domain := range domains {
-		var records BaiduRecordsResp
+// ListRecords 查询解析
+func (baidu *BaiduCloud) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	var result BaiduRecordsResp
 
-		requestBody := BaiduListRequest{
-			Domain:   domain.DomainName,
-			PageNum:  1,
-			PageSize: 1000,
-		}
+	requestBody := BaiduListRequest{
+		Domain:   domain.DomainName,
+		PageNum:  1,
+		PageSize: 1000,
+	}
 
-		err := baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/list", requestBody, &records)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
+	err = baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/list", requestBody, &result)
+	if err != nil {
+		return
+	}
 
-		find := false
-		for _, record := range records.Result {
-			if record.Domain == domain.GetSubDomain() {
-				//存在就去更新
-				baidu.modify(record, domain, recordType, ipAddr)
-				find = true
-				break
-			}
-		}
-		if !find {
-			//没找到，去创建
-			baidu.create(domain, recordType, ipAddr)
+	for _, r := range result.Result {
+		if r.Domain == domain.GetSubDomain() && r.Rdtype == recordType {
+			records = append(records, Record{
+				ID:    strconv.FormatUint(uint64(r.RecordId), 10),
+				Type:  r.Rdtype,
+				Value: r.Rdata,
+				Raw:   r,
+			})
 		}
 	}
+	return
 }
 
-// create 创建新的解析
-func (baidu *BaiduCloud) create(domain *config.Domain, recordType string, ipAddr string) {
+// CreateRecord 创建新的解析
+func (baidu *BaiduCloud) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	var baiduCreateRequest = BaiduCreateRequest{
 		Domain:   domain.GetSubDomain(), //处理一下@
 		RdType:   recordType,
@@ -142,43 +154,36 @@ func (baidu *BaiduCloud) create(domain *config.Domain, recordType string, ipAddr
 		ZoneName: domain.DomainName,
 	}
 	var result BaiduRecordsResp
-
-	err := baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/add", baiduCreateRequest, &result)
-	if err == nil {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/add", baiduCreateRequest, &result)
 }
 
-// modify 更新解析
-func (baidu *BaiduCloud) modify(record BaiduRecord, domain *config.Domain, rdType string, ipAddr string) {
-	//没有变化直接跳过
-	if record.Rdata == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// UpdateRecord 更新解析
+func (baidu *BaiduCloud) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	record := r.Raw.(BaiduRecord)
 	var baiduModifyRequest = BaiduModifyRequest{
 		RecordId: record.RecordId,
 		Domain:   record.Domain,
 		View:     record.View,
-		RdType:   rdType,
+		RdType:   record.Rdtype,
 		TTL:      record.TTL,
 		Rdata:    ipAddr,
 		ZoneName: record.ZoneName,
 	}
 	var result BaiduRecordsResp
+	return baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/edit", baiduModifyRequest, &result)
+}
 
-	err := baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/edit", baiduModifyRequest, &result)
-	if err == nil {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+// DeleteRecord 删除解析
+func (baidu *BaiduCloud) DeleteRecord(domain *config.Domain, r Record) error {
+	record := r.Raw.(BaiduRecord)
+	var result BaiduRecordsResp
+	return baidu.request("POST", baiduEndpoint+"/v1/domain/resolve/delete", struct {
+		ZoneName string `json:"zoneName"`
+		RecordId uint   `json:"recordId"`
+	}{
+		ZoneName: record.ZoneName,
+		RecordId: record.RecordId,
+	}, &result)
 }
 
 // request 统一请求接口
@@ -188,7 +193,8 @@ func (baidu *BaiduCloud) request(method string, url string, data interface{}, re
 		jsonStr, _ = json.Marshal(data)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		baidu.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
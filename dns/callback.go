@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -22,10 +23,30 @@ type Callback struct {
 	httpClient *http.Client
 	ipv4Enable bool
 	ipv6Enable bool
+	ctx        context.Context
+}
+
+func init() {
+	Register(Provider{
+		Key:   "callback",
+		Order: 7,
+		Name: map[string]string{
+			"en": "Callback",
+		},
+		IDLabel:     "URL",
+		SecretLabel: "RequestBody",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://github.com/jeessy2/ddns-go/blob/master/README_EN.md#callback'>Callback</a> Support variables #{ip}, #{domain}, #{recordType}, #{ttl}",
+			"zh-cn": "<a target='_blank' href='https://github.com/jeessy2/ddns-go#callback'>自定义回调</a> 支持的变量 #{ip}, #{domain}, #{recordType}, #{ttl}",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &Callback{} },
+	})
 }
 
 // Init 初始化
-func (cb *Callback) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (cb *Callback) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	cb.ctx = ctx
 	cb.Domains.Ipv4Cache = ipv4cache
 	cb.Domains.Ipv6Cache = ipv6cache
 	cb.lastIpv4 = ipv4cache.Addr
@@ -34,7 +55,7 @@ func (cb *Callback) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 	cb.ipv6Enable = dnsConf.Ipv6.Enable
 
 	cb.DNS = dnsConf.DNS
-	cb.Domains.GetNewIp(dnsConf)
+	cb.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600
 		cb.TTL = "600"
@@ -76,6 +97,12 @@ func (cb *Callback) addUpdateDomainRecords(recordType string) {
 	}
 
 	for _, domain := range domains {
+		// 设置了 Ipv6Suffix 的域名使用局域网主机的地址
+		ipAddr := domain.HostAddr(ipAddr)
+		// Callback 无法查询当前值
+		if planRecord(cb.ctx, domain, recordType, PlanUnknown, "", ipAddr) {
+			continue
+		}
 		method := "GET"
 		postPara := ""
 		contentType := "application/x-www-form-urlencoded"
@@ -92,7 +119,7 @@ func (cb *Callback) addUpdateDomainRecords(recordType string) {
 			util.Log("Callback的URL不正确")
 			return
 		}
-		req, err := http.NewRequest(method, u.String(), strings.NewReader(postPara))
+		req, err := http.NewRequestWithContext(cb.ctx, method, u.String(), strings.NewReader(postPara))
 		if err != nil {
 			util.Log("异常信息: %v", err)
 			domain.UpdateStatus = config.UpdatedFailed
@@ -0,0 +1,35 @@
+package dns
+
+import (
+	"sync"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+var (
+	// behindCGNAT 上次获取IPv4时是否发现运营商级NAT地址, 以配置的摘要为键
+	behindCGNAT   = map[string]bool{}
+	behindCGNATMu sync.Mutex
+)
+
+// cgnatDetected 返回是否刚发现处于运营商级NAT之后, 持续处于时只返回一次
+// 获取到非运营商级NAT的IPv4地址后重置, 未获取到地址时保持不变
+func cgnatDetected(dc *config.DnsConfig, domains *config.Domains) bool {
+	behindCGNATMu.Lock()
+	defer behindCGNATMu.Unlock()
+
+	key := configKey(dc)
+	switch {
+	case domains.CGNATAddr != "":
+		if behindCGNAT[key] {
+			return false
+		}
+		behindCGNAT[key] = true
+		util.Log("获取到的IPv4地址 %s 是运营商级NAT地址, 当前网络可能没有公网IPv4", domains.CGNATAddr)
+		return true
+	case domains.Ipv4Addr != "":
+		delete(behindCGNAT, key)
+	}
+	return false
+}
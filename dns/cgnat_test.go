@@ -0,0 +1,32 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+)
+
+func TestCGNATDetected(t *testing.T) {
+	t.Cleanup(func() { behindCGNAT = map[string]bool{} })
+
+	dc := &config.DnsConfig{Name: "test"}
+	steps := []struct {
+		domains config.Domains
+		want    bool
+	}{
+		{config.Domains{CGNATAddr: "100.64.0.1"}, true},
+		// 持续处于CGNAT之后时只触发一次
+		{config.Domains{CGNATAddr: "100.64.0.2", Ipv4Addr: "100.64.0.2"}, false},
+		// 未获取到地址时保持不变
+		{config.Domains{}, false},
+		{config.Domains{CGNATAddr: "100.64.0.1"}, false},
+		// 获取到公网地址后重置
+		{config.Domains{Ipv4Addr: "1.1.1.1"}, false},
+		{config.Domains{CGNATAddr: "100.64.0.1"}, true},
+	}
+	for i, step := range steps {
+		if got := cgnatDetected(dc, &step.domains); got != step.want {
+			t.Errorf("step %d: cgnatDetected() = %v, want %v", i, got, step.want)
+		}
+	}
+}
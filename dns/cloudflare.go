@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -21,6 +23,9 @@ type Cloudflare struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
+	// zoneIDs 根域名对应的 zone id 缓存
+	zoneIDs map[string]string
 }
 
 // CloudflareZonesResp cloudflare zones返回结果
@@ -37,7 +42,11 @@ type CloudflareZonesResp struct {
 // CloudflareRecordsResp records
 type CloudflareRecordsResp struct {
 	CloudflareStatus
-	Result []CloudflareRecord
+	Result     []CloudflareRecord
+	ResultInfo struct {
+		Page       int `json:"page"`
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
 }
 
 // CloudflareRecord 记录实体
@@ -57,12 +66,32 @@ type CloudflareStatus struct {
 	Messages []string
 }
 
+func init() {
+	Register(Provider{
+		Key:   "cloudflare",
+		Order: 5,
+		Name: map[string]string{
+			"en": "Cloudflare",
+		},
+		IDLabel:     "",
+		SecretLabel: "Token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://dash.cloudflare.com/profile/api-tokens'>Create Token -> Edit Zone DNS (Use template)</a>",
+			"zh-cn": "<a target='_blank' href='https://dash.cloudflare.com/profile/api-tokens'>创建令牌 -> 编辑区域 DNS (使用模板)</a>",
+		},
+		DefaultTTL: 1,
+		Endpoints:  []string{zonesAPI},
+		New:        func() DNS { return &Cloudflare{} },
+	})
+}
+
 // Init 初始化
-func (cf *Cloudflare) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (cf *Cloudflare) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	cf.ctx = ctx
 	cf.Domains.Ipv4Cache = ipv4cache
 	cf.Domains.Ipv6Cache = ipv6cache
 	cf.DNS = dnsConf.DNS
-	cf.Domains.GetNewIp(dnsConf)
+	cf.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认1 auto ttl
 		cf.TTL = 1
@@ -79,81 +108,74 @@ func (cf *Cloudflare) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, i
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (cf *Cloudflare) AddUpdateDomainRecords() config.Domains {
-	cf.addUpdateDomainRecords("A")
-	cf.addUpdateDomainRecords("AAAA")
+	Reconcile(cf.ctx, cf, cf.Domains.GetAllNewIpResult("A/AAAA"))
 	return cf.Domains
 }
 
-func (cf *Cloudflare) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := cf.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询记录, 会查询所有分页
+func (cf *Cloudflare) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	zoneID, err := cf.getZoneID(domain)
+	if err != nil {
 		return
 	}
 
-	for _, domain := range domains {
-		// get zone
-		result, err := cf.getZones(domain)
-
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		if len(result.Result) == 0 {
-			util.Log("在DNS服务商中未找到根域名: %s", domain.DomainName)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		params := url.Values{}
-		params.Set("type", recordType)
-		// The name of DNS records in Cloudflare API expects Punycode.
-		//
-		// See: cloudflare/cloudflare-go#690
-		params.Set("name", domain.ToASCII())
-		params.Set("per_page", "50")
-		// Add a comment only if it exists
-		if c := domain.GetCustomParams().Get("comment"); c != "" {
-			params.Set("comment", c)
-		}
-
-		zoneID := result.Result[0].ID
+	params := url.Values{}
+	params.Set("type", recordType)
+	// The name of DNS records in Cloudflare API expects Punycode.
+	//
+	// See: cloudflare/cloudflare-go#690
+	params.Set("name", domain.ToASCII())
+	params.Set("per_page", "50")
+	// Add a comment only if it exists
+	if c := domain.GetCustomParams().Get("comment"); c != "" {
+		params.Set("comment", c)
+	}
 
-		var records CloudflareRecordsResp
-		// getDomains 最多更新前50条
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+		var result CloudflareRecordsResp
 		err = cf.request(
 			"GET",
 			fmt.Sprintf(zonesAPI+"/%s/dns_records?%s", zoneID, params.Encode()),
 			nil,
-			&records,
+			&result,
 		)
-
 		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
+			return nil, err
 		}
-
-		if !records.Success {
-			util.Log("查询域名信息发生异常! %s", strings.Join(records.Messages, ", "))
-			domain.UpdateStatus = config.UpdatedFailed
-			return
+		if !result.Success {
+			return nil, errors.New(strings.Join(result.Messages, ", "))
 		}
 
-		if len(records.Result) > 0 {
-			// 更新
-			cf.modify(records, zoneID, domain, ipAddr)
-		} else {
-			// 新增
-			cf.create(zoneID, domain, recordType, ipAddr)
+		for _, r := range result.Result {
+			records = append(records, Record{ID: r.ID, Type: r.Type, Value: r.Content, Raw: r})
+		}
+		if page >= result.ResultInfo.TotalPages {
+			return records, nil
 		}
 	}
 }
 
-// 创建
-func (cf *Cloudflare) create(zoneID string, domain *config.Domain, recordType string, ipAddr string) {
+// RecordMatches IP相同, 且设置了 proxied 参数时代理状态也相同
+func (cf *Cloudflare) RecordMatches(domain *config.Domain, r Record, ipAddr string) bool {
+	customParams := domain.GetCustomParams()
+	if r.Value != ipAddr {
+		return false
+	}
+	if !customParams.Has("proxied") {
+		return true
+	}
+	record, _ := r.Raw.(CloudflareRecord)
+	return record.Proxied == (customParams.Get("proxied") == "true")
+}
+
+// CreateRecord 创建
+func (cf *Cloudflare) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	zoneID, err := cf.getZoneID(domain)
+	if err != nil {
+		return err
+	}
+
 	record := &CloudflareRecord{
 		Type:    recordType,
 		Name:    domain.ToASCII(),
@@ -163,46 +185,21 @@ func (cf *Cloudflare) create(zoneID string, domain *config.Domain, recordType st
 		Comment: domain.GetCustomParams().Get("comment"),
 	}
 	record.Proxied = domain.GetCustomParams().Get("proxied") == "true"
-	var status CloudflareStatus
-	err := cf.request(
-		"POST",
-		fmt.Sprintf(zonesAPI+"/%s/dns_records", zoneID),
-		record,
-		&status,
-	)
+	return cf.write("POST", fmt.Sprintf(zonesAPI+"/%s/dns_records", zoneID), record)
+}
 
+// UpdateRecord 修改
+func (cf *Cloudflare) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	zoneID, err := cf.getZoneID(domain)
 	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if status.Success {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, strings.Join(status.Messages, ", "))
-		domain.UpdateStatus = config.UpdatedFailed
+		return err
 	}
-}
 
-// 修改
-func (cf *Cloudflare) modify(result CloudflareRecordsResp, zoneID string, domain *config.Domain, ipAddr string) {
 	customParams := domain.GetCustomParams()
-	manageProxied := customParams.Has("proxied")
-	desiredProxied := customParams.Get("proxied") == "true"
-
-	for _, record := range result.Result {
-		ipChanged := record.Content != ipAddr
-		proxiedChanged := manageProxied && record.Proxied != desiredProxied
-
-		// 相同不修改
-		if !ipChanged && !proxiedChanged {
-			util.Log("DNS 记录没有变化, 域名 %s", domain)
-			continue
-		}
-
-		if proxiedChanged {
+	record := r.Raw.(CloudflareRecord)
+	if customParams.Has("proxied") {
+		desiredProxied := customParams.Get("proxied") == "true"
+		if record.Proxied != desiredProxied {
 			util.Log(
 				"Cloudflare 代理状态发生变化: %v -> %v! 域名 %s",
 				record.Proxied,
@@ -210,36 +207,54 @@ func (cf *Cloudflare) modify(result CloudflareRecordsResp, zoneID string, domain
 				domain,
 			)
 		}
+		record.Proxied = desiredProxied
+	}
+	record.Content = ipAddr
+	record.TTL = cf.TTL
+	return cf.write("PUT", fmt.Sprintf(zonesAPI+"/%s/dns_records/%s", zoneID, record.ID), record)
+}
 
-		var status CloudflareStatus
+// DeleteRecord 删除
+func (cf *Cloudflare) DeleteRecord(domain *config.Domain, r Record) error {
+	zoneID, err := cf.getZoneID(domain)
+	if err != nil {
+		return err
+	}
+	return cf.write("DELETE", fmt.Sprintf(zonesAPI+"/%s/dns_records/%s", zoneID, r.ID), nil)
+}
 
-		record.Content = ipAddr
-		record.TTL = cf.TTL
-		if manageProxied {
-			record.Proxied = desiredProxied
-		}
+// write 新增/修改/删除, Success 为 false 时返回错误
+func (cf *Cloudflare) write(method string, url string, data interface{}) error {
+	var status CloudflareStatus
+	err := cf.request(method, url, data, &status)
+	if err != nil {
+		return err
+	}
+	if !status.Success {
+		return errors.New(strings.Join(status.Messages, ", "))
+	}
+	return nil
+}
 
-		err := cf.request(
-			"PUT",
-			fmt.Sprintf(zonesAPI+"/%s/dns_records/%s", zoneID, record.ID),
-			record,
-			&status,
-		)
+// getZoneID 获得根域名的 zone id
+func (cf *Cloudflare) getZoneID(domain *config.Domain) (string, error) {
+	if zoneID, ok := cf.zoneIDs[domain.DomainName]; ok {
+		return zoneID, nil
+	}
 
-		if err != nil {
-			util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
+	result, err := cf.getZones(domain)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Result) == 0 {
+		return "", errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
+	}
 
-		if status.Success {
-			util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-			domain.UpdateStatus = config.UpdatedSuccess
-		} else {
-			util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, strings.Join(status.Messages, ", "))
-			domain.UpdateStatus = config.UpdatedFailed
-		}
+	if cf.zoneIDs == nil {
+		cf.zoneIDs = map[string]string{}
 	}
+	cf.zoneIDs[domain.DomainName] = result.Result[0].ID
+	return result.Result[0].ID, nil
 }
 
 // 获得域名记录列表
@@ -265,7 +280,8 @@ func (cf *Cloudflare) request(method string, url string, data interface{}, resul
 	if data != nil {
 		jsonStr, _ = json.Marshal(data)
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		cf.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
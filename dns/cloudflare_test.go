@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -59,31 +60,42 @@ func TestCloudflareModifyProxied(t *testing.T) {
 			var updatedRecord CloudflareRecord
 			client := &http.Client{
 				Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
-					requestCount++
-					if err := json.NewDecoder(request.Body).Decode(&updatedRecord); err != nil {
-						t.Fatalf("decode request body: %v", err)
+					body := `{"success":true}`
+					switch {
+					case request.Method == http.MethodGet && request.URL.Path == "/client/v4/zones":
+						body = `{"success":true,"result":[{"id":"zone-id"}]}`
+					case request.Method == http.MethodGet:
+						records, _ := json.Marshal(CloudflareRecordsResp{
+							CloudflareStatus: CloudflareStatus{Success: true},
+							Result: []CloudflareRecord{{
+								ID:      "record-id",
+								Type:    "A",
+								Content: tt.recordIP,
+								Proxied: tt.recordProxied,
+							}},
+						})
+						body = string(records)
+					default:
+						requestCount++
+						if err := json.NewDecoder(request.Body).Decode(&updatedRecord); err != nil {
+							t.Fatalf("decode request body: %v", err)
+						}
 					}
 					return &http.Response{
 						StatusCode: http.StatusOK,
-						Body:       io.NopCloser(strings.NewReader(`{"success":true}`)),
+						Body:       io.NopCloser(strings.NewReader(body)),
 						Header:     make(http.Header),
 					}, nil
 				}),
 			}
 
-			cf := Cloudflare{TTL: 1, httpClient: client}
+			cf := Cloudflare{TTL: 1, httpClient: client, ctx: context.Background()}
 			domain := &config.Domain{
 				DomainName:   "example.com",
 				SubDomain:    "test",
 				CustomParams: tt.customParams,
 			}
-			cf.modify(CloudflareRecordsResp{
-				Result: []CloudflareRecord{{
-					ID:      "record-id",
-					Content: tt.recordIP,
-					Proxied: tt.recordProxied,
-				}},
-			}, "zone-id", domain, tt.ipAddr)
+			reconcileRecord(cf.ctx, &cf, desiredRecord{domain: domain, recordType: "A", value: tt.ipAddr})
 
 			if got := requestCount > 0; got != tt.wantRequest {
 				t.Fatalf("request sent = %v, want %v", got, tt.wantRequest)
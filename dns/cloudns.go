@@ -1,8 +1,11 @@
 package dns
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/url"
+	"sort"
 
 	"github.com/jeessy2/ddns-go/v6/config"
 	"github.com/jeessy2/ddns-go/v6/util"
@@ -19,6 +22,7 @@ type ClouDNS struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // ClouDNSRecord record
@@ -35,12 +39,32 @@ type ClouDNSResp struct {
 	StatusDescription string `json:"statusDescription"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "cloudns",
+		Order: 28,
+		Name: map[string]string{
+			"en": "ClouDNS",
+		},
+		IDLabel:     "auth-id",
+		SecretLabel: "auth-password",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.cloudns.net/wiki/article/42/'>Create API user</a>",
+			"zh-cn": "<a target='_blank' href='https://www.cloudns.net/wiki/article/42/'>创建 API 用户</a>",
+		},
+		DefaultTTL: 3600,
+		Endpoints:  []string{CloudnsEndpoint},
+		New:        func() DNS { return &ClouDNS{} },
+	})
+}
+
 // Init 初始化
-func (cl *ClouDNS) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (cl *ClouDNS) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	cl.ctx = ctx
 	cl.Domains.Ipv4Cache = ipv4cache
 	cl.Domains.Ipv6Cache = ipv6cache
 	cl.DNS = dnsConf.DNS
-	cl.Domains.GetNewIp(dnsConf)
+	cl.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// Default 3600 (ClouDNS minimum for some plans)
 		cl.TTL = "3600"
@@ -52,123 +76,82 @@ func (cl *ClouDNS) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (cl *ClouDNS) AddUpdateDomainRecords() config.Domains {
-	cl.addUpdateDomainRecords("A")
-	cl.addUpdateDomainRecords("AAAA")
+	Reconcile(cl.ctx, cl, cl.Domains.GetAllNewIpResult("A/AAAA"))
 	return cl.Domains
 }
 
-func (cl *ClouDNS) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := cl.Domains.GetNewIpResult(recordType)
+// ListRecords Get current record information
+func (cl *ClouDNS) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	var result map[string]ClouDNSRecord
+	params := cl.authParams(domain)
+	params.Set("host", domain.GetSubDomain())
+	params.Set("type", recordType)
 
-	if ipAddr == "" {
+	err = cl.request("records.json", params, &result)
+	if err != nil {
 		return
 	}
 
-	for _, domain := range domains {
-		var records map[string]ClouDNSRecord
-		// Get current record information
-		params := url.Values{}
-		params.Set("auth-id", cl.DNS.ID)
-		params.Set("auth-password", cl.DNS.Secret)
-		params.Set("domain-name", domain.DomainName)
-		params.Set("host", domain.GetSubDomain())
-		params.Set("type", recordType)
-
-		err := cl.request("records.json", params, &records)
-		if err != nil {
-			util.Log("查询域名 %s 信息发生异常! %v", domain, err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		var recordSelected *ClouDNSRecord
-		if len(records) > 0 {
-			// Find the first record of the matching type and host
-			for _, r := range records {
-				if r.Type == recordType && r.Host == domain.GetSubDomain() {
-					temp := r
-					recordSelected = &temp
-					break
-				}
-			}
-		}
-
-		if recordSelected != nil {
-			// Exist, modify
-			cl.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// Not exist, create
-			cl.create(domain, recordType, ipAddr)
+	for _, r := range result {
+		if r.Type == recordType && r.Host == domain.GetSubDomain() {
+			records = append(records, Record{ID: r.ID, Type: r.Type, Value: r.Value})
 		}
 	}
+	// records.json returns an object, sort to keep the first record stable
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return
 }
 
-// create
-func (cl *ClouDNS) create(domain *config.Domain, recordType string, ipAddr string) {
-	params := url.Values{}
-	params.Set("auth-id", cl.DNS.ID)
-	params.Set("auth-password", cl.DNS.Secret)
-	params.Set("domain-name", domain.DomainName)
+// CreateRecord create
+func (cl *ClouDNS) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	params := cl.authParams(domain)
 	params.Set("host", domain.GetSubDomain())
 	params.Set("type", recordType)
 	params.Set("record", ipAddr)
 	params.Set("ttl", cl.TTL)
+	return cl.write("add-record.json", params)
+}
 
-	var result ClouDNSResp
-	err := cl.request("add-record.json", params, &result)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %v", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if result.Status == "Success" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, result.StatusDescription)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+// UpdateRecord modify
+func (cl *ClouDNS) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
+	params := cl.authParams(domain)
+	params.Set("record-id", record.ID)
+	params.Set("host", domain.GetSubDomain())
+	params.Set("record", ipAddr)
+	params.Set("ttl", cl.TTL)
+	return cl.write("modify-record.json", params)
 }
 
-// modify
-func (cl *ClouDNS) modify(recordSelected *ClouDNSRecord, domain *config.Domain, recordType string, ipAddr string) {
-	// Same, no change
-	if recordSelected.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// DeleteRecord delete
+func (cl *ClouDNS) DeleteRecord(domain *config.Domain, record Record) error {
+	params := cl.authParams(domain)
+	params.Set("record-id", record.ID)
+	return cl.write("delete-record.json", params)
+}
 
+func (cl *ClouDNS) authParams(domain *config.Domain) url.Values {
 	params := url.Values{}
 	params.Set("auth-id", cl.DNS.ID)
 	params.Set("auth-password", cl.DNS.Secret)
 	params.Set("domain-name", domain.DomainName)
-	params.Set("record-id", recordSelected.ID)
-	params.Set("host", domain.GetSubDomain())
-	params.Set("record", ipAddr)
-	params.Set("ttl", cl.TTL)
+	return params
+}
 
+// write returns an error unless the status is Success
+func (cl *ClouDNS) write(action string, params url.Values) error {
 	var result ClouDNSResp
-	err := cl.request("modify-record.json", params, &result)
-
+	err := cl.request(action, params, &result)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %v", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
-
-	if result.Status == "Success" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, result.StatusDescription)
-		domain.UpdateStatus = config.UpdatedFailed
+	if result.Status != "Success" {
+		return errors.New(result.StatusDescription)
 	}
+	return nil
 }
 
 // request
 func (cl *ClouDNS) request(action string, params url.Values, result interface{}) (err error) {
-	resp, err := cl.httpClient.PostForm(CloudnsEndpoint+action, params)
+	resp, err := util.PostForm(cl.ctx, cl.httpClient, CloudnsEndpoint+action, params)
 	return util.GetHTTPResponse(resp, err, result)
 }
@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -27,6 +29,7 @@ type DeSEC struct {
 	TTL        int
 	httpClient *http.Client
 	lastStatus int
+	ctx        context.Context
 }
 
 // DeSECRRSet RRSet记录实体
@@ -37,12 +40,33 @@ type DeSECRRSet struct {
 	TTL       int      `json:"ttl"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "desec",
+		Order: 29,
+		Name: map[string]string{
+			"en": "deSEC",
+		},
+		IDLabel:     "",
+		SecretLabel: "Token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://desec.io/tokens'>Create Token</a>",
+			"zh-cn": "<a target='_blank' href='https://desec.io/tokens'>创建令牌</a>",
+		},
+		MinTTL:     desecMinTTL,
+		DefaultTTL: 3600,
+		Endpoints:  []string{desecEndpoint},
+		New:        func() DNS { return &DeSEC{} },
+	})
+}
+
 // Init 初始化
-func (desec *DeSEC) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (desec *DeSEC) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	desec.ctx = ctx
 	desec.Domains.Ipv4Cache = ipv4cache
 	desec.Domains.Ipv6Cache = ipv6cache
 	desec.DNS = dnsConf.DNS
-	desec.Domains.GetNewIp(dnsConf)
+	desec.Domains.GetNewIp(ctx, dnsConf)
 	desec.TTL = desecMinTTL
 	if ttl, err := strconv.Atoi(dnsConf.TTL); err == nil {
 		if ttl > desecMinTTL {
@@ -57,45 +81,27 @@ func (desec *DeSEC) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (desec *DeSEC) AddUpdateDomainRecords() config.Domains {
-	desec.addUpdateDomainRecords("A")
-	desec.addUpdateDomainRecords("AAAA")
+	Reconcile(desec.ctx, desec, desec.Domains.GetAllNewIpResult("A/AAAA"))
 	return desec.Domains
 }
 
-func (desec *DeSEC) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := desec.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 按subname+type过滤查询，域名或记录不存在返回空数组，域名不存在返回404
+func (desec *DeSEC) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	rrsets, status, err := desec.getRRSets(domain.DomainName, domain.SubDomain, recordType)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
+		}
 		return
 	}
 
-	for _, domain := range domains {
-		// 按subname+type过滤查询，域名或记录不存在返回空数组，域名不存在返回404
-		rrsets, status, err := desec.getRRSets(domain.DomainName, domain.SubDomain, recordType)
-		if err != nil {
-			if status == http.StatusNotFound {
-				util.Log("在DNS服务商中未找到根域名: %s", domain.DomainName)
-			} else {
-				util.Log("查询域名信息发生异常! %s", err)
-			}
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		if len(rrsets) > 0 && len(rrsets[0].Records) > 0 && rrsets[0].Records[0] == ipAddr {
-			// ip与dns服务器一致，不执行更新
-			util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-			continue
-		}
-
-		if len(rrsets) > 0 {
-			// 更新记录
-			desec.modify(domain, recordType, ipAddr)
-		} else {
-			// 创建记录
-			desec.create(domain, recordType, ipAddr)
+	// 每个地址返回一条记录, 修改时整体替换 rrset
+	for _, rrset := range rrsets {
+		for _, value := range rrset.Records {
+			records = append(records, Record{ID: rrset.SubDomain, Type: rrset.Type, Value: value, Raw: rrset})
 		}
 	}
+	return
 }
 
 // getRRSets 按subname和type过滤查询rrsets
@@ -114,12 +120,31 @@ func (desec *DeSEC) getRRSets(zone string, subDomain string, recordType string)
 	return rrsets, desec.lastStatus, err
 }
 
-// create 创建新的解析
-func (desec *DeSEC) create(domain *config.Domain, recordType string, ipAddr string) {
+// CreateRecord 创建新的解析
+func (desec *DeSEC) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	return desec.create(domain, recordType, []string{ipAddr})
+}
+
+// SetRecords 将 rrset 替换为 values, 不存在时创建
+func (desec *DeSEC) SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error {
+	if len(records) == 0 {
+		if len(values) == 0 {
+			return nil
+		}
+		return desec.create(domain, recordType, values)
+	}
+	if values == nil {
+		values = []string{}
+	}
+	return desec.patch(domain, recordType, values)
+}
+
+// create 创建 rrset
+func (desec *DeSEC) create(domain *config.Domain, recordType string, values []string) error {
 	rrset := DeSECRRSet{
 		SubDomain: domain.SubDomain,
 		Type:      recordType,
-		Records:   []string{ipAddr},
+		Records:   values,
 		TTL:       desec.TTL,
 	}
 
@@ -130,26 +155,28 @@ func (desec *DeSEC) create(domain *config.Domain, recordType string, ipAddr stri
 		rrset,
 		&result,
 	)
+	return err
+}
 
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	}
+// UpdateRecord 更新解析
+func (desec *DeSEC) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	return desec.patch(domain, r.Type, []string{ipAddr})
+}
+
+// DeleteRecord 删除解析, records 为空时删除整个rrset
+func (desec *DeSEC) DeleteRecord(domain *config.Domain, r Record) error {
+	return desec.patch(domain, r.Type, []string{})
 }
 
-// modify 更新解析
-func (desec *DeSEC) modify(domain *config.Domain, recordType string, ipAddr string) {
+// patch 批量PATCH接口，可更新单个rrset，主域名的subname为空字符串
+func (desec *DeSEC) patch(domain *config.Domain, recordType string, records []string) error {
 	rrset := DeSECRRSet{
 		SubDomain: domain.SubDomain,
 		Type:      recordType,
-		Records:   []string{ipAddr},
+		Records:   records,
 		TTL:       desec.TTL,
 	}
 
-	// 批量PATCH接口，可更新单个rrset，主域名的subname为空字符串
 	var result []DeSECRRSet
 	_, err := desec.request(
 		"PATCH",
@@ -157,14 +184,7 @@ func (desec *DeSEC) modify(domain *config.Domain, recordType string, ipAddr stri
 		[]DeSECRRSet{rrset},
 		&result,
 	)
-
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	}
+	return err
 }
 
 // request 统一请求接口，返回响应状态码
@@ -174,7 +194,8 @@ func (desec *DeSEC) request(method string, url string, data interface{}, result
 		jsonStr, _ = json.Marshal(data)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		desec.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
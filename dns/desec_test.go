@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -107,7 +108,7 @@ func TestDesecAddUpdateDomainRecords(t *testing.T) {
 				}),
 			}
 
-			desec := DeSEC{TTL: 3600, httpClient: client}
+			desec := DeSEC{TTL: 3600, httpClient: client, ctx: context.Background()}
 			domain := &config.Domain{
 				DomainName: "example.com",
 				SubDomain:  tt.subDomain,
@@ -129,7 +130,7 @@ func TestDesecAddUpdateDomainRecords(t *testing.T) {
 				ipv6Cache.Times = 1
 			}
 
-			desec.addUpdateDomainRecords(tt.recordType)
+			desec.AddUpdateDomainRecords()
 
 			if requestCount != tt.wantRequests {
 				t.Fatalf("request count = %d, want %d", requestCount, tt.wantRequests)
@@ -170,7 +171,7 @@ func TestDesecZoneNotFound(t *testing.T) {
 		}),
 	}
 
-	desec := DeSEC{TTL: 3600, httpClient: client}
+	desec := DeSEC{TTL: 3600, httpClient: client, ctx: context.Background()}
 	domain := &config.Domain{
 		DomainName: "nonexistent.com",
 		SubDomain:  "www",
@@ -181,7 +182,7 @@ func TestDesecZoneNotFound(t *testing.T) {
 	desec.Domains.Ipv4Domains = []*config.Domain{domain}
 	desec.Domains.Ipv4Cache.Times = 1
 
-	desec.addUpdateDomainRecords("A")
+	desec.AddUpdateDomainRecords()
 
 	if domain.UpdateStatus != config.UpdatedFailed {
 		t.Errorf("update status = %v, want UpdatedFailed", domain.UpdateStatus)
@@ -206,7 +207,7 @@ func TestDesecInitTTL(t *testing.T) {
 			conf.DNS.Name = "desec"
 			conf.TTL = tt.ttl
 			desec := DeSEC{}
-			desec.Init(&conf, &util.IpCache{}, &util.IpCache{})
+			desec.Init(context.Background(), &conf, &util.IpCache{}, &util.IpCache{})
 			if desec.TTL != tt.wantValue {
 				t.Errorf("ttl = %d, want %d", desec.TTL, tt.wantValue)
 			}
@@ -2,13 +2,16 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/jeessy2/ddns-go/v6/config"
 	"github.com/jeessy2/ddns-go/v6/util"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 )
 
@@ -16,6 +19,7 @@ const (
 	recordList   string = "http://api.dns.la/api/recordList"
 	recordModify string = "http://api.dns.la/api/record"
 	recordCreate string = "http://api.dns.la/api/record"
+	recordDelete string = "http://api.dns.la/api/record"
 )
 
 // https://www.dns.la/docs/ApiDoc
@@ -25,6 +29,7 @@ type Dnsla struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // DnslaRecord
@@ -54,12 +59,32 @@ type DnslaStatus struct {
 	} `json:"data"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "dnsla",
+		Order: 18,
+		Name: map[string]string{
+			"en":    "Dnsla",
+			"zh-cn": "Dnsla",
+		},
+		IDLabel:     "APIID",
+		SecretLabel: "API密钥",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.dns.la/login?aksk=1'>Create AccessKey</a>",
+			"zh-cn": "<a target='_blank' href='https://console.dns.la/login?aksk=1'>创建 AccessKey</a>",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &Dnsla{} },
+	})
+}
+
 // Init 初始化
-func (dnsla *Dnsla) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (dnsla *Dnsla) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	dnsla.ctx = ctx
 	dnsla.Domains.Ipv4Cache = ipv4cache
 	dnsla.Domains.Ipv6Cache = ipv6cache
 	dnsla.DNS = dnsConf.DNS
-	dnsla.Domains.GetNewIp(dnsConf)
+	dnsla.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		dnsla.TTL = 600
@@ -72,55 +97,29 @@ func (dnsla *Dnsla) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (dnsla *Dnsla) AddUpdateDomainRecords() config.Domains {
-	dnsla.addUpdateDomainRecords("A")
-	dnsla.addUpdateDomainRecords("AAAA")
+	Reconcile(dnsla.ctx, dnsla, dnsla.Domains.GetAllNewIpResult("A/AAAA"))
 	return dnsla.Domains
 }
 
-func (dnsla *Dnsla) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := dnsla.Domains.GetNewIpResult(recordType)
-	if ipAddr == "" {
+// ListRecords 查询记录
+func (dnsla *Dnsla) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	resultByte, err := dnsla.getRecordList(domain, recordType)
+	if err != nil {
+		return
+	}
+	var jsonResult DnslaRecordListResp
+	err = json.Unmarshal(resultByte, &jsonResult)
+	if err != nil {
 		return
 	}
-	for _, domain := range domains {
-		resultByte, err := dnsla.getRecordList(domain, recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-		var jsonResult DnslaRecordListResp
-		errU := json.Unmarshal(resultByte, &jsonResult)
-		if errU != nil {
-			util.Log(errU.Error())
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-		if jsonResult.Data.Total > 0 { // 默认第一个
-			recordSelected := jsonResult.Data.Results[0]
-			params := domain.GetCustomParams()
-			if params.Has("id") {
-				for i := 0; i < len(jsonResult.Data.Results); i++ {
-					if jsonResult.Data.Results[i].ID == params.Get("id") {
-						recordSelected = jsonResult.Data.Results[i]
-					}
-				}
-			}
-			// 更新
-			dnsla.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// 新增
-			dnsla.create(domain, recordType, ipAddr)
-		}
+	for _, r := range jsonResult.Data.Results {
+		records = append(records, Record{ID: r.ID, Type: recordType, Value: r.Data})
 	}
+	return
 }
 
-// 创建
-func (dnsla *Dnsla) create(domain *config.Domain, recordType string, ipAddr string) {
-	recordTypeInt := 1
-	if recordType == "AAAA" {
-		recordTypeInt = 28
-	}
+// CreateRecord 创建
+func (dnsla *Dnsla) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	type CreateParams struct {
 		Domain string `json:"Domain"`
 		Host   string `json:"Host"`
@@ -131,44 +130,16 @@ func (dnsla *Dnsla) create(domain *config.Domain, recordType string, ipAddr stri
 	createParams := CreateParams{
 		Domain: domain.DomainName,
 		Host:   domain.GetSubDomain(),
-		Type:   recordTypeInt,
+		Type:   dnslaRecordType(recordType),
 		Data:   ipAddr,
 		TTL:    dnsla.TTL,
 	}
 	jsonData, _ := json.Marshal(createParams)
-	resultByte, err := dnsla.request("POST", recordCreate, jsonData)
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-	var jsonResult DnslaStatus
-	errU := json.Unmarshal(resultByte, &jsonResult)
-	if errU != nil {
-		util.Log(errU.Error())
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-	if jsonResult.Code == 200 {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, jsonResult.Msg)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return dnsla.write("POST", recordCreate, jsonData)
 }
 
-// 修改
-func (dnsla *Dnsla) modify(record DnslaRecord, domain *config.Domain, recordType string, ipAddr string) {
-	// 相同不修改
-	if record.Data == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
-	recordTypeInt := 1
-	if recordType == "AAAA" {
-		recordTypeInt = 28
-	}
+// UpdateRecord 修改
+func (dnsla *Dnsla) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
 	type ModifyParams struct {
 		ID   string `json:"Id"`
 		Host string `json:"Host"`
@@ -179,38 +150,48 @@ func (dnsla *Dnsla) modify(record DnslaRecord, domain *config.Domain, recordType
 	modifyParams := ModifyParams{
 		ID:   record.ID,
 		Host: domain.GetSubDomain(),
-		Type: recordTypeInt,
+		Type: dnslaRecordType(record.Type),
 		Data: ipAddr,
 		TTL:  dnsla.TTL,
 	}
 	jsonData, _ := json.Marshal(modifyParams)
-	resultByte, err := dnsla.request("PUT", recordModify, jsonData)
+	return dnsla.write("PUT", recordModify, jsonData)
+}
+
+// DeleteRecord 删除
+func (dnsla *Dnsla) DeleteRecord(domain *config.Domain, record Record) error {
+	return dnsla.write("DELETE", recordDelete+"?id="+url.QueryEscape(record.ID), nil)
+}
 
+// write 新增/修改/删除, code 不为 200 时返回错误
+func (dnsla *Dnsla) write(method, apiAddr string, values []byte) error {
+	resultByte, err := dnsla.request(method, apiAddr, values)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
-
 	var jsonResult DnslaStatus
-	errU := json.Unmarshal(resultByte, &jsonResult)
-	if errU != nil {
-		util.Log(errU.Error())
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+	err = json.Unmarshal(resultByte, &jsonResult)
+	if err != nil {
+		return err
 	}
-	if jsonResult.Code == 200 {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, jsonResult.Msg)
-		domain.UpdateStatus = config.UpdatedFailed
+	if jsonResult.Code != 200 {
+		return errors.New(jsonResult.Msg)
+	}
+	return nil
+}
+
+// dnslaRecordType A 为 1, AAAA 为 28
+func dnslaRecordType(recordType string) int {
+	if recordType == "AAAA" {
+		return 28
 	}
+	return 1
 }
 
 // request sends a POST request to the given API with the given values.
 func (dnsla *Dnsla) request(method, apiAddr string, values []byte) (body []byte, err error) {
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		dnsla.ctx,
 		method,
 		apiAddr,
 		bytes.NewReader(values),
@@ -255,7 +236,7 @@ func (dnsla *Dnsla) getRecordList(domain *config.Domain, typ string) (result []b
 	params.Set("pageSize", "999")
 
 	url := recordList + "?" + params.Encode()
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(dnsla.ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建 dnsla 记录列表请求失败: %w", err)
 	}
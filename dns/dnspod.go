@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/url"
 
@@ -12,6 +14,7 @@ const (
 	recordListAPI   string = "https://dnsapi.cn/Record.List"
 	recordModifyURL string = "https://dnsapi.cn/Record.Modify"
 	recordCreateAPI string = "https://dnsapi.cn/Record.Create"
+	recordRemoveAPI string = "https://dnsapi.cn/Record.Remove"
 )
 
 // https://cloud.tencent.com/document/api/302/8516
@@ -21,6 +24,7 @@ type Dnspod struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // DnspodRecord DnspodRecord
@@ -46,12 +50,32 @@ type DnspodStatus struct {
 	}
 }
 
+func init() {
+	Register(Provider{
+		Key:   "dnspod",
+		Order: 4,
+		Name: map[string]string{
+			"en": "DnsPod",
+		},
+		IDLabel:     "ID",
+		SecretLabel: "Token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.dnspod.cn/account/token/token'>Create Token</a>",
+			"zh-cn": "<a target='_blank' href='https://console.dnspod.cn/account/token/token'>创建 DNSPod Token</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{recordListAPI},
+		New:        func() DNS { return &Dnspod{} },
+	})
+}
+
 // Init 初始化
-func (dnspod *Dnspod) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (dnspod *Dnspod) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	dnspod.ctx = ctx
 	dnspod.Domains.Ipv4Cache = ipv4cache
 	dnspod.Domains.Ipv6Cache = ipv6cache
 	dnspod.DNS = dnsConf.DNS
-	dnspod.Domains.GetNewIp(dnsConf)
+	dnspod.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		dnspod.TTL = "600"
@@ -63,48 +87,24 @@ func (dnspod *Dnspod) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, i
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (dnspod *Dnspod) AddUpdateDomainRecords() config.Domains {
-	dnspod.addUpdateDomainRecords("A")
-	dnspod.addUpdateDomainRecords("AAAA")
+	Reconcile(dnspod.ctx, dnspod, dnspod.Domains.GetAllNewIpResult("A/AAAA"))
 	return dnspod.Domains
 }
 
-func (dnspod *Dnspod) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := dnspod.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询记录
+func (dnspod *Dnspod) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	result, err := dnspod.getRecordList(domain, recordType)
+	if err != nil {
 		return
 	}
-
-	for _, domain := range domains {
-		result, err := dnspod.getRecordList(domain, recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		if len(result.Records) > 0 {
-			// 默认第一个
-			recordSelected := result.Records[0]
-			params := domain.GetCustomParams()
-			if params.Has("record_id") {
-				for i := 0; i < len(result.Records); i++ {
-					if result.Records[i].ID == params.Get("record_id") {
-						recordSelected = result.Records[i]
-					}
-				}
-			}
-			// 更新
-			dnspod.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// 新增
-			dnspod.create(domain, recordType, ipAddr)
-		}
+	for _, r := range result.Records {
+		records = append(records, Record{ID: r.ID, Type: r.Type, Value: r.Value})
 	}
+	return
 }
 
-// 创建
-func (dnspod *Dnspod) create(domain *config.Domain, recordType string, ipAddr string) {
+// CreateRecord 创建
+func (dnspod *Dnspod) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	params := domain.GetCustomParams()
 	params.Set("login_token", dnspod.DNS.ID+","+dnspod.DNS.Secret)
 	params.Set("domain", domain.DomainName)
@@ -118,37 +118,16 @@ func (dnspod *Dnspod) create(domain *config.Domain, recordType string, ipAddr st
 		params.Set("record_line", "默认")
 	}
 
-	status, err := dnspod.request(recordCreateAPI, params)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if status.Status.Code == "1" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, status.Status.Message)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return dnspod.write(recordCreateAPI, params)
 }
 
-// 修改
-func (dnspod *Dnspod) modify(record DnspodRecord, domain *config.Domain, recordType string, ipAddr string) {
-
-	// 相同不修改
-	if record.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
-
+// UpdateRecord 修改
+func (dnspod *Dnspod) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
 	params := domain.GetCustomParams()
 	params.Set("login_token", dnspod.DNS.ID+","+dnspod.DNS.Secret)
 	params.Set("domain", domain.DomainName)
 	params.Set("sub_domain", domain.GetSubDomain())
-	params.Set("record_type", recordType)
+	params.Set("record_type", record.Type)
 	params.Set("value", ipAddr)
 	params.Set("ttl", dnspod.TTL)
 	params.Set("format", "json")
@@ -158,27 +137,50 @@ func (dnspod *Dnspod) modify(record DnspodRecord, domain *config.Domain, recordT
 		params.Set("record_line", "默认")
 	}
 
-	status, err := dnspod.request(recordModifyURL, params)
+	return dnspod.write(recordModifyURL, params)
+}
 
+// DeleteRecord 删除
+func (dnspod *Dnspod) DeleteRecord(domain *config.Domain, record Record) error {
+	params := url.Values{}
+	params.Set("login_token", dnspod.DNS.ID+","+dnspod.DNS.Secret)
+	params.Set("domain", domain.DomainName)
+	params.Set("record_id", record.ID)
+	params.Set("format", "json")
+	return dnspod.write(recordRemoveAPI, params)
+}
+
+// write 新增/修改/删除, 状态码不为 1 时返回错误
+func (dnspod *Dnspod) write(apiAddr string, params url.Values) error {
+	status, err := dnspod.request(apiAddr, params)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
+	}
+	if status.Status.Code != "1" {
+		return dnspodError(status)
 	}
+	return nil
+}
 
-	if status.Status.Code == "1" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, status.Status.Message)
-		domain.UpdateStatus = config.UpdatedFailed
+// dnspodError 根据状态码返回带类型的错误
+// https://docs.dnspod.cn/api/api-common-response/
+func dnspodError(status DnspodStatus) error {
+	err := errors.New(status.Status.Message)
+	switch status.Status.Code {
+	// 登录失败, 登录失败次数过多
+	case "-1", "-8":
+		return util.NewAPIError(util.ErrorAuth, err)
+	// API 使用超出限制
+	case "-2":
+		return util.NewAPIError(util.ErrorRateLimited, err)
 	}
+	return err
 }
 
 // request sends a POST request to the given API with the given values.
 func (dnspod *Dnspod) request(apiAddr string, values url.Values) (status DnspodStatus, err error) {
 	client := dnspod.httpClient
-	resp, err := client.PostForm(
+	resp, err := util.PostForm(dnspod.ctx, client,
 		apiAddr,
 		values,
 	)
@@ -199,12 +201,16 @@ func (dnspod *Dnspod) getRecordList(domain *config.Domain, typ string) (result D
 	params.Set("format", "json")
 
 	client := dnspod.httpClient
-	resp, err := client.PostForm(
+	resp, err := util.PostForm(dnspod.ctx, client,
 		recordListAPI,
 		params,
 	)
 
 	err = util.GetHTTPResponse(resp, err, &result)
+	// 10 为记录列表为空
+	if err == nil && result.Status.Code != "1" && result.Status.Code != "10" {
+		err = dnspodError(result.DnspodStatus)
+	}
 
 	return
 }
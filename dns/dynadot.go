@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"github.com/jeessy2/ddns-go/v6/config"
 	"github.com/jeessy2/ddns-go/v6/util"
 	"net/http"
@@ -23,11 +24,14 @@ type Dynadot struct {
 	LastIpv4   string
 	LastIpv6   string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // DynadotRecord record
 type DynadotRecord struct {
-	DomainName     string
+	DomainName string
+	// IpAddr 记录的地址, 设置了 Ipv6Suffix 或 NeighborMac 的子域名按地址分开更新
+	IpAddr         string
 	SubDomainNames []string
 	CustomParams   url.Values
 	Domains        []*config.Domain
@@ -41,14 +45,34 @@ type DynadotResp struct {
 	Content   []string `json:"content"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "dynadot",
+		Order: 14,
+		Name: map[string]string{
+			"en": "Dynadot",
+		},
+		IDLabel:     "",
+		SecretLabel: "Password",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.dynadot.com/community/help/question/enable-DDNS'>How to get started</a>",
+			"zh-cn": "<a target='_blank' href='https://www.dynadot.com/community/help/question/enable-DDNS'>开启Dynadot动态域名解析</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{dynadotEndpoint},
+		New:        func() DNS { return &Dynadot{} },
+	})
+}
+
 // Init 初始化
-func (dynadot *Dynadot) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (dynadot *Dynadot) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	dynadot.ctx = ctx
 	dynadot.Domains.Ipv4Cache = ipv4cache
 	dynadot.Domains.Ipv6Cache = ipv6cache
 	dynadot.LastIpv4 = ipv4cache.Addr
 	dynadot.LastIpv6 = ipv6cache.Addr
 	dynadot.DNS = dnsConf.DNS
-	dynadot.Domains.GetNewIp(dnsConf)
+	dynadot.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		dynadot.TTL = "600"
@@ -86,25 +110,28 @@ func (dynadot *Dynadot) addOrUpdateDomainRecords(recordType string) {
 		}
 	}
 
-	records := mergeDomains(domains)
+	records := mergeDomains(domains, ipAddr)
 	// dynadot 仅支持一个域名对应一个dynamic password
-	if len(records) != 1 {
-		util.Log("dynadot仅支持单域名配置，多个域名请添加更多配置")
-		return
+	for _, record := range records {
+		if record.DomainName != records[0].DomainName {
+			util.Log("dynadot仅支持单域名配置，多个域名请添加更多配置")
+			return
+		}
 	}
 	for _, record := range records {
 		// 创建或更新
-		dynadot.createOrModify(record, recordType, ipAddr)
+		dynadot.createOrModify(record, recordType)
 	}
 }
 
-// 合并域名的子域名
-func mergeDomains(domains []*config.Domain) (records []*DynadotRecord) {
+// 合并域名和地址都相同的子域名
+func mergeDomains(domains []*config.Domain, ipAddr string) (records []*DynadotRecord) {
 	records = make([]*DynadotRecord, 0)
 	for _, domain := range domains {
+		hostAddr := domain.HostAddr(ipAddr)
 		var record *DynadotRecord
 		for _, r := range records {
-			if r.DomainName == domain.DomainName {
+			if r.DomainName == domain.DomainName && r.IpAddr == hostAddr {
 				record = r
 				params := domain.GetCustomParams()
 				for key := range params {
@@ -118,6 +145,7 @@ func mergeDomains(domains []*config.Domain) (records []*DynadotRecord) {
 		if record == nil {
 			record = &DynadotRecord{
 				DomainName:     domain.DomainName,
+				IpAddr:         hostAddr,
 				CustomParams:   domain.GetCustomParams(),
 				Domains:        []*config.Domain{domain},
 				SubDomainNames: []string{domain.GetSubDomain()},
@@ -133,7 +161,15 @@ func mergeDomains(domains []*config.Domain) (records []*DynadotRecord) {
 }
 
 // 创建或变更记录
-func (dynadot *Dynadot) createOrModify(record *DynadotRecord, recordType string, ipAddr string) {
+func (dynadot *Dynadot) createOrModify(record *DynadotRecord, recordType string) {
+	ipAddr := record.IpAddr
+	if isDryRun(dynadot.ctx) {
+		for _, domain := range record.Domains {
+			planRecord(dynadot.ctx, domain, recordType, PlanUnknown, "", ipAddr)
+		}
+		return
+	}
+
 	params := record.CustomParams
 	params.Set("domain", record.DomainName)
 	params.Set("subDomain", strings.Join(record.SubDomainNames, ","))
@@ -169,7 +205,8 @@ func (dynadot *Dynadot) createOrModify(record *DynadotRecord, recordType string,
 // request 统一请求接口
 func (dynadot *Dynadot) request(params url.Values, result interface{}) (err error) {
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		dynadot.ctx,
 		"GET",
 		dynadotEndpoint,
 		bytes.NewBuffer(nil),
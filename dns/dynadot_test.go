@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// TestDynadotIpv6Suffix 设置了 Ipv6Suffix 的子域名使用局域网主机的地址单独更新
+func TestDynadotIpv6Suffix(t *testing.T) {
+	var requests []string
+	client := &http.Client{
+		Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+			query := request.URL.Query()
+			requests = append(requests, query.Get("subDomain")+"="+query.Get("ip"))
+			if query.Has("Ipv6Suffix") {
+				t.Errorf("Ipv6Suffix should not be sent to dynadot: %s", request.URL.RawQuery)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"status":"success"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	conf := config.DnsConfig{DNS: config.DNS{Name: "dynadot"}}
+	conf.Ipv6.Enable = true
+	conf.Ipv6.GetType = "cmd"
+	conf.Ipv6.Cmd = "echo 2001:db8:1:2::1"
+	conf.Ipv6.Domains = []string{"www.example.com", "nas.example.com?Ipv6Suffix=::10"}
+
+	dynadot := &Dynadot{}
+	dynadot.Init(context.Background(), &conf, &util.IpCache{}, &util.IpCache{})
+	dynadot.httpClient = client
+	domains := dynadot.AddUpdateDomainRecords()
+
+	slices.Sort(requests)
+	want := []string{"nas=2001:db8:1:2::10", "www=2001:db8:1:2::1"}
+	if !slices.Equal(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+	for _, domain := range domains.Ipv6Domains {
+		if domain.UpdateStatus != config.UpdatedSuccess {
+			t.Errorf("%s status = %q", domain, domain.UpdateStatus)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/jeessy2/ddns-go/v6/config"
 	"github.com/jeessy2/ddns-go/v6/util"
 	"net/http"
@@ -19,6 +21,7 @@ type Dynv6 struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 type Dynv6Zone struct {
@@ -36,12 +39,32 @@ type Dynv6Record struct {
 	Data   string `json:"data"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "dynv6",
+		Order: 16,
+		Name: map[string]string{
+			"en": "Dynv6",
+		},
+		IDLabel:     "",
+		SecretLabel: "Token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://dynv6.com/keys'>Create Token</a>",
+			"zh-cn": "<a target='_blank' href='https://dynv6.com/keys'>创建令牌</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{dynv6Endpoint},
+		New:        func() DNS { return &Dynv6{} },
+	})
+}
+
 // Init 初始化
-func (dynv6 *Dynv6) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (dynv6 *Dynv6) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	dynv6.ctx = ctx
 	dynv6.Domains.Ipv4Cache = ipv4cache
 	dynv6.Domains.Ipv6Cache = ipv6cache
 	dynv6.DNS = dnsConf.DNS
-	dynv6.Domains.GetNewIp(dnsConf)
+	dynv6.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		dynv6.TTL = "600"
@@ -53,79 +76,47 @@ func (dynv6 *Dynv6) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (dynv6 *Dynv6) AddUpdateDomainRecords() config.Domains {
-	dynv6.addUpdateDomainRecords("A")
-	dynv6.addUpdateDomainRecords("AAAA")
+	Reconcile(dynv6.ctx, dynv6, dynv6.Domains.GetAllNewIpResult("A/AAAA"))
 	return dynv6.Domains
 }
 
-func (dynv6 *Dynv6) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := dynv6.Domains.GetNewIpResult(recordType)
+// ListRecords 查询解析记录
+// 主域名的IP保存在zone中, 返回 Raw 为 Dynv6Zone 的记录; 子域名返回 Raw 为 Dynv6Record 的记录
+func (dynv6 *Dynv6) ListRecords(domain *config.Domain, recordType string) ([]Record, error) {
+	isFindZone, findZone, isMain, err := dynv6.findZone(domain)
+	if err != nil {
+		return nil, err
+	}
 
-	if ipAddr == "" {
-		return
+	if !isFindZone {
+		return nil, errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain))
 	}
 
-	for _, domain := range domains {
-		isFindZone, findZone, isMain, err := dynv6.findZone(domain)
+	zoneId := strconv.FormatUint(uint64(findZone.ID), 10)
 
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
+	if isMain {
+		current := findZone.Ipv4
+		if recordType == "AAAA" {
+			current = findZone.Ipv6
 		}
+		return []Record{{ID: zoneId, Type: recordType, Value: current, Raw: findZone}}, nil
+	}
 
-		if !isFindZone {
-			util.Log("在DNS服务商中未找到根域名: %s", domain)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		zoneId := strconv.FormatUint(uint64(findZone.ID), 10)
-
-		if isMain {
-			// 如果使用的域名是主域名，对比DNS记录确定是否调用更新接口
-			if (recordType == "A" && findZone.Ipv4 == ipAddr) || (recordType == "AAAA" && findZone.Ipv6 == ipAddr) {
-				// ip与dns服务器一致，不执行更新
-				util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-				domain.UpdateStatus = config.UpdatedNothing
-			} else {
-				dynv6.modifyMain(domain, zoneId, recordType, ipAddr)
-			}
-		} else {
-			// 如果是子域名，检查是否有该子域名记录，有就更新记录，没有就创建
-
-			// 处理subDomain
-			processSubDomainOk := dynv6.processSubDomain(domain, findZone)
-
-			if !processSubDomainOk {
-				util.Log("域名: %s 不正确", domain)
-				domain.UpdateStatus = config.UpdatedFailed
-				continue
-			}
-
-			isFindRecord, findRecord, err := dynv6.findRecord(domain, zoneId, recordType)
-
-			if err != nil {
-				util.Log("查询域名信息发生异常! %s", err)
-				domain.UpdateStatus = config.UpdatedFailed
-				return
-			}
+	// 处理subDomain
+	if !dynv6.processSubDomain(domain, findZone) {
+		return nil, errors.New(util.LogStr("域名: %s 不正确", domain))
+	}
 
-			if isFindRecord {
-				// 判断是否需要更新
-				if findRecord.Type == recordType && findRecord.Data == ipAddr {
-					// ip与dns服务器一致，不执行更新
-					util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-					domain.UpdateStatus = config.UpdatedNothing
-				} else {
-					dynv6.modify(domain, zoneId, findRecord, recordType, ipAddr)
-				}
-			} else {
-				// 创建记录
-				dynv6.create(domain, zoneId, recordType, ipAddr)
-			}
-		}
+	findRecords, err := dynv6.findRecords(domain, zoneId, recordType)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, r := range findRecords {
+		r.ZoneID = findZone.ID
+		records = append(records, Record{ID: strconv.FormatUint(uint64(r.ID), 10), Type: r.Type, Value: r.Data, Raw: r})
 	}
+	return records, nil
 }
 
 func (dynv6 *Dynv6) processSubDomain(domain *config.Domain, zone Dynv6Zone) bool {
@@ -169,10 +160,9 @@ func (dynv6 *Dynv6) findZone(domain *config.Domain) (isFind bool, zone Dynv6Zone
 	return
 }
 
-// 根据domain获取record
-func (dynv6 *Dynv6) findRecord(domain *config.Domain, zoneId string, recordType string) (isFind bool, record Dynv6Record, err error) {
+// 根据domain获取records
+func (dynv6 *Dynv6) findRecords(domain *config.Domain, zoneId string, recordType string) (result []Dynv6Record, err error) {
 	var records []Dynv6Record
-	isFind = false
 
 	err = dynv6.request("GET", dynv6Endpoint+"/api/v2/zones/"+zoneId+"/records", nil, &records)
 	if err != nil {
@@ -182,70 +172,63 @@ func (dynv6 *Dynv6) findRecord(domain *config.Domain, zoneId string, recordType
 	// 遍历zone下所有record，判断是更新还是创建
 	for _, r := range records {
 		if r.Name == domain.SubDomain && r.Type == recordType {
-			isFind = true
-			record = r
-			break
+			result = append(result, r)
 		}
 	}
 
 	return
 }
 
-// modify 更新根域名
-func (dynv6 *Dynv6) modifyMain(domain *config.Domain, zoneId string, recordType string, ipAddr string) {
-	var zoneUpdateReq = Dynv6Zone{}
-	if recordType == "A" {
-		zoneUpdateReq.Ipv4 = ipAddr
-	} else {
-		zoneUpdateReq.Ipv6 = ipAddr
-	}
-
-	err := dynv6.request("PATCH", dynv6Endpoint+"/api/v2/zones/"+zoneId, zoneUpdateReq, &Dynv6Zone{})
-
+// CreateRecord 创建新的解析
+func (dynv6 *Dynv6) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	isFindZone, findZone, _, err := dynv6.findZone(domain)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
+		return err
+	}
+	if !isFindZone {
+		return errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain))
 	}
-}
 
-// create 创建新的解析
-func (dynv6 *Dynv6) create(domain *config.Domain, zoneId string, recordType string, ipAddr string) {
 	recordUpdateReq := Dynv6Record{
 		Name: domain.SubDomain,
 		Type: recordType,
 		Data: ipAddr,
 	}
 
-	err := dynv6.request("POST", dynv6Endpoint+"/api/v2/zones/"+zoneId+"/records", recordUpdateReq, &Dynv6Record{})
+	zoneId := strconv.FormatUint(uint64(findZone.ID), 10)
+	return dynv6.request("POST", dynv6Endpoint+"/api/v2/zones/"+zoneId+"/records", recordUpdateReq, &Dynv6Record{})
+}
 
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
+// UpdateRecord 更新解析, 主域名更新zone的IP
+func (dynv6 *Dynv6) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	if _, ok := r.Raw.(Dynv6Zone); ok {
+		return dynv6.patchZone(r.ID, r.Type, ipAddr)
 	}
-}
 
-// modify 更新解析
-func (dynv6 *Dynv6) modify(domain *config.Domain, zoneId string, record Dynv6Record, recordType string, ipAddr string) {
-	record.Type = recordType
+	record := r.Raw.(Dynv6Record)
 	record.Data = ipAddr
+	zoneId := strconv.FormatUint(uint64(record.ZoneID), 10)
+	return dynv6.request("PATCH", dynv6Endpoint+"/api/v2/zones/"+zoneId+"/records/"+r.ID, record, &Dynv6Record{})
+}
 
-	recordId := strconv.FormatUint(uint64(record.ID), 10)
+// DeleteRecord 删除解析, 主域名清空zone的IP
+func (dynv6 *Dynv6) DeleteRecord(domain *config.Domain, r Record) error {
+	if _, ok := r.Raw.(Dynv6Zone); ok {
+		return dynv6.patchZone(r.ID, r.Type, "")
+	}
 
-	err := dynv6.request("PATCH", dynv6Endpoint+"/api/v2/zones/"+zoneId+"/records/"+recordId, record, &Dynv6Record{})
+	record := r.Raw.(Dynv6Record)
+	zoneId := strconv.FormatUint(uint64(record.ZoneID), 10)
+	return dynv6.request("DELETE", dynv6Endpoint+"/api/v2/zones/"+zoneId+"/records/"+r.ID, nil, nil)
+}
 
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
+// patchZone 更新根域名
+func (dynv6 *Dynv6) patchZone(zoneId string, recordType string, ipAddr string) error {
+	zoneUpdateReq := map[string]string{"ipv4address": ipAddr}
+	if recordType == "AAAA" {
+		zoneUpdateReq = map[string]string{"ipv6prefix": ipAddr}
 	}
+	return dynv6.request("PATCH", dynv6Endpoint+"/api/v2/zones/"+zoneId, zoneUpdateReq, &Dynv6Zone{})
 }
 
 // request 统一请求接口
@@ -255,7 +238,8 @@ func (dynv6 *Dynv6) request(method string, url string, data interface{}, result
 		jsonStr, _ = json.Marshal(data)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		dynv6.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -22,6 +24,7 @@ type EdgeOne struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 type EdgeOneRecord struct {
@@ -75,12 +78,33 @@ type EdgeOneStatus struct {
 	}
 }
 
+func init() {
+	Register(Provider{
+		Key:   "edgeone",
+		Order: 23,
+		Name: map[string]string{
+			"en":    "Edgeone",
+			"zh-cn": "Edgeone",
+		},
+		IDLabel:     "SecretId",
+		SecretLabel: "SecretKey",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.cloud.tencent.com/cam/capi'>Create AccessKey</a><br/>Origin group mode: append <code>?GroupId=origin-xxx</code> or <code>?OriginGroupName=your-group</code> to the domain entry.",
+			"zh-cn": "<a target='_blank' href='https://console.cloud.tencent.com/cam/capi'>创建腾讯云 API 密钥</a><br/>源站组模式：在域名后追加 <code>?GroupId=origin-xxx</code> 或 <code>?OriginGroupName=your-group</code>。",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{edgeoneEndPoint},
+		New:        func() DNS { return &EdgeOne{} },
+	})
+}
+
 // Init 初始化
-func (eo *EdgeOne) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (eo *EdgeOne) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	eo.ctx = ctx
 	eo.Domains.Ipv4Cache = ipv4cache
 	eo.Domains.Ipv6Cache = ipv6cache
 	eo.DNS = dnsConf.DNS
-	eo.Domains.GetNewIp(dnsConf)
+	eo.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认 600s
 		eo.TTL = 600
@@ -101,144 +125,119 @@ func (eo *EdgeOne) AddUpdateDomainRecords() config.Domains {
 	ipv6Addr, ipv6Domains := eo.Domains.GetNewIpResult("AAAA")
 
 	eo.addUpdateOriginGroups(buildEdgeOneDomainTuples(eo.Domains, ipv4Addr, ipv4Domains, ipv6Addr, ipv6Domains))
-	eo.addUpdateDomainRecords("A", ipv4Addr, ipv4Domains)
-	eo.addUpdateDomainRecords("AAAA", ipv6Addr, ipv6Domains)
+	Reconcile(eo.ctx, eo, filterTuples(eo.Domains.GetAllNewIpResult("A/AAAA"), func(domain *config.Domain, _ string) bool {
+		return !eo.isOriginGroupDomain(domain)
+	}))
 	return eo.Domains
 }
 
-func (eo *EdgeOne) addUpdateDomainRecords(recordType string, ipAddr string, domains []*config.Domain) {
-	if ipAddr == "" {
+// ListRecords 查询记录, 未指定 RecordId 时忽略已停用的记录
+func (eo *EdgeOne) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	zoneId, err := eo.getRecordZoneId(domain)
+	if err != nil {
+		return
+	}
+	recordResult, err := eo.getRecordList(domain, recordType, zoneId)
+	if err != nil {
 		return
 	}
 
-	for _, domain := range domains {
-		if eo.isOriginGroupDomain(domain) {
+	hasRecordId := domain.GetCustomParams().Has("RecordId")
+	for _, r := range recordResult.Response.DnsRecords {
+		if !hasRecordId && r.Status != "enable" {
 			continue
 		}
+		r.ZoneId = zoneId
+		records = append(records, Record{ID: r.RecordId, Type: r.Type, Value: r.Content, Raw: r})
+	}
+	return
+}
 
-		zoneResult, err := eo.getZone(domain.DomainName)
-		if err != nil || zoneResult.Response.TotalCount <= 0 || zoneResult.Response.Zones[0].ZoneName != domain.DomainName {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-		zoneId := zoneResult.Response.Zones[0].ZoneId
-		recordResult, err := eo.getRecordList(domain, recordType, zoneId)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		params := domain.GetCustomParams()
-		var isValid func(*EdgeOneRecord) bool
-		if params.Has("RecordId") {
-			isValid = func(r *EdgeOneRecord) bool { return r.RecordId == params.Get("RecordId") }
-		} else {
-			isValid = func(r *EdgeOneRecord) bool {
-				return r.Status == "enable" || r.Status == "disable" && r.Content == ipAddr
-			}
-		}
-		var recordSelected *EdgeOneRecord
-		for i := range recordResult.Response.DnsRecords {
-			r := &recordResult.Response.DnsRecords[i]
-			if isValid(r) {
-				recordSelected = r
-				break
-			}
-		}
-		if recordSelected != nil {
-			// 修改记录
-			eo.modify(*recordSelected, domain, recordType, ipAddr, zoneId)
-		} else {
-			// 添加记录
-			eo.create(domain, recordType, ipAddr, zoneId)
-		}
+// getRecordZoneId 查询域名所在站点的 ZoneId
+func (eo *EdgeOne) getRecordZoneId(domain *config.Domain) (string, error) {
+	zoneResult, err := eo.getZone(domain.DomainName)
+	if err != nil {
+		return "", err
+	}
+	if zoneResult.Response.TotalCount <= 0 || zoneResult.Response.Zones[0].ZoneName != domain.DomainName {
+		return "", errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
 	}
+	return zoneResult.Response.Zones[0].ZoneId, nil
 }
 
-// CreateDnsRecord https://cloud.tencent.com/document/product/1552/80720
-func (eo *EdgeOne) create(domain *config.Domain, recordType string, ipAddr string, ZoneId string) {
-	d := domain.DomainName
-	if domain.SubDomain != "" && domain.SubDomain != "@" {
-		d = domain.SubDomain + "." + domain.DomainName
+// CreateRecord CreateDnsRecord https://cloud.tencent.com/document/product/1552/80720
+func (eo *EdgeOne) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	zoneId, err := eo.getRecordZoneId(domain)
+	if err != nil {
+		return err
 	}
-	asciiDomain, _ := idna.ToASCII(d)
+
 	record := &EdgeOneRecord{
-		ZoneId:   ZoneId,
-		Name:     asciiDomain,
+		ZoneId:   zoneId,
+		Name:     eo.getRecordName(domain),
 		Type:     recordType,
 		Content:  ipAddr,
 		Location: eo.getLocation(domain),
 		TTL:      eo.TTL,
 	}
-	var status EdgeOneStatus
-	err := eo.request(
-		"CreateDnsRecord",
-		record,
-		&status,
-	)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if status.Response.Error.Code == "" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, status.Response.Error.Message)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return eo.write("CreateDnsRecord", record)
 }
 
-// ModifyDnsRecords https://cloud.tencent.com/document/product/1552/114252
-func (eo *EdgeOne) modify(record EdgeOneRecord, domain *config.Domain, recordType string, ipAddr string, ZoneId string) {
-	// 相同不修改
-	if record.Content == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
-	var status EdgeOneStatus
-	d := domain.DomainName
-	if domain.SubDomain != "" && domain.SubDomain != "@" {
-		d = domain.SubDomain + "." + domain.DomainName
-	}
-	asciiDomain, _ := idna.ToASCII(d)
-	record.ZoneId = ZoneId
-	record.Name = asciiDomain
-	record.Type = recordType
+// UpdateRecord ModifyDnsRecords https://cloud.tencent.com/document/product/1552/114252
+func (eo *EdgeOne) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	record := r.Raw.(EdgeOneRecord)
+	record.Name = eo.getRecordName(domain)
 	record.Content = ipAddr
 	record.Location = eo.getLocation(domain)
 	record.TTL = eo.TTL
 
-	err := eo.request(
+	return eo.write(
 		"ModifyDnsRecords",
 		struct {
 			ZoneId     string          `json:"ZoneId"`
 			DnsRecords []EdgeOneRecord `json:"DnsRecords"`
 		}{
-			ZoneId:     ZoneId,
+			ZoneId:     record.ZoneId,
 			DnsRecords: []EdgeOneRecord{record},
 		},
-		&status,
 	)
+}
 
+// DeleteRecord DeleteDnsRecords https://cloud.tencent.com/document/product/1552/80718
+func (eo *EdgeOne) DeleteRecord(domain *config.Domain, r Record) error {
+	return eo.write(
+		"DeleteDnsRecords",
+		struct {
+			ZoneId    string   `json:"ZoneId"`
+			RecordIds []string `json:"RecordIds"`
+		}{
+			ZoneId:    r.Raw.(EdgeOneRecord).ZoneId,
+			RecordIds: []string{r.ID},
+		},
+	)
+}
+
+// write 新增/修改/删除, 返回错误码时视为失败
+func (eo *EdgeOne) write(action string, data interface{}) error {
+	var status EdgeOneStatus
+	err := eo.request(action, data, &status)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
+	if status.Response.Error.Code != "" {
+		return errors.New(status.Response.Error.Message)
+	}
+	return nil
+}
 
-	if status.Response.Error.Code == "" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, status.Response.Error.Message)
-		domain.UpdateStatus = config.UpdatedFailed
+// getRecordName 返回记录的完整域名
+func (eo *EdgeOne) getRecordName(domain *config.Domain) string {
+	d := domain.DomainName
+	if domain.SubDomain != "" && domain.SubDomain != "@" {
+		d = domain.SubDomain + "." + domain.DomainName
 	}
+	asciiDomain, _ := idna.ToASCII(d)
+	return asciiDomain
 }
 
 func (eo *EdgeOne) getZone(domain string) (result EdgeOneZoneResponse, err error) {
@@ -258,15 +257,10 @@ func (eo *EdgeOne) getZone(domain string) (result EdgeOneZoneResponse, err error
 
 // DescribeDnsRecords https://cloud.tencent.com/document/product/1552/80716
 func (eo *EdgeOne) getRecordList(domain *config.Domain, recordType string, ZoneId string) (result EdgeOneRecordResponse, err error) {
-	d := domain.DomainName
-	if domain.SubDomain != "" && domain.SubDomain != "@" {
-		d = domain.SubDomain + "." + domain.DomainName
-	}
-	asciiDomain, _ := idna.ToASCII(d)
 	record := EdgeOneDescribeDns{
 		ZoneId: ZoneId,
 		Filters: []Filter{
-			{Name: "name", Values: []string{asciiDomain}},
+			{Name: "name", Values: []string{eo.getRecordName(domain)}},
 			{Name: "type", Values: []string{recordType}},
 		},
 	}
@@ -294,7 +288,8 @@ func (eo *EdgeOne) request(action string, data interface{}, result interface{})
 	if data != nil {
 		jsonStr, _ = json.Marshal(data)
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		eo.ctx,
 		"POST",
 		edgeoneEndPoint,
 		bytes.NewBuffer(jsonStr),
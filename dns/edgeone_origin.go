@@ -256,11 +256,18 @@ func (eo *EdgeOne) getOriginGroup(domain *config.Domain, zoneId string) (EdgeOne
 }
 
 func (eo *EdgeOne) modifyOriginGroup(originGroup EdgeOneOriginGroup, domainTuple *config.DomainTuple, zoneId string, records []EdgeOneOriginRecord) {
+	current := strings.Join(edgeOneOriginRecordValues(originGroup.Records), ",")
+	desired := strings.Join(edgeOneOriginRecordValues(records), ",")
 	if sameEdgeOneOriginRecords(originGroup.Records, records) {
+		planRecord(eo.ctx, domainTuple.Primary, domainTuple.RecordType, PlanNoop, current, desired)
 		util.Log("你的IP %s 没有变化, EdgeOne 源站组 %s", strings.Join(edgeOneOriginRecordValues(records), ","), originGroup.Name)
 		return
 	}
 
+	if planRecord(eo.ctx, domainTuple.Primary, domainTuple.RecordType, PlanUpdate, current, desired) {
+		return
+	}
+
 	var status EdgeOneStatus
 	err := eo.request(
 		"ModifyOriginGroup",
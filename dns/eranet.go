@@ -1,10 +1,12 @@
 package dns
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,6 +26,7 @@ type Eranet struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 type EranetRecord struct {
@@ -48,12 +51,32 @@ type EranetBaseResult struct {
 	Error     string `json:"error"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "eranet",
+		Order: 20,
+		Name: map[string]string{
+			"en":    "Eranet",
+			"zh-cn": "Eranet",
+		},
+		IDLabel:     "auth-userid",
+		SecretLabel: "api-key",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://partner.eranet.com/admin/mode_Http_Api_detail.php'>api-key</a>",
+			"zh-cn": "<a target='_blank' href='https://partner.eranet.com/admin/mode_Http_Api_detail.php'>获取 api-key</a>",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &Eranet{} },
+	})
+}
+
 // Init 初始化
-func (eranet *Eranet) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (eranet *Eranet) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	eranet.ctx = ctx
 	eranet.Domains.Ipv4Cache = ipv4cache
 	eranet.Domains.Ipv6Cache = ipv6cache
 	eranet.DNS = dnsConf.DNS
-	eranet.Domains.GetNewIp(dnsConf)
+	eranet.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		eranet.TTL = "600"
@@ -65,48 +88,24 @@ func (eranet *Eranet) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, i
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (eranet *Eranet) AddUpdateDomainRecords() config.Domains {
-	eranet.addUpdateDomainRecords("A")
-	eranet.addUpdateDomainRecords("AAAA")
+	Reconcile(eranet.ctx, eranet, eranet.Domains.GetAllNewIpResult("A/AAAA"))
 	return eranet.Domains
 }
 
-func (eranet *Eranet) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := eranet.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询DNS记录
+func (eranet *Eranet) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	result, err := eranet.getRecordList(domain, recordType)
+	if err != nil {
 		return
 	}
-
-	for _, domain := range domains {
-		result, err := eranet.getRecordList(domain, recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		if len(result.Data) > 0 {
-			// 默认第一个
-			recordSelected := result.Data[0]
-			params := domain.GetCustomParams()
-			if params.Has("Id") {
-				for i := 0; i < len(result.Data); i++ {
-					if strconv.Itoa(result.Data[i].ID) == params.Get("Id") {
-						recordSelected = result.Data[i]
-					}
-				}
-			}
-			// 更新
-			eranet.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// 新增
-			eranet.create(domain, recordType, ipAddr)
-		}
+	for _, r := range result.Data {
+		records = append(records, Record{ID: strconv.Itoa(r.ID), Type: recordType, Value: r.Value})
 	}
+	return
 }
 
-// create 创建DNS记录
-func (eranet *Eranet) create(domain *config.Domain, recordType string, ipAddr string) {
+// CreateRecord 创建DNS记录
+func (eranet *Eranet) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	param := map[string]string{
 		"Domain": domain.DomainName,
 		"Host":   domain.GetSubDomain(),
@@ -114,58 +113,46 @@ func (eranet *Eranet) create(domain *config.Domain, recordType string, ipAddr st
 		"Value":  ipAddr,
 		"Ttl":    eranet.TTL,
 	}
-	res, err := eranet.request("/api/Dns/AddDomainRecord", param, "GET")
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
-	}
-	var result NowcnBaseResult
-	err = json.Unmarshal(res, &result)
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
-	}
-	if result.Error != "" {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, result.Error)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		domain.UpdateStatus = config.UpdatedSuccess
-	}
+	return eranet.write("/api/Dns/AddDomainRecord", param)
 }
 
-// modify 修改DNS记录
-func (eranet *Eranet) modify(record EranetRecord, domain *config.Domain, recordType string, ipAddr string) {
-	// 相同不修改
-	if record.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// UpdateRecord 修改DNS记录
+func (eranet *Eranet) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
 	param := map[string]string{
-		"Id":     strconv.Itoa(record.ID),
+		"Id":     record.ID,
 		"Domain": domain.DomainName,
 		"Host":   domain.GetSubDomain(),
-		"Type":   recordType,
+		"Type":   record.Type,
 		"Value":  ipAddr,
 		"Ttl":    eranet.TTL,
 	}
-	res, err := eranet.request("/api/Dns/UpdateDomainRecord", param, "GET")
+	return eranet.write("/api/Dns/UpdateDomainRecord", param)
+}
+
+// DeleteRecord 删除DNS记录
+func (eranet *Eranet) DeleteRecord(domain *config.Domain, record Record) error {
+	param := map[string]string{
+		"Id":     record.ID,
+		"Domain": domain.DomainName,
+	}
+	return eranet.write("/api/Dns/DeleteDomainRecord", param)
+}
+
+// write 新增/修改/删除, 返回 error 时视为失败
+func (eranet *Eranet) write(apiPath string, param map[string]string) error {
+	res, err := eranet.request(apiPath, param, "GET")
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
+		return err
 	}
-	var result NowcnBaseResult
+	var result EranetBaseResult
 	err = json.Unmarshal(res, &result)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
+		return err
 	}
 	if result.Error != "" {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, result.Error)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
+		return errors.New(result.Error)
 	}
+	return nil
 }
 
 // getRecordList 获取域名记录列表
@@ -176,6 +163,9 @@ func (eranet *Eranet) getRecordList(domain *config.Domain, typ string) (result E
 		"Host":   domain.GetSubDomain(),
 	}
 	res, err := eranet.request("/api/Dns/DescribeRecordIndex", param, "GET")
+	if err != nil {
+		return
+	}
 	err = json.Unmarshal(res, &result)
 	return
 }
@@ -257,7 +247,7 @@ func (t *Eranet) request(apiPath string, params map[string]string, method string
 	fullURL := baseURL + apiPath + "?" + queryString
 
 	// 创建HTTP请求
-	req, err := http.NewRequest(method, fullURL, nil)
+	req, err := http.NewRequestWithContext(t.ctx, method, fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
@@ -0,0 +1,111 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+)
+
+const (
+	// addrHistoryKeep 地址变化历史的保留时间
+	addrHistoryKeep = 7 * 24 * time.Hour
+	// addrHistoryMax 每种变化最多保留的记录数, 避免频繁抖动时状态文件过大
+	addrHistoryMax = 1000
+)
+
+// AddrHistory 地址变化的历史
+type AddrHistory struct {
+	// Changes 地址改变并更新的时间
+	Changes []time.Time `json:"changes,omitempty"`
+	// Flaps 等待稳定的地址在稳定前又发生变化的时间
+	Flaps []time.Time `json:"flaps,omitempty"`
+}
+
+func (h AddrHistory) empty() bool {
+	return len(h.Changes) == 0 && len(h.Flaps) == 0
+}
+
+// AddrStats 地址变化的统计
+type AddrStats struct {
+	Changes24h int
+	Changes7d  int
+	Flaps24h   int
+	Flaps7d    int
+	// LastChange 最后一次地址改变的时间, 没有时为零值
+	LastChange time.Time
+}
+
+var (
+	// addrHistory 各配置IPv4/IPv6地址变化的历史, 以配置的摘要为键, 强制比对时不会清空
+	addrHistory   = map[string][2]AddrHistory{}
+	addrHistoryMu sync.Mutex
+)
+
+// recordAddrHistory 记录本次获取到的地址的变化, 并清理过期的历史
+func recordAddrHistory(dc *config.DnsConfig, domains *config.Domains, now time.Time) {
+	addrHistoryMu.Lock()
+	defer addrHistoryMu.Unlock()
+
+	key := configKey(dc)
+	h := addrHistory[key]
+	for i, s := range [2]config.AddrStability{domains.Ipv4Stability, domains.Ipv6Stability} {
+		if s.Changed {
+			h[i].Changes = append(h[i].Changes, now)
+		}
+		if s.Flapped {
+			h[i].Flaps = append(h[i].Flaps, now)
+		}
+		h[i].Changes = trimHistory(h[i].Changes, now)
+		h[i].Flaps = trimHistory(h[i].Flaps, now)
+	}
+	if h[0].empty() && h[1].empty() {
+		delete(addrHistory, key)
+		return
+	}
+	addrHistory[key] = h
+}
+
+// trimHistory 删除超过保留时间或数量的记录
+func trimHistory(times []time.Time, now time.Time) []time.Time {
+	if len(times) > addrHistoryMax {
+		times = times[len(times)-addrHistoryMax:]
+	}
+	for len(times) > 0 && now.Sub(times[0]) > addrHistoryKeep {
+		times = times[1:]
+	}
+	if len(times) == 0 {
+		return nil
+	}
+	return times
+}
+
+// GetAddrStats 配置最近 24 小时和 7 天内IPv4/IPv6地址改变和抖动的次数
+func GetAddrStats(dc *config.DnsConfig, now time.Time) (stats [2]AddrStats) {
+	addrHistoryMu.Lock()
+	defer addrHistoryMu.Unlock()
+
+	h := addrHistory[configKey(dc)]
+	for i := range h {
+		stats[i].Changes24h, stats[i].Changes7d = countSince(h[i].Changes, now)
+		stats[i].Flaps24h, stats[i].Flaps7d = countSince(h[i].Flaps, now)
+		if n := len(h[i].Changes); n > 0 {
+			stats[i].LastChange = h[i].Changes[n-1]
+		}
+	}
+	return
+}
+
+// countSince 最近 24 小时和 7 天内的次数
+func countSince(times []time.Time, now time.Time) (day, week int) {
+	for _, t := range times {
+		age := now.Sub(t)
+		if age <= addrHistoryKeep {
+			week++
+		}
+		if age <= 24*time.Hour {
+			day++
+		}
+	}
+	return
+}
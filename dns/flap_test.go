@@ -0,0 +1,36 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+)
+
+func TestAddrHistory(t *testing.T) {
+	t.Cleanup(func() { addrHistory = map[string][2]AddrHistory{} })
+
+	dc := &config.DnsConfig{Name: "test"}
+	now := time.Now()
+	record := func(at time.Time, v4, v6 config.AddrStability) {
+		recordAddrHistory(dc, &config.Domains{Ipv4Stability: v4, Ipv6Stability: v6}, at)
+	}
+	record(now.Add(-8*24*time.Hour), config.AddrStability{Changed: true}, config.AddrStability{})
+	record(now.Add(-48*time.Hour), config.AddrStability{Changed: true}, config.AddrStability{Waiting: true, Flapped: true})
+	record(now.Add(-time.Hour), config.AddrStability{Changed: true}, config.AddrStability{})
+	record(now, config.AddrStability{Waiting: true, Flapped: true}, config.AddrStability{})
+
+	stats := GetAddrStats(dc, now)
+	want := AddrStats{Changes24h: 1, Changes7d: 2, Flaps24h: 1, Flaps7d: 1, LastChange: now.Add(-time.Hour)}
+	if stats[0] != want {
+		t.Errorf("IPv4 stats = %+v, want %+v", stats[0], want)
+	}
+	if stats[1].Changes7d != 0 || stats[1].Flaps7d != 1 || stats[1].Flaps24h != 0 {
+		t.Errorf("IPv6 stats = %+v", stats[1])
+	}
+
+	// 修改配置后不再使用旧的历史
+	if stats := GetAddrStats(&config.DnsConfig{Name: "changed"}, now); stats[0].Changes7d != 0 {
+		t.Errorf("changed config stats = %+v", stats[0])
+	}
+}
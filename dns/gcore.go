@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -20,6 +22,7 @@ type Gcore struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // GcoreZoneResponse zones返回结果
@@ -71,12 +74,32 @@ type GcoreInputResourceRecord struct {
 	Meta    map[string]interface{} `json:"meta,omitempty"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "gcore",
+		Order: 22,
+		Name: map[string]string{
+			"en": "Gcore",
+		},
+		IDLabel:     "",
+		SecretLabel: "API Token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://portal.gcore.com/accounts/profile/api-tokens/create'>Create API Token</a>",
+			"zh-cn": "<a target='_blank' href='https://portal.gcore.com/accounts/profile/api-tokens/create'>创建 API Token</a>",
+		},
+		DefaultTTL: 120,
+		Endpoints:  []string{gcoreAPIEndpoint},
+		New:        func() DNS { return &Gcore{} },
+	})
+}
+
 // Init 初始化
-func (gc *Gcore) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (gc *Gcore) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	gc.ctx = ctx
 	gc.Domains.Ipv4Cache = ipv4cache
 	gc.Domains.Ipv6Cache = ipv6cache
 	gc.DNS = dnsConf.DNS
-	gc.Domains.GetNewIp(dnsConf)
+	gc.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认 120 秒（免费版最低值）
 		gc.TTL = 120
@@ -93,49 +116,32 @@ func (gc *Gcore) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6ca
 
 // AddUpdateDomainRecords 添加或更新 IPv4 / IPv6 记录
 func (gc *Gcore) AddUpdateDomainRecords() config.Domains {
-	gc.addUpdateDomainRecords("A")
-	gc.addUpdateDomainRecords("AAAA")
+	Reconcile(gc.ctx, gc, gc.Domains.GetAllNewIpResult("A/AAAA"))
 	return gc.Domains
 }
 
-func (gc *Gcore) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := gc.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询RRSet, Raw 为所在的 zone 名称
+func (gc *Gcore) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	zoneInfo, err := gc.getZoneByDomain(domain)
+	if err != nil {
 		return
 	}
+	if zoneInfo == nil {
+		return nil, errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
+	}
 
-	for _, domain := range domains {
-		// get zone
-		zoneInfo, err := gc.getZoneByDomain(domain)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		if zoneInfo == nil {
-			util.Log("在DNS服务商中未找到根域名: %s", domain.DomainName)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		// 查询现有记录
-		existingRecord, err := gc.getRRSet(zoneInfo.Name, domain.GetSubDomain(), recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
+	existingRecord, err := gc.getRRSet(zoneInfo.Name, domain.GetSubDomain(), recordType)
+	if err != nil || existingRecord == nil {
+		return
+	}
 
-		if existingRecord != nil {
-			// 更新现有记录
-			gc.updateRecord(zoneInfo.Name, domain, recordType, ipAddr, existingRecord)
-		} else {
-			// 创建新记录
-			gc.createRecord(zoneInfo.Name, domain, recordType, ipAddr)
+	// 每个资源记录返回一条记录, 修改时整体替换 RRSet
+	for _, rr := range existingRecord.ResourceRecords {
+		if len(rr.Content) > 0 {
+			records = append(records, Record{ID: existingRecord.Name, Type: existingRecord.Type, Value: fmt.Sprint(rr.Content[0]), Raw: zoneInfo.Name})
 		}
 	}
+	return
 }
 
 // 获取域名对应的Zone信息
@@ -177,14 +183,7 @@ func (gc *Gcore) getRRSet(zoneName, recordName, recordType string) (*GcoreRRSet,
 		return nil, err
 	}
 
-	// 查找匹配的记录
-	fullRecordName := recordName
-	if recordName != "" && recordName != "@" {
-		fullRecordName = recordName + "." + zoneName
-	} else {
-		fullRecordName = zoneName
-	}
-
+	fullRecordName := gcoreRecordName(zoneName, recordName)
 	for _, rrset := range result.RRSets {
 		if rrset.Name == fullRecordName && rrset.Type == recordType {
 			return &rrset, nil
@@ -194,86 +193,80 @@ func (gc *Gcore) getRRSet(zoneName, recordName, recordType string) (*GcoreRRSet,
 	return nil, nil
 }
 
-// 创建新记录
-func (gc *Gcore) createRecord(zoneName string, domain *config.Domain, recordType string, ipAddr string) {
-	recordName := domain.GetSubDomain()
-	if recordName == "" || recordName == "@" {
-		recordName = zoneName
-	} else {
-		recordName = recordName + "." + zoneName
-	}
-
-	inputRRSet := GcoreInputRRSet{
-		TTL: gc.TTL,
-		ResourceRecords: []GcoreInputResourceRecord{
-			{
-				Content: []interface{}{ipAddr},
-				Enabled: true,
-			},
-		},
+// gcoreRecordName 返回RRSet的完整名称
+func gcoreRecordName(zoneName, subDomain string) string {
+	if subDomain == "" || subDomain == "@" {
+		return zoneName
 	}
+	return subDomain + "." + zoneName
+}
 
-	var result interface{}
-	err := gc.request(
-		"POST",
-		fmt.Sprintf("%s/zones/%s/%s/%s", gcoreAPIEndpoint, zoneName, recordName, recordType),
-		inputRRSet,
-		&result,
-	)
-
+// CreateRecord 创建新记录
+func (gc *Gcore) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	zoneInfo, err := gc.getZoneByDomain(domain)
 	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
+	if zoneInfo == nil {
+		return errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
+	}
+	return gc.write("POST", zoneInfo.Name, gcoreRecordName(zoneInfo.Name, domain.GetSubDomain()), recordType, []string{ipAddr})
+}
 
-	util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-	domain.UpdateStatus = config.UpdatedSuccess
+// UpdateRecord 更新现有记录
+func (gc *Gcore) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	return gc.write("PUT", r.Raw.(string), r.ID, r.Type, []string{ipAddr})
 }
 
-// 更新现有记录
-func (gc *Gcore) updateRecord(zoneName string, domain *config.Domain, recordType string, ipAddr string, existingRecord *GcoreRRSet) {
-	// 检查IP是否相同
-	if len(existingRecord.ResourceRecords) > 0 && len(existingRecord.ResourceRecords[0].Content) > 0 {
-		if existingRecord.ResourceRecords[0].Content[0] == ipAddr {
-			util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-			return
+// SetRecords 将 RRSet 替换为 values, 不存在时创建
+func (gc *Gcore) SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error {
+	if len(records) == 0 {
+		if len(values) == 0 {
+			return nil
 		}
+		zoneInfo, err := gc.getZoneByDomain(domain)
+		if err != nil {
+			return err
+		}
+		if zoneInfo == nil {
+			return errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
+		}
+		return gc.write("POST", zoneInfo.Name, gcoreRecordName(zoneInfo.Name, domain.GetSubDomain()), recordType, values)
 	}
-
-	recordName := domain.GetSubDomain()
-	if recordName == "" || recordName == "@" {
-		recordName = zoneName
-	} else {
-		recordName = recordName + "." + zoneName
+	if len(values) == 0 {
+		return gc.DeleteRecord(domain, records[0])
 	}
+	return gc.write("PUT", records[0].Raw.(string), records[0].ID, recordType, values)
+}
 
-	inputRRSet := GcoreInputRRSet{
-		TTL: gc.TTL,
-		ResourceRecords: []GcoreInputResourceRecord{
-			{
-				Content: []interface{}{ipAddr},
-				Enabled: true,
-			},
-		},
+// DeleteRecord 删除记录
+func (gc *Gcore) DeleteRecord(domain *config.Domain, r Record) error {
+	var result interface{}
+	return gc.request(
+		"DELETE",
+		fmt.Sprintf("%s/zones/%s/%s/%s", gcoreAPIEndpoint, r.Raw.(string), r.ID, r.Type),
+		nil,
+		&result,
+	)
+}
+
+// write 创建或替换RRSet
+func (gc *Gcore) write(method, zoneName, recordName, recordType string, values []string) error {
+	inputRRSet := GcoreInputRRSet{TTL: gc.TTL}
+	for _, value := range values {
+		inputRRSet.ResourceRecords = append(inputRRSet.ResourceRecords, GcoreInputResourceRecord{
+			Content: []interface{}{value},
+			Enabled: true,
+		})
 	}
 
 	var result interface{}
-	err := gc.request(
-		"PUT",
+	return gc.request(
+		method,
 		fmt.Sprintf("%s/zones/%s/%s/%s", gcoreAPIEndpoint, zoneName, recordName, recordType),
 		inputRRSet,
 		&result,
 	)
-
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-	domain.UpdateStatus = config.UpdatedSuccess
 }
 
 // request 统一请求接口
@@ -283,7 +276,8 @@ func (gc *Gcore) request(method string, url string, data interface{}, result int
 		jsonStr, _ = json.Marshal(data)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		gc.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
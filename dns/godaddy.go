@@ -2,15 +2,20 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/jeessy2/ddns-go/v6/config"
 	"github.com/jeessy2/ddns-go/v6/util"
 )
 
+const godaddyEndpoint = "https://api.godaddy.com"
+
 type godaddyRecord struct {
 	Data string `json:"data"`
 	Name string `json:"name"`
@@ -21,23 +26,43 @@ type godaddyRecord struct {
 type godaddyRecords []godaddyRecord
 
 type GoDaddyDNS struct {
-	dns      config.DNS
-	domains  config.Domains
-	ttl      int
-	header   http.Header
-	client   *http.Client
-	lastIpv4 string
-	lastIpv6 string
+	dns     config.DNS
+	domains config.Domains
+	ttl     int
+	header  http.Header
+	client  *http.Client
+	ctx     context.Context
+	// recordSets 各记录集当前的值, 以域名和记录类型为键
+	recordSets map[string][]string
+}
+
+func init() {
+	Register(Provider{
+		Key:   "godaddy",
+		Order: 10,
+		Name: map[string]string{
+			"en": "GoDaddy",
+		},
+		IDLabel:     "Key",
+		SecretLabel: "Secret",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://developer.godaddy.com/keys'>Create API KEY</a><br/><span style='color: #ff9800;'>⚠️ Note: GoDaddy API requires you to have 10 or more domains or a Pro plan</span>",
+			"zh-cn": "<a target='_blank' href='https://developer.godaddy.com/keys'>创建 API KEY</a><br/><span style='color: #ff9800;'>⚠️ 温馨提示：GoDaddy 现在需要拥有 10 个及以上的域名或 Pro Plan 才可以使用 API</span>",
+		},
+		DefaultTTL:     600,
+		MaxConcurrency: 1,
+		Endpoints:      []string{godaddyEndpoint},
+		New:            func() DNS { return &GoDaddyDNS{} },
+	})
 }
 
-func (g *GoDaddyDNS) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (g *GoDaddyDNS) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	g.ctx = ctx
 	g.domains.Ipv4Cache = ipv4cache
 	g.domains.Ipv6Cache = ipv6cache
-	g.lastIpv4 = ipv4cache.Addr
-	g.lastIpv6 = ipv6cache.Addr
 
 	g.dns = dnsConf.DNS
-	g.domains.GetNewIp(dnsConf)
+	g.domains.GetNewIp(ctx, dnsConf)
 	g.ttl = 600
 	if val, err := strconv.Atoi(dnsConf.TTL); err == nil {
 		g.ttl = val
@@ -50,70 +75,119 @@ func (g *GoDaddyDNS) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ip
 	g.client = dnsConf.GetHTTPClient()
 }
 
-func (g *GoDaddyDNS) updateDomainRecord(recordType string, ipAddr string, domains []*config.Domain) {
-	if ipAddr == "" {
+func (g *GoDaddyDNS) AddUpdateDomainRecords() config.Domains {
+	Reconcile(g.ctx, g, g.domains.GetAllNewIpResult("A/AAAA"))
+	return g.domains
+}
+
+// ListRecords 查询记录, GoDaddy 的记录没有ID, 使用记录值作为ID
+// https://developer.godaddy.com/doc/endpoint/domains#/v1/recordGet
+func (g *GoDaddyDNS) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	var result godaddyRecords
+	if err = g.sendReq(http.MethodGet, recordType, domain, nil, &result); err != nil {
 		return
 	}
+	for _, r := range result {
+		records = append(records, Record{ID: r.Data, Type: recordType, Value: r.Data})
+	}
+	g.setRecordSet(domain, recordType, recordValues(records))
+	return
+}
+
+// CreateRecord 在记录集中新增一条记录
+func (g *GoDaddyDNS) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	values := g.recordSet(domain, recordType)
+	return g.SetRecords(domain, recordType, nil, append(values, ipAddr))
+}
 
-	// 防止多次发送Webhook通知
-	if recordType == "A" {
-		if g.lastIpv4 == ipAddr {
-			util.Log("你的IPv4未变化, 未触发 %s 请求", "godaddy")
-			return
+// UpdateRecord GoDaddy 只能整体替换记录集, 替换时保留其他记录
+func (g *GoDaddyDNS) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
+	values := g.recordSet(domain, record.Type)
+	for i, value := range values {
+		if value == record.Value {
+			values[i] = ipAddr
 		}
-	} else {
-		if g.lastIpv6 == ipAddr {
-			util.Log("你的IPv6未变化, 未触发 %s 请求", "godaddy")
-			return
+	}
+	return g.SetRecords(domain, record.Type, nil, values)
+}
+
+// DeleteRecord 从记录集中删除一条记录
+func (g *GoDaddyDNS) DeleteRecord(domain *config.Domain, record Record) error {
+	var values []string
+	for _, value := range g.recordSet(domain, record.Type) {
+		if value != record.Value {
+			values = append(values, value)
 		}
 	}
+	return g.SetRecords(domain, record.Type, []Record{record}, values)
+}
 
-	for _, domain := range domains {
-		err := g.sendReq(http.MethodPut, recordType, domain, &godaddyRecords{godaddyRecord{
-			Data: ipAddr,
+// SetRecords 将记录集替换为 values, values 为空时删除记录集
+// https://developer.godaddy.com/doc/endpoint/domains#/v1/recordReplaceTypeName
+func (g *GoDaddyDNS) SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error {
+	if len(values) == 0 {
+		if len(records) == 0 {
+			return nil
+		}
+		if err := g.sendReq(http.MethodDelete, recordType, domain, nil, nil); err != nil {
+			return err
+		}
+		g.setRecordSet(domain, recordType, nil)
+		return nil
+	}
+	data := make(godaddyRecords, 0, len(values))
+	for _, value := range values {
+		// 修改多条记录后可能出现相同的值, 相同的值只保留一条
+		if slices.ContainsFunc(data, func(r godaddyRecord) bool { return r.Data == value }) {
+			continue
+		}
+		data = append(data, godaddyRecord{
+			Data: value,
 			Name: domain.GetSubDomain(),
 			TTL:  g.ttl,
 			Type: recordType,
-		}})
-		if err == nil {
-			util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-			domain.UpdateStatus = config.UpdatedSuccess
-		} else {
-			util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-			domain.UpdateStatus = config.UpdatedFailed
-		}
+		})
+	}
+	if err := g.sendReq(http.MethodPut, recordType, domain, &data, nil); err != nil {
+		return err
 	}
+	g.setRecordSet(domain, recordType, values)
+	return nil
 }
 
-func (g *GoDaddyDNS) AddUpdateDomainRecords() config.Domains {
-	if ipv4Addr, ipv4Domains := g.domains.GetNewIpResult("A"); ipv4Addr != "" {
-		g.updateDomainRecord("A", ipv4Addr, ipv4Domains)
-	}
-	if ipv6Addr, ipv6Domains := g.domains.GetNewIpResult("AAAA"); ipv6Addr != "" {
-		g.updateDomainRecord("AAAA", ipv6Addr, ipv6Domains)
-	}
-	return g.domains
+// recordSet 记录集当前的值, 来自 ListRecords 的结果及之后的修改, 避免每次修改前重新查询
+func (g *GoDaddyDNS) recordSet(domain *config.Domain, recordType string) []string {
+	return slices.Clone(g.recordSets[domain.String()+" "+recordType])
 }
 
-func (g *GoDaddyDNS) sendReq(method string, rType string, domain *config.Domain, data *godaddyRecords) error {
+func (g *GoDaddyDNS) setRecordSet(domain *config.Domain, recordType string, values []string) {
+	if g.recordSets == nil {
+		g.recordSets = map[string][]string{}
+	}
+	g.recordSets[domain.String()+" "+recordType] = slices.Clone(values)
+}
 
-	var body *bytes.Buffer
+func (g *GoDaddyDNS) sendReq(method string, rType string, domain *config.Domain, data *godaddyRecords, result *godaddyRecords) error {
+	var body io.Reader
 	if data != nil {
-		if buffer, err := json.Marshal(data); err != nil {
+		buffer, err := json.Marshal(data)
+		if err != nil {
 			return err
-		} else {
-			body = bytes.NewBuffer(buffer)
 		}
+		body = bytes.NewBuffer(buffer)
 	}
-	path := fmt.Sprintf("https://api.godaddy.com/v1/domains/%s/records/%s/%s",
-		domain.DomainName, rType, domain.GetSubDomain())
+	path := fmt.Sprintf("%s/v1/domains/%s/records/%s/%s",
+		godaddyEndpoint, domain.DomainName, rType, domain.GetSubDomain())
 
-	req, err := http.NewRequest(method, path, body)
+	req, err := http.NewRequestWithContext(g.ctx, method, path, body)
 	if err != nil {
 		return err
 	}
 	req.Header = g.header
 	resp, err := g.client.Do(req)
-	_, err = util.GetHTTPResponseOrg(resp, err)
-	return err
+	if result == nil {
+		_, err = util.GetHTTPResponseOrg(resp, err)
+		return err
+	}
+	return util.GetHTTPResponse(resp, err, result)
 }
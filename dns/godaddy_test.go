@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestGoDaddyAddUpdateDomainRecords(t *testing.T) {
+	tests := []struct {
+		name       string
+		records    []string
+		ipAddr     string
+		wantMethod string // 为空时只查询
+		wantValues []string
+	}{
+		{
+			name:    "ip unchanged, no update request",
+			records: []string{"192.0.2.1"},
+			ipAddr:  "192.0.2.1",
+		},
+		{
+			name:       "ip changed, replace record set",
+			records:    []string{"192.0.2.1"},
+			ipAddr:     "192.0.2.2",
+			wantMethod: "PUT",
+			wantValues: []string{"192.0.2.2"},
+		},
+		{
+			name:       "record not found, create record set",
+			ipAddr:     "192.0.2.2",
+			wantMethod: "PUT",
+			wantValues: []string{"192.0.2.2"},
+		},
+		{
+			name:       "duplicated records are all updated",
+			records:    []string{"192.0.2.1", "192.0.2.3"},
+			ipAddr:     "192.0.2.2",
+			wantMethod: "PUT",
+			wantValues: []string{"192.0.2.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := slices.Clone(tt.records)
+			var lastMethod string
+			gets := 0
+			client := &http.Client{
+				Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+					if request.URL.Path != "/v1/domains/example.com/records/A/www" {
+						t.Errorf("path = %s", request.URL.Path)
+					}
+					body := "[]"
+					switch request.Method {
+					case "GET":
+						gets++
+						var records godaddyRecords
+						for _, v := range current {
+							records = append(records, godaddyRecord{Data: v, Name: "www", TTL: 600, Type: "A"})
+						}
+						byt, _ := json.Marshal(records)
+						body = string(byt)
+					case "PUT":
+						lastMethod = request.Method
+						var records godaddyRecords
+						if err := json.NewDecoder(request.Body).Decode(&records); err != nil {
+							t.Fatalf("decode request body: %v", err)
+						}
+						current = nil
+						for _, r := range records {
+							current = append(current, r.Data)
+						}
+					default:
+						lastMethod = request.Method
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(body)),
+						Header:     make(http.Header),
+					}, nil
+				}),
+			}
+
+			g := &GoDaddyDNS{ttl: 600, client: client, header: http.Header{}, ctx: context.Background()}
+			g.domains.Ipv4Cache = &util.IpCache{Times: 1}
+			g.domains.Ipv6Cache = &util.IpCache{}
+			g.domains.Ipv4Addr = tt.ipAddr
+			g.domains.Ipv4Domains = []*config.Domain{{DomainName: "example.com", SubDomain: "www"}}
+
+			g.AddUpdateDomainRecords()
+
+			if lastMethod != tt.wantMethod {
+				t.Errorf("method = %q, want %q", lastMethod, tt.wantMethod)
+			}
+			// 修改时使用已查询到的记录集, 不再重新查询
+			if gets != 1 {
+				t.Errorf("GET requests = %d, want 1", gets)
+			}
+			if tt.wantMethod != "" && !slices.Equal(current, tt.wantValues) {
+				t.Errorf("records = %v, want %v", current, tt.wantValues)
+			}
+		})
+	}
+}
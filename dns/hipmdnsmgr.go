@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -25,6 +26,7 @@ type HiPMDnsMgr struct {
 	lastIpv4   string
 	lastIpv6   string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // DnsMgrApiResponse DNSMgr API 响应结构
@@ -73,15 +75,35 @@ type DnsMgrRecordList struct {
 	List  []DnsMgrRecord `json:"list"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "hipmdnsmgr",
+		Order: 27,
+		Name: map[string]string{
+			"en":    "HiPM DNSMgr",
+			"zh-cn": "HiPM DNSMgr",
+		},
+		IDLabel:     "Base URL",
+		SecretLabel: "API Token",
+		HelpHTML: map[string]string{
+			"en":    "Enter your DNSMgr URL (e.g., http://localhost:3001) and API Token. Create token in DNSMgr Settings > API Tokens.",
+			"zh-cn": "输入 DNSMgr 地址（如 http://localhost:3001）和 API Token。在 DNSMgr 设置 > API Token 中创建令牌。",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &HiPMDnsMgr{} },
+	})
+}
+
 // Init 初始化
-func (h *HiPMDnsMgr) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (h *HiPMDnsMgr) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	h.ctx = ctx
 	h.Domains.Ipv4Cache = ipv4cache
 	h.Domains.Ipv6Cache = ipv6cache
 	h.lastIpv4 = ipv4cache.Addr
 	h.lastIpv6 = ipv6cache.Addr
 
 	h.DNS = dnsConf.DNS
-	h.Domains.GetNewIp(dnsConf)
+	h.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		h.TTL = "600"
 	} else {
@@ -92,77 +114,111 @@ func (h *HiPMDnsMgr) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ip
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (h *HiPMDnsMgr) AddUpdateDomainRecords() config.Domains {
-	h.addUpdateDomainRecords("A")
-	h.addUpdateDomainRecords("AAAA")
-	return h.Domains
-}
-
-func (h *HiPMDnsMgr) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := h.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
-		return
-	}
-
 	// 防止多次发送Webhook通知
-	if recordType == "A" {
-		if h.lastIpv4 == ipAddr {
-			util.Log("你的IPv4未变化, 未触发 %s 请求", "HiPMDnsMgr")
-			return
-		}
-	} else {
-		if h.lastIpv6 == ipAddr {
-			util.Log("你的IPv6未变化, 未触发 %s 请求", "HiPMDnsMgr")
-			return
-		}
+	skipIpv4 := h.Domains.Ipv4Addr != "" && h.lastIpv4 == h.Domains.Ipv4Addr
+	if skipIpv4 {
+		util.Log("你的IPv4未变化, 未触发 %s 请求", "HiPMDnsMgr")
+	}
+	skipIpv6 := h.Domains.Ipv6Addr != "" && h.lastIpv6 == h.Domains.Ipv6Addr
+	if skipIpv6 {
+		util.Log("你的IPv6未变化, 未触发 %s 请求", "HiPMDnsMgr")
 	}
 
-	for _, domain := range domains {
-		err := h.updateRecord(domain, ipAddr, recordType)
-		if err != nil {
-			util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-			domain.UpdateStatus = config.UpdatedFailed
-		} else {
-			util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-			domain.UpdateStatus = config.UpdatedSuccess
+	tuples := filterTuples(h.Domains.GetAllNewIpResult("A/AAAA"), func(_ *config.Domain, ipAddr string) bool {
+		if recordTypeOf(ipAddr) == "AAAA" {
+			return !skipIpv6
 		}
-	}
+		return !skipIpv4
+	})
+	Reconcile(h.ctx, h, tuples)
+	return h.Domains
 }
 
-// updateRecord 更新或创建 DNS 记录
-func (h *HiPMDnsMgr) updateRecord(domain *config.Domain, ipAddr string, recordType string) error {
+// baseURL 返回 DNSMgr 地址和 API Token
+func (h *HiPMDnsMgr) baseURL() (string, string, error) {
 	baseURL := h.DNS.ID
 	if baseURL == "" {
 		baseURL = hipmDnsMgrEndpoint
 	}
 	apiToken := h.DNS.Secret
 	if apiToken == "" {
-		return fmt.Errorf("API token cannot be empty")
+		return "", "", fmt.Errorf("API token cannot be empty")
+	}
+	return baseURL, apiToken, nil
+}
+
+// ListRecords 查询记录, Raw 为所在的域名ID
+func (h *HiPMDnsMgr) ListRecords(domain *config.Domain, recordType string) ([]Record, error) {
+	baseURL, apiToken, err := h.baseURL()
+	if err != nil {
+		return nil, err
 	}
 
 	// Get domain ID
+	domainID, err := h.getDomainID(baseURL, apiToken, domain.DomainName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain ID: %w", err)
+	}
+
+	// Get existing records
+	found, err := h.getRecords(baseURL, apiToken, domainID, domain.SubDomain, recordType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	var records []Record
+	for _, r := range found {
+		records = append(records, Record{ID: r.ID, Type: r.Type, Value: r.Value, Raw: domainID})
+	}
+	return records, nil
+}
+
+// CreateRecord 创建记录
+func (h *HiPMDnsMgr) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	baseURL, apiToken, err := h.baseURL()
+	if err != nil {
+		return err
+	}
+
 	domainID, err := h.getDomainID(baseURL, apiToken, domain.DomainName)
 	if err != nil {
 		return fmt.Errorf("failed to get domain ID: %w", err)
 	}
+	return h.createRecord(baseURL, apiToken, domainID, domain.SubDomain, recordType, ipAddr, h.ttl())
+}
+
+// UpdateRecord 更新记录
+func (h *HiPMDnsMgr) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	baseURL, apiToken, err := h.baseURL()
+	if err != nil {
+		return err
+	}
+	return h.updateExistingRecord(baseURL, apiToken, r.Raw.(int), r.ID, domain.SubDomain, r.Type, ipAddr, h.ttl())
+}
 
-	// Get existing record
-	record, err := h.getRecord(baseURL, apiToken, domainID, domain.SubDomain, recordType)
+// DeleteRecord 删除记录
+func (h *HiPMDnsMgr) DeleteRecord(domain *config.Domain, r Record) error {
+	baseURL, apiToken, err := h.baseURL()
 	if err != nil {
-		return fmt.Errorf("failed to get record: %w", err)
+		return err
 	}
 
+	apiResp, err := h.request(baseURL, apiToken, "DELETE", fmt.Sprintf("/domains/%d/records/%s", r.Raw.(int), r.ID), nil)
+	if err != nil {
+		return err
+	}
+	if apiResp.Code != 0 {
+		return fmt.Errorf("API error: %s", apiResp.Msg)
+	}
+	return nil
+}
+
+// ttl 返回TTL, 默认600
+func (h *HiPMDnsMgr) ttl() int {
 	ttl, _ := strconv.Atoi(h.TTL)
 	if ttl == 0 {
 		ttl = 600
 	}
-
-	if record != nil {
-		// Update existing record
-		return h.updateExistingRecord(baseURL, apiToken, domainID, record.ID, domain.SubDomain, recordType, ipAddr, ttl)
-	}
-	// Create new record
-	return h.createRecord(baseURL, apiToken, domainID, domain.SubDomain, recordType, ipAddr, ttl)
+	return ttl
 }
 
 // getHeaders 获取请求头
@@ -200,7 +256,7 @@ func (h *HiPMDnsMgr) request(baseURL, apiToken, method, path string, body interf
 		bodyReader = bytes.NewBuffer(nil)
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(h.ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -336,9 +392,10 @@ func (h *HiPMDnsMgr) getDomainID(baseURL, apiToken, domainName string) (int, err
 	return 0, fmt.Errorf("domain %s not found", domainName)
 }
 
-// getRecord Get DNS record
-// Paginate through all records to find the target
-func (h *HiPMDnsMgr) getRecord(baseURL, apiToken string, domainID int, subDomain, recordType string) (*DnsMgrRecord, error) {
+// getRecords Get DNS records
+// Paginate through all records to find the targets
+func (h *HiPMDnsMgr) getRecords(baseURL, apiToken string, domainID int, subDomain, recordType string) ([]DnsMgrRecord, error) {
+	var records []DnsMgrRecord
 	const pageSize = 100
 	currentPage := 1
 
@@ -360,10 +417,10 @@ func (h *HiPMDnsMgr) getRecord(baseURL, apiToken string, domainID int, subDomain
 			return nil, fmt.Errorf("failed to parse record list: %w", err)
 		}
 
-		// Find matching record in current page
+		// Find matching records in current page
 		for _, r := range recordList.List {
 			if r.Name == subDomain && r.Type == recordType {
-				return &r, nil
+				records = append(records, r)
 			}
 		}
 
@@ -380,7 +437,7 @@ func (h *HiPMDnsMgr) getRecord(baseURL, apiToken string, domainID int, subDomain
 		}
 	}
 
-	return nil, nil
+	return records, nil
 }
 
 // createRecord Create new record
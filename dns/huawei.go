@@ -2,10 +2,13 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 
 	"github.com/jeessy2/ddns-go/v6/config"
@@ -23,6 +26,7 @@ type Huaweicloud struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // HuaweicloudZonesResp zones response
@@ -51,12 +55,33 @@ type HuaweicloudRecordsets struct {
 	Weight  int      `json:"weight"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "huaweicloud",
+		Order: 6,
+		Name: map[string]string{
+			"en":    "Huawei",
+			"zh-cn": "华为云",
+		},
+		IDLabel:     "Access Key Id",
+		SecretLabel: "Secret Access Key",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.huaweicloud.com/iam/?locale=zh-cn#/mine/accessKey'>Create</a>",
+			"zh-cn": "<a target='_blank' href='https://console.huaweicloud.com/iam/?locale=zh-cn#/mine/accessKey'>新增访问密钥</a>",
+		},
+		DefaultTTL: 300,
+		Endpoints:  []string{huaweicloudEndpoint},
+		New:        func() DNS { return &Huaweicloud{} },
+	})
+}
+
 // Init 初始化
-func (hw *Huaweicloud) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (hw *Huaweicloud) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	hw.ctx = ctx
 	hw.Domains.Ipv4Cache = ipv4cache
 	hw.Domains.Ipv6Cache = ipv6cache
 	hw.DNS = dnsConf.DNS
-	hw.Domains.GetNewIp(dnsConf)
+	hw.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认300s
 		hw.TTL = 300
@@ -73,110 +98,106 @@ func (hw *Huaweicloud) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache,
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (hw *Huaweicloud) AddUpdateDomainRecords() config.Domains {
-	hw.addUpdateDomainRecords("A")
-	hw.addUpdateDomainRecords("AAAA")
+	Reconcile(hw.ctx, hw, hw.Domains.GetAllNewIpResult("A/AAAA"))
 	return hw.Domains
 }
 
-func (hw *Huaweicloud) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := hw.Domains.GetNewIpResult(recordType)
+// ListRecords 查询记录集
+func (hw *Huaweicloud) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	customParams := domain.GetCustomParams()
+	params := url.Values{}
+	params.Set("name", domain.String())
+	params.Set("type", recordType)
+
+	// 如果有精准匹配
+	// 详见 查询记录集 https://support.huaweicloud.com/api-dns/dns_api_64002.html
+	if customParams.Has("zone_id") && customParams.Has("recordset_id") {
+		var record HuaweicloudRecordsets
+		err = hw.request(
+			"GET",
+			fmt.Sprintf(huaweicloudEndpoint+"/v2.1/zones/%s/recordsets/%s", customParams.Get("zone_id"), customParams.Get("recordset_id")),
+			params,
+			&record,
+		)
+		if err != nil {
+			return
+		}
+		return huaweicloudRecords(record), nil
+	}
 
-	if ipAddr == "" {
+	// 没有精准匹配，则支持更多的查询参数。详见 查询租户记录集列表 https://support.huaweicloud.com/api-dns/dns_api_64003.html
+	// 复制所有自定义参数
+	util.CopyUrlParams(customParams, params, nil)
+	// 参数名修正
+	if params.Has("recordset_id") {
+		params.Set("id", params.Get("recordset_id"))
+		params.Del("recordset_id")
+	}
+
+	var result HuaweicloudRecordsResp
+	err = hw.request(
+		"GET",
+		huaweicloudEndpoint+"/v2.1/recordsets",
+		params,
+		&result,
+	)
+	if err != nil {
 		return
 	}
 
-	for _, domain := range domains {
-		customParams := domain.GetCustomParams()
-		params := url.Values{}
-		params.Set("name", domain.String())
-		params.Set("type", recordType)
-
-		// 如果有精准匹配
-		// 详见 查询记录集 https://support.huaweicloud.com/api-dns/dns_api_64002.html
-		if customParams.Has("zone_id") && customParams.Has("recordset_id") {
-			var record HuaweicloudRecordsets
-			err := hw.request(
-				"GET",
-				fmt.Sprintf(huaweicloudEndpoint+"/v2.1/zones/%s/recordsets/%s", customParams.Get("zone_id"), customParams.Get("recordset_id")),
-				params,
-				&record,
-			)
-
-			if err != nil {
-				util.Log("查询域名信息发生异常！ %s", err)
-				domain.UpdateStatus = config.UpdatedFailed
-				return
-			}
-
-			// 更新
-			hw.modify(record, domain, ipAddr)
-
-		} else { // 没有精准匹配，则支持更多的查询参数。详见 查询租户记录集列表 https://support.huaweicloud.com/api-dns/dns_api_64003.html
-			// 复制所有自定义参数
-			util.CopyUrlParams(customParams, params, nil)
-			// 参数名修正
-			if params.Has("recordset_id") {
-				params.Set("id", params.Get("recordset_id"))
-				params.Del("recordset_id")
-			}
-
-			var records HuaweicloudRecordsResp
-			err := hw.request(
-				"GET",
-				huaweicloudEndpoint+"/v2.1/recordsets",
-				params,
-				&records,
-			)
-
-			if err != nil {
-				util.Log("查询域名信息发生异常! %s", err)
-				domain.UpdateStatus = config.UpdatedFailed
-				return
-			}
-
-			find := false
-			for _, record := range records.Recordsets {
-				// 名称相同才更新。华为云默认是模糊搜索
-				if record.Name == domain.String()+"." {
-					// 更新
-					hw.modify(record, domain, ipAddr)
-					find = true
-					break
-				}
-			}
-
-			if !find {
-				thIdParamName := ""
-				if customParams.Has("id") {
-					thIdParamName = "id"
-				} else if customParams.Has("recordset_id") {
-					thIdParamName = "recordset_id"
-				}
-
-				if thIdParamName != "" {
-					util.Log("域名 %s 解析未找到，且因添加了参数 %s=%s 导致无法创建。本次更新已被忽略", domain, thIdParamName, customParams.Get(thIdParamName))
-				} else {
-					// 新增
-					hw.create(domain, recordType, ipAddr)
-				}
-			}
+	for _, record := range result.Recordsets {
+		// 名称相同才更新。华为云默认是模糊搜索
+		if record.Name == domain.String()+"." {
+			records = append(records, huaweicloudRecords(record)...)
 		}
 	}
+	return
+}
+
+// huaweicloudRecords 记录集中的每个地址返回一条记录
+func huaweicloudRecords(record HuaweicloudRecordsets) (records []Record) {
+	for _, value := range record.Records {
+		records = append(records, Record{ID: record.ID, Type: record.Type, Value: value, Raw: record})
+	}
+	return
+}
+
+// CreateRecord 创建
+func (hw *Huaweicloud) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	return hw.create(domain, recordType, []string{ipAddr})
+}
+
+// SetRecords 将记录集替换为 values, 存在多个记录集时修改选中的记录集
+func (hw *Huaweicloud) SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error {
+	if len(records) == 0 {
+		if len(values) == 0 {
+			return nil
+		}
+		return hw.create(domain, recordType, values)
+	}
+	record := selectRecords(domain, records)[0]
+	if len(values) == 0 {
+		return hw.DeleteRecord(domain, record)
+	}
+	return hw.update(record.Raw.(HuaweicloudRecordsets), values)
 }
 
-// 创建
-func (hw *Huaweicloud) create(domain *config.Domain, recordType string, ipAddr string) {
+// create 创建记录集
+func (hw *Huaweicloud) create(domain *config.Domain, recordType string, values []string) error {
+	customParams := domain.GetCustomParams()
+	for _, name := range []string{"id", "recordset_id"} {
+		if customParams.Has(name) {
+			return errors.New(util.LogStr("域名 %s 解析未找到，且因添加了参数 %s=%s 导致无法创建。本次更新已被忽略", domain, name, customParams.Get(name)))
+		}
+	}
+
 	zone, err := hw.getZones(domain)
 	if err != nil {
-		util.Log("查询域名信息发生异常! %s", err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
 
 	if len(zone.Zones) == 0 {
-		util.Log("在DNS服务商中未找到根域名: %s", domain.DomainName)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
 	}
 
 	zoneID := zone.Zones[0].ID
@@ -190,7 +211,7 @@ func (hw *Huaweicloud) create(domain *config.Domain, recordType string, ipAddr s
 	record := &HuaweicloudRecordsets{
 		Type:    recordType,
 		Name:    domain.String() + ".",
-		Records: []string{ipAddr},
+		Records: values,
 		TTL:     hw.TTL,
 		Weight:  1,
 	}
@@ -201,59 +222,54 @@ func (hw *Huaweicloud) create(domain *config.Domain, recordType string, ipAddr s
 		record,
 		&result,
 	)
-
 	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
-
-	if len(result.Records) > 0 && result.Records[0] == ipAddr {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, result.Status)
-		domain.UpdateStatus = config.UpdatedFailed
+	if !slices.Contains(result.Records, values[0]) {
+		return errors.New(result.Status)
 	}
+	return nil
 }
 
-// 修改
-func (hw *Huaweicloud) modify(record HuaweicloudRecordsets, domain *config.Domain, ipAddr string) {
-
-	// 相同不修改
-	if len(record.Records) > 0 && record.Records[0] == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// UpdateRecord 修改
+func (hw *Huaweicloud) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	return hw.update(r.Raw.(HuaweicloudRecordsets), []string{ipAddr})
+}
 
+// update 修改记录集
+func (hw *Huaweicloud) update(record HuaweicloudRecordsets, values []string) error {
 	var request = make(map[string]interface{})
 	request["name"] = record.Name
 	request["type"] = record.Type
-	request["records"] = []string{ipAddr}
+	request["records"] = values
 	request["ttl"] = hw.TTL
 
 	var result HuaweicloudRecordsets
-
 	err := hw.request(
 		"PUT",
 		fmt.Sprintf(huaweicloudEndpoint+"/v2.1/zones/%s/recordsets/%s", record.ZoneID, record.ID),
 		&request,
 		&result,
 	)
-
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
-
-	if len(result.Records) > 0 && result.Records[0] == ipAddr {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, result.Status)
-		domain.UpdateStatus = config.UpdatedFailed
+	if !slices.Contains(result.Records, values[0]) {
+		return errors.New(result.Status)
 	}
+	return nil
+}
+
+// DeleteRecord 删除
+func (hw *Huaweicloud) DeleteRecord(domain *config.Domain, r Record) error {
+	record := r.Raw.(HuaweicloudRecordsets)
+	var result HuaweicloudRecordsets
+	return hw.request(
+		"DELETE",
+		fmt.Sprintf(huaweicloudEndpoint+"/v2.1/zones/%s/recordsets/%s", record.ZoneID, record.ID),
+		nil,
+		&result,
+	)
 }
 
 // 获得域名记录列表
@@ -275,7 +291,8 @@ func (hw *Huaweicloud) request(method string, urlString string, data interface{}
 	)
 
 	if method == "GET" {
-		req, err = http.NewRequest(
+		req, err = http.NewRequestWithContext(
+			hw.ctx,
 			method,
 			urlString,
 			bytes.NewBuffer(nil),
@@ -288,7 +305,8 @@ func (hw *Huaweicloud) request(method string, urlString string, data interface{}
 			jsonStr, _ = json.Marshal(data)
 		}
 
-		req, err = http.NewRequest(
+		req, err = http.NewRequestWithContext(
+			hw.ctx,
 			method,
 			urlString,
 			bytes.NewBuffer(jsonStr),
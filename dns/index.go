@@ -1,6 +1,11 @@
 package dns
 
 import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jeessy2/ddns-go/v6/config"
@@ -9,132 +14,300 @@ import (
 
 // DNS interface
 type DNS interface {
-	Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache)
+	// 初始化, ctx 取消后进行中的请求会被中断
+	Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache)
 	// 添加或更新IPv4/IPv6记录
 	AddUpdateDomainRecords() (domains config.Domains)
 }
 
 var (
-	Addresses = []string{
-		alidnsEndpoint,
-		aliesaEndpoint,
-		baiduEndpoint,
-		zonesAPI,
-		recordListAPI,
-		huaweicloudEndpoint,
-		nameCheapEndpoint,
-		nameSiloListRecordEndpoint,
-		porkbunEndpoint,
-		tencentCloudEndPoint,
-		dynadotEndpoint,
-		dynv6Endpoint,
-		gcoreAPIEndpoint,
-		edgeoneEndPoint,
-		rainyunEndpoint,
-		CloudnsEndpoint,
-		desecEndpoint,
-	}
-
 	Ipcache = [][2]util.IpCache{}
+	// ipcacheKeys Ipcache 中各项对应的配置摘要, 配置增删后按摘要找回原来的缓存
+	ipcacheKeys []string
+
+	// Workers 同时更新的配置数量上限
+	Workers = 4
+
+	// runMu 保证同一时间只有一个 RunOnce 在运行, 以保护 Ipcache
+	runMu sync.Mutex
+
+	// providerSems 各DNS服务商的并发限制
+	providerSems   = map[string]chan struct{}{}
+	providerSemsMu sync.Mutex
+
+	// cancelRunning 取消正在运行的 RunOnce
+	cancelRunning   context.CancelFunc
+	cancelRunningMu sync.Mutex
 )
 
-// RunTimer 定时运行
-func RunTimer(delay time.Duration) {
+// RunTimer 定时运行, ctx 取消后返回
+// 设置了更新计划的配置按各自的计划运行, 其余配置每隔 delay 运行一次
+// Linux 下同时监听网卡变化, 使用网卡获取IP的配置在网卡变化后立即更新, 定时运行作为兜底
+func RunTimer(ctx context.Context, delay time.Duration) {
+	changes, err := watchInterfaces(ctx)
+	if err != nil {
+		util.Log("监听网卡变化失败, 将只定时检测IP变化: %s", err)
+	}
+
+	nextRunsMu.Lock()
+	scheduleDelay = delay
+	nextRunsMu.Unlock()
+
+	RunOnce(ctx)
+
+	timer := time.NewTimer(runDue(ctx))
+	defer timer.Stop()
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	changed := map[string]bool{}
 	for {
-		RunOnce()
-		time.Sleep(delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			timer.Reset(runDue(ctx))
+		case name, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			// 网卡变化通常连续出现多次, 等待稳定后再更新
+			changed[name] = true
+			debounce.Reset(watchDebounce)
+		case <-debounce.C:
+			runChanged(ctx, changed)
+			changed = map[string]bool{}
+		}
+	}
+}
+
+// watchDebounce 网卡变化后等待的时间
+const watchDebounce = 2 * time.Second
+
+// runChanged 只更新使用了发生变化的网卡的配置
+func runChanged(ctx context.Context, changed map[string]bool) {
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		return
+	}
+	if !slices.ContainsFunc(conf.DnsConf, func(dc config.DnsConfig) bool { return usesInterface(&dc, changed) }) {
+		return
+	}
+
+	names := slices.Sorted(maps.Keys(changed))
+	util.Log("网卡 %s 发生变化, 立即更新", strings.Join(names, ", "))
+	runConfigs(ctx, func(dc *config.DnsConfig) bool { return usesInterface(dc, changed) })
+}
+
+// usesInterface 配置是否通过指定的网卡获取IP
+func usesInterface(dc *config.DnsConfig, names map[string]bool) bool {
+	if dc.Ipv4.Enable && dc.Ipv4.GetType == "netInterface" && names[dc.Ipv4.NetInterface] {
+		return true
+	}
+	return dc.Ipv6.Enable && dc.Ipv6.GetType == "netInterface" && names[dc.Ipv6.NetInterface]
+}
+
+// CancelRunning 取消正在运行的更新, 如重新加载配置时
+func CancelRunning() {
+	cancelRunningMu.Lock()
+	defer cancelRunningMu.Unlock()
+
+	if cancelRunning != nil {
+		cancelRunning()
 	}
 }
 
 // RunOnce RunOnce
-func RunOnce() {
+func RunOnce(ctx context.Context) {
+	runConfigs(ctx, nil)
+}
+
+// runConfigs 更新 match 返回 true 的配置, match 为 nil 时更新全部配置
+func runConfigs(ctx context.Context, match func(dc *config.DnsConfig) bool) {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancelRunningMu.Lock()
+	cancelRunning = cancel
+	cancelRunningMu.Unlock()
+	defer func() {
+		cancelRunningMu.Lock()
+		cancelRunning = nil
+		cancelRunningMu.Unlock()
+		cancel()
+	}()
+
 	conf, err := config.GetConfigCached()
 	if err != nil {
 		return
 	}
-	if util.ForceCompareGlobal || len(Ipcache) != len(conf.DnsConf) {
-		Ipcache = [][2]util.IpCache{}
-		for range conf.DnsConf {
-			Ipcache = append(Ipcache, [2]util.IpCache{{}, {}})
+	// 本轮中获取方式相同的配置只获取一次地址
+	ctx = config.WithDetectCache(ctx)
+	remapIpcache(&conf, util.ForceCompareGlobal)
+	defer saveState(&conf)
+
+	workers := Workers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range conf.DnsConf {
+		if ctx.Err() != nil {
+			break
+		}
+		if match != nil && !match(&conf.DnsConf[i]) {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			runDnsConfig(ctx, i, &conf.DnsConf[i], &conf)
+		}(i)
+	}
+	wg.Wait()
+
+	// 被取消时保留 ForceCompareGlobal, 下次运行时重新比对
+	if ctx.Err() == nil {
+		util.ForceCompareGlobal = false
+	}
+}
+
+// remapIpcache 按配置的摘要重新排列 Ipcache, 新增或修改过的配置使用空缓存
+// reset 为 true 时清空缓存的地址以重新比对, 保留获取IP失败的次数和稳定状态
+func remapIpcache(conf *config.Config, reset bool) {
+	old := ipcacheByKey()
+
+	Ipcache = make([][2]util.IpCache, len(conf.DnsConf))
+	ipcacheKeys = make([]string, len(conf.DnsConf))
+	for i := range conf.DnsConf {
+		key := configKey(&conf.DnsConf[i])
+		ipcacheKeys[i] = key
+		c := old[key]
+		if reset {
+			c[0].Reset()
+			c[1].Reset()
 		}
+		Ipcache[i] = c
 	}
+}
 
-	for i, dc := range conf.DnsConf {
-		var dnsSelected DNS
-		switch dc.DNS.Name {
-		case "alidns":
-			dnsSelected = &Alidns{}
-		case "aliesa":
-			dnsSelected = &Aliesa{}
-		case "tencentcloud":
-			dnsSelected = &TencentCloud{}
-		case "trafficroute":
-			dnsSelected = &TrafficRoute{}
-		case "dnspod":
-			dnsSelected = &Dnspod{}
-		case "dnsla":
-			dnsSelected = &Dnsla{}
-		case "cloudflare":
-			dnsSelected = &Cloudflare{}
-		case "huaweicloud":
-			dnsSelected = &Huaweicloud{}
-		case "callback":
-			dnsSelected = &Callback{}
-		case "baiducloud":
-			dnsSelected = &BaiduCloud{}
-		case "porkbun":
-			dnsSelected = &Porkbun{}
-		case "godaddy":
-			dnsSelected = &GoDaddyDNS{}
-		case "namecheap":
-			dnsSelected = &NameCheap{}
-		case "namesilo":
-			dnsSelected = &NameSilo{}
-		case "vercel":
-			dnsSelected = &Vercel{}
-		case "dynadot":
-			dnsSelected = &Dynadot{}
-		case "dynv6":
-			dnsSelected = &Dynv6{}
-		case "spaceship":
-			dnsSelected = &Spaceship{}
-		case "nowcn":
-			dnsSelected = &Nowcn{}
-		case "eranet":
-			dnsSelected = &Eranet{}
-		case "tnethk":
-			dnsSelected = &Tnethk{}
-		case "gcore":
-			dnsSelected = &Gcore{}
-		case "edgeone":
-			dnsSelected = &EdgeOne{}
-		case "nsone":
-			dnsSelected = &NSOne{}
-		case "name_com":
-			dnsSelected = &NameCom{}
-		case "rainyun":
-			dnsSelected = &Rainyun{}
-		case "hipmdnsmgr":
-			dnsSelected = &HiPMDnsMgr{}
-		case "cloudns":
-			dnsSelected = &ClouDNS{}
-		case "desec":
-			dnsSelected = &DeSEC{}
-		default:
-			dnsSelected = &Alidns{}
+// ipcacheByKey 以配置的摘要为键的 Ipcache 副本, 需持有 runMu
+func ipcacheByKey() map[string][2]util.IpCache {
+	caches := make(map[string][2]util.IpCache, len(ipcacheKeys))
+	for i, key := range ipcacheKeys {
+		if i < len(Ipcache) {
+			caches[key] = Ipcache[i]
 		}
-		dnsSelected.Init(&dc, &Ipcache[i][0], &Ipcache[i][1])
-		domains := dnsSelected.AddUpdateDomainRecords()
-		// webhook
-		v4Status, v6Status := config.ExecWebhook(&domains, &conf)
-		// 重置单个cache
-		if v4Status == config.UpdatedFailed {
-			Ipcache[i][0] = util.IpCache{}
+	}
+	return caches
+}
+
+// runDnsConfig 更新单个配置, 每个配置只访问自己的 Ipcache[i]
+func runDnsConfig(ctx context.Context, i int, dc *config.DnsConfig, conf *config.Config) {
+	p, ok := GetProvider(dc.DNS.Name)
+	if !ok {
+		// 未知的DNS服务商, 不再回退到阿里云, 避免凭证被发送到错误的服务商
+		util.Log("第 %s 个配置的DNS服务商 %s 不存在, 已跳过", util.Ordinal(i+1, conf.Lang), dc.DNS.Name)
+		return
+	}
+
+	if until := PausedUntil(dc); !until.IsZero() {
+		util.Log("第 %s 个配置认证失败, 已暂停更新至 %s, 修改配置后恢复", util.Ordinal(i+1, conf.Lang), until.Format(time.DateTime))
+		return
+	}
+
+	if p.MaxConcurrency > 0 {
+		providerSem := getProviderSem(p.Key, p.MaxConcurrency)
+		select {
+		case providerSem <- struct{}{}:
+			defer func() { <-providerSem }()
+		case <-ctx.Done():
+			return
 		}
-		if v6Status == config.UpdatedFailed {
-			Ipcache[i][1] = util.IpCache{}
+	}
+
+	// 每个配置单独超时
+	timeoutCtx, cancel := context.WithTimeout(ctx, dc.GetTimeout())
+	defer cancel()
+
+	verified := &verifiedRecords{keys: map[string]bool{}}
+	timeoutCtx = withVerifiedRecords(timeoutCtx, verified)
+
+	dnsSelected := p.New()
+	dnsSelected.Init(timeoutCtx, dc, &Ipcache[i][0], &Ipcache[i][1])
+	domains := dnsSelected.AddUpdateDomainRecords()
+	reconcileStale(timeoutCtx, dnsSelected, dc, &domains)
+	recordAddrHistory(dc, &domains, time.Now())
+
+	if ctx.Err() != nil {
+		// 停止或重新加载配置, 记录已完成的部分后直接返回, 不触发webhook
+		util.Log("第 %s 个配置的更新已取消, 部分结果: %s", util.Ordinal(i+1, conf.Lang), domainsResult(&domains))
+		Ipcache[i][0].Reset()
+		Ipcache[i][1].Reset()
+		return
+	}
+	updateLastRecords(dc, &domains, verified)
+	if timeoutCtx.Err() != nil {
+		util.Log("第 %s 个配置的更新超时, 部分结果: %s", util.Ordinal(i+1, conf.Lang), domainsResult(&domains))
+	}
+	verifyPublished(timeoutCtx, dc, &domains)
+	if ctx.Err() != nil {
+		// 等待生效时停止或重新加载配置, 不触发webhook
+		return
+	}
+
+	if until := pauseOnAuthFailure(dc, &domains, time.Now()); !until.IsZero() {
+		util.Log("第 %s 个配置认证失败, 已暂停更新至 %s, 修改配置后恢复", util.Ordinal(i+1, conf.Lang), until.Format(time.DateTime))
+	}
+
+	// webhook
+	if cgnatDetected(dc, &domains) {
+		config.ExecCGNATWebhook(&domains, conf)
+	}
+	v4Status, v6Status := config.ExecWebhook(&domains, conf, configKey(dc))
+	// 重置单个cache, 保留获取IP失败的次数和稳定状态; 重试也不会成功的错误保留cache, 避免反复请求
+	if v4Status == config.UpdatedFailed && !permanentFailure(domains.Ipv4Domains) {
+		Ipcache[i][0].Reset()
+	}
+	if v6Status == config.UpdatedFailed && !permanentFailure(domains.Ipv6Domains) {
+		Ipcache[i][1].Reset()
+	}
+}
+
+// domainsResult 各域名的更新状态, 用于记录被中断时的部分结果
+func domainsResult(domains *config.Domains) string {
+	var results []string
+	status := func(d *config.Domain) string {
+		if d.UpdateStatus == "" {
+			return util.LogStr("未处理")
 		}
+		return util.LogStr(string(d.UpdateStatus))
+	}
+	for _, d := range domains.Ipv4Domains {
+		results = append(results, d.String()+"(A): "+status(d))
 	}
+	for _, d := range domains.Ipv6Domains {
+		results = append(results, d.String()+"(AAAA): "+status(d))
+	}
+	return strings.Join(results, ", ")
+}
 
-	util.ForceCompareGlobal = false
+// getProviderSem 获取DNS服务商的并发信号量
+func getProviderSem(key string, max int) chan struct{} {
+	providerSemsMu.Lock()
+	defer providerSemsMu.Unlock()
+
+	sem, ok := providerSems[key]
+	if !ok {
+		sem = make(chan struct{}, max)
+		providerSems[key] = sem
+	}
+	return sem
 }
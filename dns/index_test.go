@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestGetProviderSem(t *testing.T) {
+	sem := getProviderSem("test-provider", 2)
+	if cap(sem) != 2 {
+		t.Fatalf("cap(sem) = %d, want 2", cap(sem))
+	}
+	if getProviderSem("test-provider", 5) != sem {
+		t.Error("getProviderSem should return the same semaphore for the same provider")
+	}
+
+	// 并发数不应超过上限
+	var (
+		mu      sync.Mutex
+		running int
+		maxSeen int
+		wg      sync.WaitGroup
+	)
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			mu.Lock()
+			running++
+			maxSeen = max(maxSeen, running)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if maxSeen > 2 {
+		t.Errorf("max concurrent = %d, want <= 2", maxSeen)
+	}
+}
+
+func TestUsesInterface(t *testing.T) {
+	var dc config.DnsConfig
+	dc.Ipv4.Enable = true
+	dc.Ipv4.GetType = "netInterface"
+	dc.Ipv4.NetInterface = "ppp0"
+	dc.Ipv6.Enable = true
+	dc.Ipv6.GetType = "url"
+	dc.Ipv6.NetInterface = "eth0"
+
+	tests := []struct {
+		names map[string]bool
+		want  bool
+	}{
+		{map[string]bool{"ppp0": true}, true},
+		{map[string]bool{"eth0": true}, false},
+		{map[string]bool{"eth1": true, "ppp0": true}, true},
+		{map[string]bool{}, false},
+	}
+	for _, tt := range tests {
+		if got := usesInterface(&dc, tt.names); got != tt.want {
+			t.Errorf("usesInterface(%v) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+
+	dc.Ipv4.Enable = false
+	if usesInterface(&dc, map[string]bool{"ppp0": true}) {
+		t.Error("usesInterface should ignore disabled IPv4")
+	}
+}
+
+// TestRemapIpcache 保存配置后等待稳定的地址仍需等待, 新增的配置使用空缓存
+func TestRemapIpcache(t *testing.T) {
+	t.Cleanup(func() {
+		Ipcache = [][2]util.IpCache{}
+		ipcacheKeys = nil
+	})
+
+	held := config.DnsConfig{Name: "held", DNS: config.DNS{Name: "cloudflare"}}
+	added := config.DnsConfig{Name: "added", DNS: config.DNS{Name: "cloudflare"}}
+	now := time.Now()
+
+	remapIpcache(&config.Config{DnsConf: []config.DnsConfig{held}}, true)
+	cache := &Ipcache[0][0]
+	cache.Check("192.0.2.1")
+	cache.Stable("192.0.2.1", 3, 0, now)
+	cache.TimesFailedIP = 2
+	if stable, _ := cache.Stable("192.0.2.2", 3, 0, now); stable {
+		t.Fatal("new address should be held back")
+	}
+
+	// 保存配置: 新增一个配置并放在前面
+	remapIpcache(&config.Config{DnsConf: []config.DnsConfig{added, held}}, true)
+	if Ipcache[0][0] != (util.IpCache{}) {
+		t.Errorf("added config cache = %+v, want empty", Ipcache[0][0])
+	}
+	cache = &Ipcache[1][0]
+	if cache.Addr != "" || cache.Times != 0 {
+		t.Errorf("cache address not reset: %+v", cache)
+	}
+	if cache.StableAddr != "192.0.2.1" || cache.TimesFailedIP != 2 {
+		t.Errorf("cache state lost: %+v", cache)
+	}
+	if stable, _ := cache.Stable("192.0.2.2", 3, 0, now); stable {
+		t.Error("address should still be held back after saving config")
+	}
+	if stable, _ := cache.Stable("192.0.2.2", 3, 0, now); !stable {
+		t.Error("address should be stable after 3 times")
+	}
+}
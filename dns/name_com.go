@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -17,6 +18,7 @@ const (
 	listRecords  = "https://api.name.com/core/v1/domains/%s/records"
 	createRecord = "https://api.name.com/core/v1/domains/%s/records"
 	updateRecord = "https://api.name.com/core/v1/domains/%s/records/%d"
+	deleteRecord = "https://api.name.com/core/v1/domains/%s/records/%d"
 )
 
 type NameCom struct {
@@ -24,6 +26,7 @@ type NameCom struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 type NameComRecord struct {
@@ -53,11 +56,31 @@ type NameComRecordListResp struct {
 	NextPage   int                 `json:"nextPage"`
 }
 
-func (n *NameCom) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "name_com",
+		Order: 25,
+		Name: map[string]string{
+			"en":    "name.com",
+			"zh-cn": "name.com",
+		},
+		IDLabel:     "username",
+		SecretLabel: "token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.name.com/zh-cn/account/settings/api'>name.com Create API Token</a>",
+			"zh-cn": "<a target='_blank' href='https://www.name.com/zh-cn/account/settings/api'>name.com 创建 API Token</a>",
+		},
+		DefaultTTL: 300,
+		New:        func() DNS { return &NameCom{} },
+	})
+}
+
+func (n *NameCom) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	n.ctx = ctx
 	n.Domains.Ipv4Cache = ipv4cache
 	n.Domains.Ipv6Cache = ipv6cache
 	n.DNS = dnsConf.DNS
-	n.Domains.GetNewIp(dnsConf)
+	n.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		n.TTL = "300"
 	} else {
@@ -67,61 +90,31 @@ func (n *NameCom) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6c
 }
 
 func (n *NameCom) AddUpdateDomainRecords() (domains config.Domains) {
-	n.addUpdateDomainRecords("A")
-	n.addUpdateDomainRecords("AAAA")
+	Reconcile(n.ctx, n, n.Domains.GetAllNewIpResult("A/AAAA"))
 	domains = n.Domains
 	return
 }
 
-func (n *NameCom) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := n.Domains.GetNewIpResult(recordType)
-	if ipAddr == "" {
+// ListRecords 查询记录
+func (n *NameCom) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	var resp *NameComRecordListResp
+	err = n.request("GET", fmt.Sprintf(listRecords, domain.DomainName), nil, &resp)
+	if err != nil || resp == nil {
 		return
 	}
-
-	for _, domain := range domains {
-		resp, err := n.getRecordList(domain)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-		resp4TypeRecords := make([]NameComRecordResp, 0, resp.TotalCount)
-		if resp.TotalCount > 0 {
-			for _, r := range resp.Records {
-				if r.Type == recordType && r.Host == domain.SubDomain {
-					resp4TypeRecords = append(resp4TypeRecords, r)
-				}
-			}
-		}
-		if len(resp4TypeRecords) > 0 {
-			for _, r := range resp4TypeRecords {
-				err := n.update(r, domain, ipAddr, recordType)
-				if err != nil {
-					domain.UpdateStatus = config.UpdatedFailed
-					return
-				}
-			}
-		} else {
-			_, err := n.create(domain, recordType, ipAddr)
-			if err != nil {
-				domain.UpdateStatus = config.UpdatedFailed
-				return
-			}
+	for _, r := range resp.Records {
+		if r.Type == recordType && r.Host == domain.SubDomain {
+			records = append(records, Record{ID: strconv.Itoa(r.Id), Type: r.Type, Value: r.Answer, Raw: r})
 		}
 	}
-}
-
-func (n *NameCom) getRecordList(domain *config.Domain) (resp *NameComRecordListResp, err error) {
-	url := fmt.Sprintf(listRecords, domain.DomainName)
-	err = n.request("GET", url, nil, &resp)
 	return
 }
 
-func (n *NameCom) create(domain *config.Domain, recordType string, ipAddr string) (resp *NameComRecord, err error) {
+// CreateRecord 创建记录
+func (n *NameCom) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	i, err := strconv.Atoi(n.TTL)
 	if err != nil {
-		return
+		return err
 	}
 
 	resq := &NameComRecord{
@@ -130,31 +123,20 @@ func (n *NameCom) create(domain *config.Domain, recordType string, ipAddr string
 		Host:   domain.SubDomain,
 		Type:   recordType,
 	}
-	url := fmt.Sprintf(createRecord, domain.DomainName)
-	err = n.request("POST", url, resq, resp)
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		return
-	}
-	util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-	return
+	return n.request("POST", fmt.Sprintf(createRecord, domain.DomainName), resq, nil)
 }
 
-func (n *NameCom) update(record NameComRecordResp, domain *config.Domain, ipAddr, recordType string) (err error) {
-	if record.Answer == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// UpdateRecord 更新记录
+func (n *NameCom) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	record := r.Raw.(NameComRecordResp)
 	record.Answer = ipAddr
-	record.Type = recordType
-	url := fmt.Sprintf(updateRecord, domain.DomainName, record.Id)
-	err = n.request("PUT", url, record, nil)
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		return
-	}
-	util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-	return
+	return n.request("PUT", fmt.Sprintf(updateRecord, domain.DomainName, record.Id), record, nil)
+}
+
+// DeleteRecord 删除记录
+func (n *NameCom) DeleteRecord(domain *config.Domain, r Record) error {
+	record := r.Raw.(NameComRecordResp)
+	return n.request("DELETE", fmt.Sprintf(deleteRecord, domain.DomainName, record.Id), nil, nil)
 }
 
 func (n *NameCom) request(action string, url string, data any, result any) (err error) {
@@ -165,7 +147,8 @@ func (n *NameCom) request(action string, url string, data any, result any) (err
 			return
 		}
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		n.ctx,
 		action,
 		url,
 		bytes.NewBuffer(jsonStr),
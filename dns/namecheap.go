@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"strings"
@@ -20,6 +21,7 @@ type NameCheap struct {
 	lastIpv4   string
 	lastIpv6   string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // NameCheap 修改域名解析结果
@@ -28,15 +30,36 @@ type NameCheapResp struct {
 	Errors []string
 }
 
+func init() {
+	Register(Provider{
+		Key:   "namecheap",
+		Order: 11,
+		Name: map[string]string{
+			"en": "Namecheap",
+		},
+		IDLabel:     "",
+		SecretLabel: "Password",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.namecheap.com/support/knowledgebase/article.aspx/36/11/how-do-i-start-using-dynamic-dns/'>How to get started</a> <span style='color: red'>Namecheap DDNS does not support updating IPv6</span>",
+			"zh-cn": "<a target='_blank' href='https://www.namecheap.com/support/knowledgebase/article.aspx/36/11/how-do-i-start-using-dynamic-dns/'>开启namecheap动态域名解析</a> <span style='color: red'>Namecheap DDNS 不支持更新 IPv6</span>",
+		},
+		RecordTypes: []string{"A"},
+		DefaultTTL:  600,
+		Endpoints:   []string{nameCheapEndpoint},
+		New:         func() DNS { return &NameCheap{} },
+	})
+}
+
 // Init 初始化
-func (nc *NameCheap) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (nc *NameCheap) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	nc.ctx = ctx
 	nc.Domains.Ipv4Cache = ipv4cache
 	nc.Domains.Ipv6Cache = ipv6cache
 	nc.lastIpv4 = ipv4cache.Addr
 	nc.lastIpv6 = ipv6cache.Addr
 
 	nc.DNS = dnsConf.DNS
-	nc.Domains.GetNewIp(dnsConf)
+	nc.Domains.GetNewIp(ctx, dnsConf)
 	nc.httpClient = dnsConf.GetHTTPClient()
 }
 
@@ -67,12 +90,17 @@ func (nc *NameCheap) addUpdateDomainRecords(recordType string) {
 	}
 
 	for _, domain := range domains {
-		nc.modify(domain, ipAddr)
+		// 设置了 NeighborMac 的域名使用局域网主机的地址
+		nc.modify(domain, recordType, domain.HostAddr(ipAddr))
 	}
 }
 
 // 修改
-func (nc *NameCheap) modify(domain *config.Domain, ipAddr string) {
+func (nc *NameCheap) modify(domain *config.Domain, recordType, ipAddr string) {
+	if planRecord(nc.ctx, domain, recordType, PlanUnknown, "", ipAddr) {
+		return
+	}
+
 	var result NameCheapResp
 	err := nc.request(&result, ipAddr, domain)
 
@@ -101,7 +129,8 @@ func (nc *NameCheap) request(result *NameCheapResp, ipAddr string, domain *confi
 		"#{ip}", ipAddr,
 	).Replace(nameCheapEndpoint)
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		nc.ctx,
 		http.MethodGet,
 		url,
 		http.NoBody,
@@ -1,7 +1,9 @@
 package dns
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -14,6 +16,7 @@ const (
 	nameSiloListRecordEndpoint   = "https://www.namesilo.com/api/dnsListRecords?version=1&type=xml&key=#{password}&domain=#{domain}"
 	nameSiloAddRecordEndpoint    = "https://www.namesilo.com/api/dnsAddRecord?version=1&type=xml&key=#{password}&domain=#{domain}&rrhost=#{host}&rrtype=#{recordType}&rrvalue=#{ip}&rrttl=3600"
 	nameSiloUpdateRecordEndpoint = "https://www.namesilo.com/api/dnsUpdateRecord?version=1&type=xml&key=#{password}&domain=#{domain}&rrhost=#{host}&rrid=#{recordID}&rrvalue=#{ip}&rrttl=3600"
+	nameSiloDeleteRecordEndpoint = "https://www.namesilo.com/api/dnsDeleteRecord?version=1&type=xml&key=#{password}&domain=#{domain}&rrid=#{recordID}"
 )
 
 // NameSilo Domain
@@ -23,6 +26,7 @@ type NameSilo struct {
 	lastIpv4   string
 	lastIpv6   string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // NameSiloResp 修改域名解析结果
@@ -64,88 +68,90 @@ type ResourceRecord struct {
 	Distance int    `xml:"distance"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "namesilo",
+		Order: 12,
+		Name: map[string]string{
+			"en": "NameSilo",
+		},
+		IDLabel:     "",
+		SecretLabel: "Password",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.namesilo.com/account/api-manager'>How to get started</a> <b>Please note that the TTL of namesilo is at least 1 hour</b>",
+			"zh-cn": "<a target='_blank' href='https://www.namesilo.com/account/api-manager'>开启namesilo动态域名解析</a> <b>请注意namesilo的TTL最低1小时</b>",
+		},
+		MinTTL:         3600,
+		DefaultTTL:     3600,
+		MaxConcurrency: 1,
+		Endpoints:      []string{nameSiloListRecordEndpoint},
+		New:            func() DNS { return &NameSilo{} },
+	})
+}
+
 // Init 初始化
-func (ns *NameSilo) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (ns *NameSilo) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	ns.ctx = ctx
 	ns.Domains.Ipv4Cache = ipv4cache
 	ns.Domains.Ipv6Cache = ipv6cache
 	ns.lastIpv4 = ipv4cache.Addr
 	ns.lastIpv6 = ipv6cache.Addr
 
 	ns.DNS = dnsConf.DNS
-	ns.Domains.GetNewIp(dnsConf)
+	ns.Domains.GetNewIp(ctx, dnsConf)
 	ns.httpClient = dnsConf.GetHTTPClient()
 }
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (ns *NameSilo) AddUpdateDomainRecords() config.Domains {
-	ns.addUpdateDomainRecords("A")
-	ns.addUpdateDomainRecords("AAAA")
+	Reconcile(ns.ctx, ns, ns.Domains.GetAllNewIpResult("A/AAAA"))
 	return ns.Domains
 }
 
-func (ns *NameSilo) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := ns.Domains.GetNewIpResult(recordType)
+// ListRecords 拿到DNS记录列表，从列表中去取对应域名的记录
+func (ns *NameSilo) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	if domain.SubDomain == "" {
+		domain.SubDomain = "@"
+	}
 
-	if ipAddr == "" {
+	resp, err := ns.listRecords(domain)
+	if err != nil {
 		return
 	}
+	for _, r := range resp.Reply.ResourceItems {
+		if r.Host == domain.SubDomain && r.Type == recordType {
+			records = append(records, Record{ID: r.RecordID, Type: r.Type, Value: r.Value})
+		}
+	}
+	return
+}
 
-	for _, domain := range domains {
+// CreateRecord 新增
+func (ns *NameSilo) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	return ns.write(ns.request(ipAddr, domain, "", recordType, nameSiloAddRecordEndpoint))
+}
 
-		if domain.SubDomain == "" {
-			domain.SubDomain = "@"
-		}
+// UpdateRecord 修改
+func (ns *NameSilo) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
+	return ns.write(ns.request(ipAddr, domain, record.ID, "", nameSiloUpdateRecordEndpoint))
+}
 
-		// 拿到DNS记录列表，从列表中去取对应域名的id，有id进行修改，没ID进行新增
-		records, err := ns.listRecords(domain)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-		items := records.Reply.ResourceItems
-		record := findResourceRecord(items, recordType, domain.SubDomain)
-		var isAdd bool
-		var recordID string
-		if record == nil {
-			isAdd = true
-		} else {
-			recordID = record.RecordID
-			if record.Value == ipAddr {
-				util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-				continue
-			}
-		}
-		ns.modify(domain, recordID, recordType, ipAddr, isAdd)
-	}
+// DeleteRecord 删除
+func (ns *NameSilo) DeleteRecord(domain *config.Domain, record Record) error {
+	return ns.write(ns.request("", domain, record.ID, "", nameSiloDeleteRecordEndpoint))
 }
 
-// 修改
-func (ns *NameSilo) modify(domain *config.Domain, recordID, recordType, ipAddr string, isAdd bool) {
-	var err error
-	var result string
-	var requestType string
-	if isAdd {
-		requestType = "新增"
-		result, err = ns.request(ipAddr, domain, "", recordType, nameSiloAddRecordEndpoint)
-	} else {
-		requestType = "更新"
-		result, err = ns.request(ipAddr, domain, recordID, "", nameSiloUpdateRecordEndpoint)
-	}
+// write 解析新增/修改/删除的结果, code 不为 300 时返回错误
+func (ns *NameSilo) write(result string, err error) error {
 	if err != nil {
-		util.Log("异常信息: %s", err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
 	var resp NameSiloResp
 	xml.Unmarshal([]byte(result), &resp)
-	if resp.Reply.Code == 300 {
-		util.Log(requestType+"域名解析 %s 成功! IP: %s\n", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log(requestType+"域名解析 %s 失败! 异常信息: %s", domain, resp.Reply.Detail)
-		domain.UpdateStatus = config.UpdatedFailed
+	if resp.Reply.Code != 300 {
+		return errors.New(resp.Reply.Detail)
 	}
+	return nil
 }
 
 func (ns *NameSilo) listRecords(domain *config.Domain) (*NameSiloDNSListRecordResp, error) {
@@ -172,7 +178,8 @@ func (ns *NameSilo) request(ipAddr string, domain *config.Domain, recordID, reco
 		"#{recordType}", recordType,
 		"#{ip}", ipAddr,
 	).Replace(url)
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ns.ctx,
 		http.MethodGet,
 		url,
 		http.NoBody,
@@ -193,12 +200,3 @@ func (ns *NameSilo) request(ipAddr string, domain *config.Domain, recordID, reco
 	result = string(data)
 	return
 }
-
-func findResourceRecord(data []ResourceRecord, recordType, domain string) *ResourceRecord {
-	for i := 0; i < len(data); i++ {
-		if data[i].Host == domain && data[i].Type == recordType {
-			return &data[i]
-		}
-	}
-	return nil
-}
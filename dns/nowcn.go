@@ -1,10 +1,12 @@
 package dns
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,6 +26,7 @@ type Nowcn struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // NowcnRecord DNS记录结构
@@ -51,12 +54,32 @@ type NowcnBaseResult struct {
 	Error     string `json:"error"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "nowcn",
+		Order: 19,
+		Name: map[string]string{
+			"en":    "Nowcn",
+			"zh-cn": "时代互联",
+		},
+		IDLabel:     "auth-userid",
+		SecretLabel: "api-key",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.now.cn/'>api-key</a>",
+			"zh-cn": "<a target='_blank' href='https://www.now.cn/'>获取 api-key</a>",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &Nowcn{} },
+	})
+}
+
 // Init 初始化
-func (nowcn *Nowcn) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (nowcn *Nowcn) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	nowcn.ctx = ctx
 	nowcn.Domains.Ipv4Cache = ipv4cache
 	nowcn.Domains.Ipv6Cache = ipv6cache
 	nowcn.DNS = dnsConf.DNS
-	nowcn.Domains.GetNewIp(dnsConf)
+	nowcn.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		nowcn.TTL = "600"
@@ -68,48 +91,24 @@ func (nowcn *Nowcn) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (nowcn *Nowcn) AddUpdateDomainRecords() config.Domains {
-	nowcn.addUpdateDomainRecords("A")
-	nowcn.addUpdateDomainRecords("AAAA")
+	Reconcile(nowcn.ctx, nowcn, nowcn.Domains.GetAllNewIpResult("A/AAAA"))
 	return nowcn.Domains
 }
 
-func (nowcn *Nowcn) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := nowcn.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询DNS记录
+func (nowcn *Nowcn) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	result, err := nowcn.getRecordList(domain, recordType)
+	if err != nil {
 		return
 	}
-
-	for _, domain := range domains {
-		result, err := nowcn.getRecordList(domain, recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		if len(result.Data) > 0 {
-			// 默认第一个
-			recordSelected := result.Data[0]
-			params := domain.GetCustomParams()
-			if params.Has("Id") {
-				for i := 0; i < len(result.Data); i++ {
-					if strconv.Itoa(result.Data[i].ID) == params.Get("Id") {
-						recordSelected = result.Data[i]
-					}
-				}
-			}
-			// 更新
-			nowcn.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// 新增
-			nowcn.create(domain, recordType, ipAddr)
-		}
+	for _, r := range result.Data {
+		records = append(records, Record{ID: strconv.Itoa(r.ID), Type: recordType, Value: r.Value})
 	}
+	return
 }
 
-// create 创建DNS记录
-func (nowcn *Nowcn) create(domain *config.Domain, recordType string, ipAddr string) {
+// CreateRecord 创建DNS记录
+func (nowcn *Nowcn) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	param := map[string]string{
 		"Domain": domain.DomainName,
 		"Host":   domain.GetSubDomain(),
@@ -117,58 +116,46 @@ func (nowcn *Nowcn) create(domain *config.Domain, recordType string, ipAddr stri
 		"Value":  ipAddr,
 		"Ttl":    nowcn.TTL,
 	}
-	res, err := nowcn.request("/api/Dns/AddDomainRecord", param, "GET")
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
-	}
-	var result NowcnBaseResult
-	err = json.Unmarshal(res, &result)
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
-	}
-	if result.Error != "" {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, result.Error)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		domain.UpdateStatus = config.UpdatedSuccess
-	}
+	return nowcn.write("/api/Dns/AddDomainRecord", param)
 }
 
-// modify 修改DNS记录
-func (nowcn *Nowcn) modify(record NowcnRecord, domain *config.Domain, recordType string, ipAddr string) {
-	// 相同不修改
-	if record.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// UpdateRecord 修改DNS记录
+func (nowcn *Nowcn) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
 	param := map[string]string{
-		"Id":     strconv.Itoa(record.ID),
+		"Id":     record.ID,
 		"Domain": domain.DomainName,
 		"Host":   domain.GetSubDomain(),
-		"Type":   recordType,
+		"Type":   record.Type,
 		"Value":  ipAddr,
 		"Ttl":    nowcn.TTL,
 	}
-	res, err := nowcn.request("/api/Dns/UpdateDomainRecord", param, "GET")
+	return nowcn.write("/api/Dns/UpdateDomainRecord", param)
+}
+
+// DeleteRecord 删除DNS记录
+func (nowcn *Nowcn) DeleteRecord(domain *config.Domain, record Record) error {
+	param := map[string]string{
+		"Id":     record.ID,
+		"Domain": domain.DomainName,
+	}
+	return nowcn.write("/api/Dns/DeleteDomainRecord", param)
+}
+
+// write 新增/修改/删除, 返回 error 时视为失败
+func (nowcn *Nowcn) write(apiPath string, param map[string]string) error {
+	res, err := nowcn.request(apiPath, param, "GET")
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
+		return err
 	}
 	var result NowcnBaseResult
 	err = json.Unmarshal(res, &result)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err.Error())
-		domain.UpdateStatus = config.UpdatedFailed
+		return err
 	}
 	if result.Error != "" {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, result.Error)
-		domain.UpdateStatus = config.UpdatedFailed
-	} else {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
+		return errors.New(result.Error)
 	}
+	return nil
 }
 
 // getRecordList 获取域名记录列表
@@ -179,6 +166,9 @@ func (nowcn *Nowcn) getRecordList(domain *config.Domain, typ string) (result Now
 		"Host":   domain.GetSubDomain(),
 	}
 	res, err := nowcn.request("/api/Dns/DescribeRecordIndex", param, "GET")
+	if err != nil {
+		return
+	}
 	err = json.Unmarshal(res, &result)
 	return
 }
@@ -246,7 +236,7 @@ func (t *Nowcn) request(apiPath string, params map[string]string, method string)
 	fullURL := baseURL + apiPath + "?" + queryString
 
 	// 创建HTTP请求
-	req, err := http.NewRequest(method, fullURL, nil)
+	req, err := http.NewRequestWithContext(t.ctx, method, fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
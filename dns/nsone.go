@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -19,6 +21,7 @@ type NSOne struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 type NSOneZone struct {
@@ -174,11 +177,31 @@ type NSOneRecordRequest struct {
 	Zone    string              `json:"zone"`
 }
 
-func (nsone *NSOne) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "nsone",
+		Order: 24,
+		Name: map[string]string{
+			"en":    "IBM NS1 Connect",
+			"zh-cn": "IBM NS1 Connect",
+		},
+		IDLabel:     "",
+		SecretLabel: "API Key",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://my.nsone.net/#/account/settings/keys'>Create API Key</a>",
+			"zh-cn": "<a target='_blank' href='https://my.nsone.net/#/account/settings/keys'>创建 API 密钥</a>",
+		},
+		DefaultTTL: 60,
+		New:        func() DNS { return &NSOne{} },
+	})
+}
+
+func (nsone *NSOne) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	nsone.ctx = ctx
 	nsone.Domains.Ipv4Cache = ipv4cache
 	nsone.Domains.Ipv6Cache = ipv6cache
 	nsone.DNS = dnsConf.DNS
-	nsone.Domains.GetNewIp(dnsConf)
+	nsone.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		nsone.TTL = 60
 	} else {
@@ -194,45 +217,33 @@ func (nsone *NSOne) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 }
 
 func (nsone *NSOne) AddUpdateDomainRecords() config.Domains {
-	nsone.addUpdateDomainRecords("A")
-	nsone.addUpdateDomainRecords("AAAA")
+	Reconcile(nsone.ctx, nsone, nsone.Domains.GetAllNewIpResult("A/AAAA"))
 	return nsone.Domains
 }
 
-func (nsone *NSOne) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := nsone.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
-		return
+// ListRecords 查询记录, NS1 中同名同类型只有一条记录
+func (nsone *NSOne) ListRecords(domain *config.Domain, recordType string) ([]Record, error) {
+	zoneInfo, err := nsone.getZone(domain)
+	if err != nil {
+		return nil, err
+	}
+	if zoneInfo == nil {
+		return nil, errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
 	}
 
-	for _, domain := range domains {
-		zoneInfo, err := nsone.getZone(domain)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		if zoneInfo == nil {
-			util.Log("在DNS服务商中未找到根域名: %s", domain.DomainName)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		existingRecord, err := nsone.getRecord(domain, recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
+	existingRecord, err := nsone.getRecord(domain, recordType)
+	if err != nil || existingRecord == nil {
+		return nil, err
+	}
 
-		if existingRecord != nil {
-			nsone.updateRecord(domain, recordType, ipAddr, existingRecord)
-		} else {
-			nsone.createRecord(domain, recordType, ipAddr)
+	// 每个 answer 返回一条记录, 修改时整体替换
+	var records []Record
+	for _, answer := range existingRecord.Answers {
+		if len(answer.Answer) > 0 {
+			records = append(records, Record{ID: existingRecord.ID, Type: recordType, Value: answer.Answer[0]})
 		}
 	}
+	return records, nil
 }
 
 func (nsone *NSOne) getZone(domain *config.Domain) (*NSOneZone, error) {
@@ -273,79 +284,60 @@ func (nsone *NSOne) getRecord(domain *config.Domain, recordType string) (*NSOneR
 	return nil, nil
 }
 
-func (nsone *NSOne) createRecord(domain *config.Domain, recordType string, ipAddr string) {
-	recordName := domain.GetFullDomain()
-	request := NSOneRecordRequest{
-		Answers: []NSOneRecordAnswer{
-			{
-				Answer: []string{
-					ipAddr,
-				},
-			},
-		},
-		Domain: recordName,
-		TTL:    nsone.TTL,
-		Type:   recordType,
-		Zone:   domain.DomainName,
-	}
-
-	var response NSOneRecordResponse
-	err := nsone.request(
-		"PUT",
-		fmt.Sprintf("%s/%s/%s/%s", nsoneAPIEndpoint, domain.DomainName, recordName, recordType),
-		request,
-		&response,
-	)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
+// CreateRecord 创建记录
+func (nsone *NSOne) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	return nsone.write("PUT", domain, recordType, []string{ipAddr})
+}
 
-	util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-	domain.UpdateStatus = config.UpdatedSuccess
+// UpdateRecord 更新记录
+func (nsone *NSOne) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
+	return nsone.write("POST", domain, record.Type, []string{ipAddr})
 }
 
-func (nsone *NSOne) updateRecord(domain *config.Domain, recordType string, ipAddr string, existingRecord *NSOneRecordResponse) {
-	if len(existingRecord.Answers) > 0 && len(existingRecord.Answers[0].Answer) > 0 {
-		if existingRecord.Answers[0].Answer[0] == ipAddr {
-			util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-			return
+// SetRecords 将记录的 answers 替换为 values, 不存在时创建
+func (nsone *NSOne) SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error {
+	if len(records) == 0 {
+		if len(values) == 0 {
+			return nil
 		}
+		return nsone.write("PUT", domain, recordType, values)
 	}
+	if len(values) == 0 {
+		return nsone.DeleteRecord(domain, records[0])
+	}
+	return nsone.write("POST", domain, recordType, values)
+}
 
+// DeleteRecord 删除记录
+func (nsone *NSOne) DeleteRecord(domain *config.Domain, record Record) error {
+	return nsone.request(
+		"DELETE",
+		fmt.Sprintf("%s/%s/%s/%s", nsoneAPIEndpoint, domain.DomainName, domain.GetFullDomain(), record.Type),
+		nil,
+		nil,
+	)
+}
+
+// write PUT 创建, POST 更新
+func (nsone *NSOne) write(method string, domain *config.Domain, recordType string, values []string) error {
 	recordName := domain.GetFullDomain()
 	request := NSOneRecordRequest{
-		Answers: []NSOneRecordAnswer{
-			{
-				Answer: []string{
-					ipAddr,
-				},
-			},
-		},
 		Domain: recordName,
 		TTL:    nsone.TTL,
 		Type:   recordType,
 		Zone:   domain.DomainName,
 	}
+	for _, value := range values {
+		request.Answers = append(request.Answers, NSOneRecordAnswer{Answer: []string{value}})
+	}
 
 	var response NSOneRecordResponse
-	err := nsone.request(
-		"POST",
+	return nsone.request(
+		method,
 		fmt.Sprintf("%s/%s/%s/%s", nsoneAPIEndpoint, domain.DomainName, recordName, recordType),
 		request,
 		&response,
 	)
-
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-	domain.UpdateStatus = config.UpdatedSuccess
 }
 
 func (nsone *NSOne) request(method string, url string, data interface{}, result interface{}) (err error) {
@@ -354,7 +346,8 @@ func (nsone *NSOne) request(method string, url string, data interface{}, result
 		jsonStr, _ = json.Marshal(data)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		nsone.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
@@ -0,0 +1,70 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// authPause 认证失败后暂停更新的时间, 修改配置后立即恢复
+const authPause = time.Hour
+
+var (
+	// pausedUntil 因认证失败暂停的配置, 以配置的摘要为键
+	pausedUntil   = map[string]time.Time{}
+	pausedUntilMu sync.Mutex
+)
+
+// PausedUntil 配置因认证失败暂停更新的截止时间, 未暂停时返回零值
+func PausedUntil(dc *config.DnsConfig) time.Time {
+	pausedUntilMu.Lock()
+	defer pausedUntilMu.Unlock()
+
+	key := configKey(dc)
+	until := pausedUntil[key]
+	if !until.IsZero() && !time.Now().Before(until) {
+		delete(pausedUntil, key)
+		return time.Time{}
+	}
+	return until
+}
+
+// pauseOnAuthFailure 有域名认证失败时暂停配置, 避免使用无效的凭证反复请求服务商
+func pauseOnAuthFailure(dc *config.DnsConfig, domains *config.Domains, now time.Time) (until time.Time) {
+	if !hasErrorKind(domains.Ipv4Domains, util.ErrorAuth) && !hasErrorKind(domains.Ipv6Domains, util.ErrorAuth) {
+		return
+	}
+
+	pausedUntilMu.Lock()
+	defer pausedUntilMu.Unlock()
+	until = now.Add(authPause)
+	pausedUntil[configKey(dc)] = until
+	return
+}
+
+func hasErrorKind(domains []*config.Domain, kind util.ErrorKind) bool {
+	for _, d := range domains {
+		if d.UpdateStatus == config.UpdatedFailed && d.ErrorKind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// permanentFailure 失败的域名都是重试也不会成功的错误, 如域名不存在
+// 此时保留IP缓存, 地址变化或达到比对次数前不再请求服务商; 认证失败由 pauseOnAuthFailure 处理
+func permanentFailure(domains []*config.Domain) bool {
+	failed := false
+	for _, d := range domains {
+		if d.UpdateStatus != config.UpdatedFailed {
+			continue
+		}
+		if !d.ErrorKind.Permanent() || d.ErrorKind == util.ErrorAuth {
+			return false
+		}
+		failed = true
+	}
+	return failed
+}
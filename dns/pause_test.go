@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestPauseOnAuthFailure(t *testing.T) {
+	t.Cleanup(func() { pausedUntil = map[string]time.Time{} })
+
+	dc := &config.DnsConfig{Name: "test"}
+	domains := &config.Domains{Ipv4Domains: []*config.Domain{
+		{DomainName: "example.com", UpdateStatus: config.UpdatedFailed, ErrorKind: util.ErrorNotFound},
+	}}
+	now := time.Now()
+	if until := pauseOnAuthFailure(dc, domains, now); !until.IsZero() {
+		t.Fatalf("paused until %s without auth failure", until)
+	}
+
+	domains.Ipv6Domains = []*config.Domain{{DomainName: "example.com", UpdateStatus: config.UpdatedFailed, ErrorKind: util.ErrorAuth}}
+	until := pauseOnAuthFailure(dc, domains, now)
+	if !until.Equal(now.Add(authPause)) || !PausedUntil(dc).Equal(until) {
+		t.Errorf("PausedUntil = %s, want %s", PausedUntil(dc), until)
+	}
+
+	// 修改配置后恢复
+	changed := &config.DnsConfig{Name: "test", Schedule: "1h"}
+	if !PausedUntil(changed).IsZero() {
+		t.Error("changed config should not be paused")
+	}
+
+	// 到期后恢复
+	pauseOnAuthFailure(dc, domains, now.Add(-authPause))
+	if !PausedUntil(dc).IsZero() {
+		t.Error("pause should expire")
+	}
+}
+
+func TestPermanentFailure(t *testing.T) {
+	domain := func(status string, kind util.ErrorKind) *config.Domain {
+		d := &config.Domain{DomainName: "example.com", ErrorKind: kind}
+		d.UpdateStatus = config.UpdatedSuccess
+		if status == "failed" {
+			d.UpdateStatus = config.UpdatedFailed
+		}
+		return d
+	}
+
+	tests := []struct {
+		name    string
+		domains []*config.Domain
+		want    bool
+	}{
+		{"not found", []*config.Domain{domain("failed", util.ErrorNotFound), domain("ok", "")}, true},
+		{"validation", []*config.Domain{domain("failed", util.ErrorValidation)}, true},
+		{"mixed", []*config.Domain{domain("failed", util.ErrorNotFound), domain("failed", util.ErrorTransient)}, false},
+		{"auth", []*config.Domain{domain("failed", util.ErrorAuth)}, false},
+		{"unclassified", []*config.Domain{domain("failed", "")}, false},
+		{"no failure", []*config.Domain{domain("ok", "")}, false},
+	}
+	for _, tt := range tests {
+		if got := permanentFailure(tt.domains); got != tt.want {
+			t.Errorf("%s: permanentFailure() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package dns
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// PlanAction dry-run 中的操作
+type PlanAction string
+
+const (
+	// PlanCreate 新增记录
+	PlanCreate PlanAction = "create"
+	// PlanUpdate 更新记录
+	PlanUpdate PlanAction = "update"
+	// PlanNoop 无需修改
+	PlanNoop PlanAction = "no-op"
+	// PlanDelete 删除记录
+	PlanDelete PlanAction = "delete"
+	// PlanUnknown 服务商无法查询当前值, 不确定是否需要更新, 正常运行时会直接更新
+	PlanUnknown PlanAction = "unknown"
+)
+
+// PlanEntry 单个域名的计划
+type PlanEntry struct {
+	Config     string
+	Domain     string
+	RecordType string
+	Action     PlanAction
+	// Current DNS服务商中的当前值, 为空表示不存在; 无法查询时 (Action 为 PlanUnknown) 为最后一次确认的值
+	Current string
+	Desired string
+}
+
+// Plan dry-run 的结果
+type Plan struct {
+	mu     sync.Mutex
+	config string
+	// key 配置的摘要, 用于查询最后一次确认的记录值
+	key     string
+	Entries []PlanEntry
+}
+
+type planKey struct{}
+
+// withPlan 返回 dry-run 模式的 ctx, 服务商会将写操作记录到 plan 中而不执行
+func withPlan(ctx context.Context, plan *Plan) context.Context {
+	return context.WithValue(ctx, planKey{}, plan)
+}
+
+// isDryRun 是否为 dry-run 模式
+func isDryRun(ctx context.Context) bool {
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	return plan != nil
+}
+
+// planRecord 在 dry-run 模式下记录计划并返回 true, 调用方应跳过写操作
+func planRecord(ctx context.Context, domain *config.Domain, recordType string, action PlanAction, current, desired string) bool {
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	if plan == nil {
+		return false
+	}
+
+	if action == PlanUnknown && current == "" {
+		current = lastRecordValue(plan.key, domain, recordType)
+	}
+
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	plan.Entries = append(plan.Entries, PlanEntry{
+		Config:     plan.config,
+		Domain:     domain.String(),
+		RecordType: recordType,
+		Action:     action,
+		Current:    current,
+		Desired:    desired,
+	})
+	return true
+}
+
+// DryRun 获取IP并查询DNS服务商, 只返回每个域名的计划, 不会新增或更新任何记录
+func DryRun(ctx context.Context) ([]PlanEntry, error) {
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		return nil, err
+	}
+
+	runMu.Lock()
+	caches := ipcacheByKey()
+	runMu.Unlock()
+
+	var entries []PlanEntry
+	for i := range conf.DnsConf {
+		dc := &conf.DnsConf[i]
+		p, ok := GetProvider(dc.DNS.Name)
+		if !ok {
+			util.Log("第 %s 个配置的DNS服务商 %s 不存在, 已跳过", util.Ordinal(i+1, conf.Lang), dc.DNS.Name)
+			continue
+		}
+
+		plan := &Plan{config: dc.Name, key: configKey(dc)}
+		if plan.config == "" {
+			plan.config = util.Ordinal(i+1, conf.Lang)
+		}
+		timeoutCtx, cancel := context.WithTimeout(withPlan(ctx, plan), dc.GetTimeout())
+		// 使用缓存的副本并跳过预检查, 保留地址的稳定状态, 确保每个域名都会被查询, 且不影响正常运行的缓存
+		cache := caches[plan.key]
+		cache[0].Reset()
+		cache[1].Reset()
+		planConf := *dc
+		planConf.Precheck = false
+		dnsSelected := p.New()
+		dnsSelected.Init(timeoutCtx, &planConf, &cache[0], &cache[1])
+		dnsSelected.AddUpdateDomainRecords()
+		cancel()
+
+		for _, e := range plan.Entries {
+			util.Log("[dry-run] %s %s(%s): %s -> %s, %s", e.Config, e.Domain, e.RecordType, e.Current, e.Desired, string(e.Action))
+		}
+		entries = append(entries, plan.Entries...)
+	}
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestReconcileDryRun(t *testing.T) {
+	changed := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+	unchanged := &config.Domain{DomainName: "example.com", SubDomain: "b"}
+	created := &config.Domain{DomainName: "example.com", SubDomain: "c"}
+
+	store := &fakeStore{records: map[string][]Record{
+		"a.example.comA": {{ID: "1", Type: "A", Value: "192.0.2.1"}},
+		"b.example.comA": {{ID: "2", Type: "A", Value: "192.0.2.2"}},
+	}}
+	plan := &Plan{config: "test"}
+	Reconcile(withPlan(context.Background(), plan), store, newTuples(
+		"192.0.2.2", []*config.Domain{changed, unchanged, created}, "", nil,
+	))
+
+	if len(store.writes) != 0 {
+		t.Fatalf("writes = %q, want none in dry-run", store.writes)
+	}
+	want := []PlanEntry{
+		{Config: "test", Domain: changed.String(), RecordType: "A", Action: PlanUpdate, Current: "192.0.2.1", Desired: "192.0.2.2"},
+		{Config: "test", Domain: unchanged.String(), RecordType: "A", Action: PlanNoop, Current: "192.0.2.2", Desired: "192.0.2.2"},
+		{Config: "test", Domain: created.String(), RecordType: "A", Action: PlanCreate, Current: "", Desired: "192.0.2.2"},
+	}
+	if len(plan.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(plan.Entries), len(want), plan.Entries)
+	}
+	for i := range want {
+		if plan.Entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, plan.Entries[i], want[i])
+		}
+	}
+	for _, d := range []*config.Domain{changed, unchanged, created} {
+		if d.UpdateStatus != "" {
+			t.Errorf("%s status = %q, want untouched", d, d.UpdateStatus)
+		}
+	}
+}
+
+// TestDryRunUnknownCurrent 测试无法查询当前值的服务商在 dry-run 中报告当前值未知, 并使用最后一次确认的值
+func TestDryRunUnknownCurrent(t *testing.T) {
+	t.Cleanup(func() { lastRecords = map[string][]RecordState{} })
+
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "www"}
+	lastRecords["test-key"] = []RecordState{{Domain: domain.String(), RecordType: "A", Value: "192.0.2.9"}}
+	plan := &Plan{config: "test", key: "test-key"}
+	cb := &Callback{
+		ctx:        withPlan(context.Background(), plan),
+		ipv4Enable: true,
+		Domains: config.Domains{
+			Ipv4Addr:    "192.0.2.1",
+			Ipv4Cache:   &util.IpCache{},
+			Ipv4Domains: []*config.Domain{domain},
+			Ipv6Cache:   &util.IpCache{},
+		},
+	}
+	cb.AddUpdateDomainRecords()
+
+	want := PlanEntry{Config: "test", Domain: domain.String(), RecordType: "A", Action: PlanUnknown, Current: "192.0.2.9", Desired: "192.0.2.1"}
+	if len(plan.Entries) != 1 || plan.Entries[0] != want {
+		t.Errorf("entries = %+v, want %+v", plan.Entries, want)
+	}
+}
+
+// TestDryRunStable 测试 dry-run 使用缓存中的稳定状态, 未稳定的地址不会出现在计划中, 且不修改正常运行的缓存
+func TestDryRunStable(t *testing.T) {
+	t.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	t.Cleanup(func() {
+		Ipcache = [][2]util.IpCache{}
+		ipcacheKeys = nil
+	})
+
+	dc := config.DnsConfig{Name: "stable", DNS: config.DNS{Name: "callback"}, StableTimes: "3"}
+	dc.Ipv4.Enable = true
+	dc.Ipv4.GetType = "cmd"
+	dc.Ipv4.Cmd = "echo 192.0.2.2"
+	dc.Ipv4.Domains = []string{"www.example.com"}
+	conf := config.Config{DnsConf: []config.DnsConfig{dc}}
+	if err := conf.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remapIpcache(&conf, false)
+	Ipcache[0][0] = util.IpCache{Addr: "192.0.2.1", Times: 5, StableAddr: "192.0.2.1"}
+
+	entries, err := DryRun(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("entries = %+v, want none while the new address is not stable", entries)
+	}
+	if want := (util.IpCache{Addr: "192.0.2.1", Times: 5, StableAddr: "192.0.2.1"}); Ipcache[0][0] != want {
+		t.Errorf("Ipcache = %+v, want unchanged %+v", Ipcache[0][0], want)
+	}
+
+	// 没有缓存时立即更新
+	Ipcache[0][0] = util.IpCache{}
+	entries, err = DryRun(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Desired != "192.0.2.2" {
+		t.Errorf("entries = %+v, want one entry for 192.0.2.2", entries)
+	}
+}
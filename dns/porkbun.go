@@ -2,7 +2,9 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -19,8 +21,10 @@ type Porkbun struct {
 	Domains    config.Domains
 	TTL        string
 	httpClient *http.Client
+	ctx        context.Context
 }
 type PorkbunDomainRecord struct {
+	ID      *string `json:"id,omitempty"`
 	Name    *string `json:"name"`    // subdomain
 	Type    *string `json:"type"`    // record type, e.g. A AAAA CNAME
 	Content *string `json:"content"` // value
@@ -46,12 +50,32 @@ type PorkbunDomainCreateOrUpdateVO struct {
 	*PorkbunDomainRecord
 }
 
+func init() {
+	Register(Provider{
+		Key:   "porkbun",
+		Order: 9,
+		Name: map[string]string{
+			"en": "Porkbun",
+		},
+		IDLabel:     "API Key",
+		SecretLabel: "Secret Key",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://porkbun.com/account/api'>Create Access</a>",
+			"zh-cn": "<a target='_blank' href='https://porkbun.com/account/api'>创建 Access</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{porkbunEndpoint},
+		New:        func() DNS { return &Porkbun{} },
+	})
+}
+
 // Init 初始化
-func (pb *Porkbun) Init(conf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (pb *Porkbun) Init(ctx context.Context, conf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	pb.ctx = ctx
 	pb.Domains.Ipv4Cache = ipv4cache
 	pb.Domains.Ipv6Cache = ipv6cache
 	pb.DNSConfig = conf.DNS
-	pb.Domains.GetNewIp(conf)
+	pb.Domains.GetNewIp(ctx, conf)
 	if conf.TTL == "" {
 		// 默认600s
 		pb.TTL = "600"
@@ -63,124 +87,93 @@ func (pb *Porkbun) Init(conf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cac
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (pb *Porkbun) AddUpdateDomainRecords() config.Domains {
-	pb.addUpdateDomainRecords("A")
-	pb.addUpdateDomainRecords("AAAA")
+	Reconcile(pb.ctx, pb, pb.Domains.GetAllNewIpResult("A/AAAA"))
 	return pb.Domains
 }
 
-func (pb *Porkbun) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := pb.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 获取当前域名信息
+func (pb *Porkbun) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	var result PorkbunDomainQueryResponse
+	err = pb.request(
+		porkbunEndpoint+fmt.Sprintf("/retrieveByNameType/%s/%s/%s", domain.DomainName, recordType, domain.SubDomain),
+		pb.apiKey(),
+		&result,
+	)
+	if err != nil {
 		return
 	}
+	if result.PorkbunResponse == nil || result.Status != "SUCCESS" {
+		return nil, errors.New(util.LogStr("在DNS服务商中未找到根域名: %s", domain.DomainName))
+	}
 
-	for _, domain := range domains {
-		var record PorkbunDomainQueryResponse
-		// 获取当前域名信息
-		err := pb.request(
-			porkbunEndpoint+fmt.Sprintf("/retrieveByNameType/%s/%s/%s", domain.DomainName, recordType, domain.SubDomain),
-			&PorkbunApiKey{
-				AccessKey: pb.DNSConfig.ID,
-				SecretKey: pb.DNSConfig.Secret,
-			},
-			&record,
-		)
-
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
+	for _, r := range result.Records {
+		record := Record{Type: recordType}
+		if r.ID != nil {
+			record.ID = *r.ID
 		}
-		if record.Status == "SUCCESS" {
-			if len(record.Records) > 0 {
-				// 存在，更新
-				pb.modify(&record, domain, recordType, ipAddr)
-			} else {
-				// 不存在，创建
-				pb.create(domain, recordType, ipAddr)
-			}
-		} else {
-			util.Log("在DNS服务商中未找到根域名: %s", domain.DomainName)
-			domain.UpdateStatus = config.UpdatedFailed
+		if r.Content != nil {
+			record.Value = *r.Content
 		}
+		records = append(records, record)
 	}
+	return
 }
 
-// 创建
-func (pb *Porkbun) create(domain *config.Domain, recordType string, ipAddr string) {
-	var response PorkbunResponse
-
-	err := pb.request(
+// CreateRecord 创建
+func (pb *Porkbun) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	return pb.write(
 		porkbunEndpoint+fmt.Sprintf("/create/%s", domain.DomainName),
-		&PorkbunDomainCreateOrUpdateVO{
-			PorkbunApiKey: &PorkbunApiKey{
-				AccessKey: pb.DNSConfig.ID,
-				SecretKey: pb.DNSConfig.Secret,
-			},
-			PorkbunDomainRecord: &PorkbunDomainRecord{
-				Name:    &domain.SubDomain,
-				Type:    &recordType,
-				Content: &ipAddr,
-				Ttl:     &pb.TTL,
-			},
+		&PorkbunDomainRecord{
+			Name:    &domain.SubDomain,
+			Type:    &recordType,
+			Content: &ipAddr,
+			Ttl:     &pb.TTL,
 		},
-		&response,
 	)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if response.Status == "SUCCESS" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, response.Status)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
 }
 
-// 修改
-func (pb *Porkbun) modify(record *PorkbunDomainQueryResponse, domain *config.Domain, recordType string, ipAddr string) {
+// UpdateRecord 修改
+func (pb *Porkbun) UpdateRecord(domain *config.Domain, record Record, ipAddr string) error {
+	return pb.write(
+		porkbunEndpoint+fmt.Sprintf("/edit/%s/%s", domain.DomainName, record.ID),
+		&PorkbunDomainRecord{
+			Name:    &domain.SubDomain,
+			Type:    &record.Type,
+			Content: &ipAddr,
+			Ttl:     &pb.TTL,
+		},
+	)
+}
 
-	// 相同不修改
-	if len(record.Records) > 0 && *record.Records[0].Content == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
+// DeleteRecord 删除
+func (pb *Porkbun) DeleteRecord(domain *config.Domain, record Record) error {
+	return pb.write(porkbunEndpoint+fmt.Sprintf("/delete/%s/%s", domain.DomainName, record.ID), nil)
+}
 
+// write 新增/修改/删除, 状态不为 SUCCESS 时返回错误
+func (pb *Porkbun) write(url string, record *PorkbunDomainRecord) error {
 	var response PorkbunResponse
-
 	err := pb.request(
-		porkbunEndpoint+fmt.Sprintf("/editByNameType/%s/%s/%s", domain.DomainName, recordType, domain.SubDomain),
+		url,
 		&PorkbunDomainCreateOrUpdateVO{
-			PorkbunApiKey: &PorkbunApiKey{
-				AccessKey: pb.DNSConfig.ID,
-				SecretKey: pb.DNSConfig.Secret,
-			},
-			PorkbunDomainRecord: &PorkbunDomainRecord{
-				Content: &ipAddr,
-				Ttl:     &pb.TTL,
-			},
+			PorkbunApiKey:       pb.apiKey(),
+			PorkbunDomainRecord: record,
 		},
 		&response,
 	)
-
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
+	}
+	if response.Status != "SUCCESS" {
+		return errors.New(response.Status)
 	}
+	return nil
+}
 
-	if response.Status == "SUCCESS" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, response.Status)
-		domain.UpdateStatus = config.UpdatedFailed
+func (pb *Porkbun) apiKey() *PorkbunApiKey {
+	return &PorkbunApiKey{
+		AccessKey: pb.DNSConfig.ID,
+		SecretKey: pb.DNSConfig.Secret,
 	}
 }
 
@@ -190,7 +183,8 @@ func (pb *Porkbun) request(url string, data interface{}, result interface{}) (er
 	if data != nil {
 		jsonStr, _ = json.Marshal(data)
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		pb.ctx,
 		"POST",
 		url,
 		bytes.NewBuffer(jsonStr),
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -24,6 +25,7 @@ type Rainyun struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // RainyunRecord 雨云DNS记录
@@ -44,12 +46,33 @@ type RainyunResp struct {
 	Data    any    `json:"data"`
 }
 
+func init() {
+	Register(Provider{
+		Key:   "rainyun",
+		Order: 26,
+		Name: map[string]string{
+			"en":    "Rainyun",
+			"zh-cn": "雨云",
+		},
+		IDLabel:     "Domain ID",
+		SecretLabel: "API Key",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://app.rainyun.com/apps/domain/manage'>Get Domain ID</a> <a target='_blank' href='https://app.rainyun.com/account/settings/api-key'>Get API Token</a>",
+			"zh-cn": "<a target='_blank' href='https://app.rainyun.com/apps/domain/manage'>获取 Domain ID</a> <a target='_blank' href='https://app.rainyun.com/account/settings/api-key'>获取 API Token</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{rainyunEndpoint},
+		New:        func() DNS { return &Rainyun{} },
+	})
+}
+
 // Init 初始化
-func (rainyun *Rainyun) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func (rainyun *Rainyun) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	rainyun.ctx = ctx
 	rainyun.Domains.Ipv4Cache = ipv4cache
 	rainyun.Domains.Ipv6Cache = ipv6cache
 	rainyun.DNS = dnsConf.DNS
-	rainyun.Domains.GetNewIp(dnsConf)
+	rainyun.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认600s
 		rainyun.TTL = 600
@@ -62,47 +85,56 @@ func (rainyun *Rainyun) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache,
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (rainyun *Rainyun) AddUpdateDomainRecords() (domains config.Domains) {
-	rainyun.addUpdateDomainRecords("A")
-	rainyun.addUpdateDomainRecords("AAAA")
+	Reconcile(rainyun.ctx, rainyun, rainyun.Domains.GetAllNewIpResult("A/AAAA"))
 	return rainyun.Domains
 }
 
-func (rainyun *Rainyun) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := rainyun.Domains.GetNewIpResult(recordType)
-	if ipAddr == "" {
+// ListRecords 查找匹配的记录
+func (rainyun *Rainyun) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	list, err := rainyun.getRecordList(rainyun.DNS.ID)
+	if err != nil {
 		return
 	}
-
-	for _, domain := range domains {
-		// 获取Domain ID
-		domainID := rainyun.DNS.ID
-
-		// 获取记录列表
-		records, err := rainyun.getRecordList(domainID)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			continue
-		}
-
-		// 查找匹配的记录
-		var recordSelected *RainyunRecord
-		for i := range records {
-			if strings.EqualFold(records[i].Host, domain.GetSubDomain()) &&
-				strings.EqualFold(records[i].Type, recordType) {
-				recordSelected = &records[i]
-				break
-			}
+	for _, r := range list {
+		if strings.EqualFold(r.Host, domain.GetSubDomain()) && strings.EqualFold(r.Type, recordType) {
+			records = append(records, Record{ID: strconv.FormatInt(r.RecordID, 10), Type: r.Type, Value: r.Value, Raw: r})
 		}
+	}
+	return
+}
 
-		if recordSelected != nil {
-			// 更新记录
-			rainyun.modify(domainID, recordSelected, domain, ipAddr)
-		} else {
-			// 新增记录
-			rainyun.create(domainID, domain, recordType, ipAddr)
-		}
+// CreateRecord 创建DNS记录
+func (rainyun *Rainyun) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	record := &RainyunRecord{
+		Host:  domain.GetSubDomain(),
+		Type:  recordType,
+		Value: ipAddr,
+		Line:  "DEFAULT",
+		TTL:   rainyun.TTL,
+		Level: 10,
 	}
+	return rainyun.createRecord(rainyun.DNS.ID, record)
+}
+
+// UpdateRecord 修改DNS记录
+func (rainyun *Rainyun) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	record := r.Raw.(RainyunRecord)
+	record.Value = ipAddr
+	record.TTL = rainyun.TTL
+	return rainyun.patchRecord(rainyun.DNS.ID, &record)
+}
+
+// DeleteRecord 删除DNS记录
+func (rainyun *Rainyun) DeleteRecord(domain *config.Domain, r Record) error {
+	query := url.Values{}
+	query.Set("record_id", r.ID)
+	return rainyun.request(
+		http.MethodDelete,
+		fmt.Sprintf("/product/domain/%s/dns", url.PathEscape(rainyun.DNS.ID)),
+		query,
+		nil,
+		nil,
+	)
 }
 
 // getRecordList 获取域名记录列表
@@ -128,28 +160,6 @@ func (rainyun *Rainyun) getRecordList(domainID string) ([]RainyunRecord, error)
 	return result.Records, nil
 }
 
-// create 创建DNS记录
-func (rainyun *Rainyun) create(domainID string, domain *config.Domain, recordType string, ipAddr string) {
-	record := &RainyunRecord{
-		Host:  domain.GetSubDomain(),
-		Type:  recordType,
-		Value: ipAddr,
-		Line:  "DEFAULT",
-		TTL:   rainyun.TTL,
-		Level: 10,
-	}
-
-	err := rainyun.createRecord(domainID, record)
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-	domain.UpdateStatus = config.UpdatedSuccess
-}
-
 // createRecord 发送POST请求创建记录
 func (rainyun *Rainyun) createRecord(domainID string, record *RainyunRecord) error {
 	payload := map[string]any{
@@ -172,27 +182,6 @@ func (rainyun *Rainyun) createRecord(domainID string, record *RainyunRecord) err
 	)
 }
 
-// modify 修改DNS记录
-func (rainyun *Rainyun) modify(domainID string, record *RainyunRecord, domain *config.Domain, ipAddr string) {
-	if record.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
-
-	record.Value = ipAddr
-	record.TTL = rainyun.TTL
-
-	err := rainyun.patchRecord(domainID, record)
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-	domain.UpdateStatus = config.UpdatedSuccess
-}
-
 // patchRecord 发送PATCH请求更新记录
 func (rainyun *Rainyun) patchRecord(domainID string, record *RainyunRecord) error {
 	payload := map[string]any{
@@ -233,7 +222,7 @@ func (rainyun *Rainyun) request(method string, path string, query url.Values, bo
 		reader = bytes.NewReader(body)
 	}
 
-	req, err := http.NewRequest(method, u.String(), reader)
+	req, err := http.NewRequestWithContext(rainyun.ctx, method, u.String(), reader)
 	if err != nil {
 		return err
 	}
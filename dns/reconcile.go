@@ -0,0 +1,359 @@
+package dns
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// Record DNS服务商中的一条记录
+type Record struct {
+	ID    string
+	Type  string
+	Value string
+	// Raw 服务商的原始记录, 供 UpdateRecord/DeleteRecord 使用
+	Raw any
+}
+
+// RecordStore DNS服务商的记录操作, 比对、日志、状态和 dry-run 由 Reconcile 统一处理
+type RecordStore interface {
+	// ListRecords 查询域名指定类型的记录, 不存在时返回空
+	ListRecords(domain *config.Domain, recordType string) ([]Record, error)
+	// CreateRecord 新增记录
+	CreateRecord(domain *config.Domain, recordType string, value string) error
+	// UpdateRecord 将记录修改为 value
+	UpdateRecord(domain *config.Domain, record Record, value string) error
+	// DeleteRecord 删除记录
+	DeleteRecord(domain *config.Domain, record Record) error
+}
+
+// RecordSetStore 可选, 服务商以记录集 (RRSet) 为单位修改记录时实现, 启用多地址时整体替换记录集
+type RecordSetStore interface {
+	// SetRecords 将 records (ListRecords 的结果) 替换为 values, values 为空时删除记录集
+	SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error
+}
+
+// RecordMatcher 可选, 除记录值外还需比对其他属性时实现, 如 Cloudflare 的 proxied
+type RecordMatcher interface {
+	// RecordMatches 返回记录是否已是期望状态
+	RecordMatches(domain *config.Domain, record Record, value string) bool
+}
+
+// recordIDParams 自定义参数中用于指定记录的参数名, 存在多条记录时使用, 否则修改全部记录
+var recordIDParams = []string{"RecordId", "record_id", "Id", "id"}
+
+// desiredRecord 期望的记录
+type desiredRecord struct {
+	domain     *config.Domain
+	recordType string
+	value      string
+	// values 启用多地址时的全部地址, 服务商中的记录需与之完全一致
+	values []string
+}
+
+// Reconcile 比对期望状态 (GetAllNewIpResult 的结果) 与服务商中的记录, 新增或更新不一致的记录
+// 认证失败后其余域名不再请求服务商
+func Reconcile(ctx context.Context, store RecordStore, tuples config.DomainTuples) {
+	authFailed := false
+	for _, d := range desiredRecords(tuples) {
+		if ctx.Err() != nil {
+			return
+		}
+		if authFailed {
+			d.domain.UpdateStatus = config.UpdatedFailed
+			d.domain.ErrorKind = util.ErrorAuth
+			continue
+		}
+		reconcileRecord(ctx, store, d)
+		authFailed = d.domain.ErrorKind == util.ErrorAuth
+	}
+}
+
+// failDomain 标记更新失败并记录错误类型
+func failDomain(domain *config.Domain, err error) {
+	domain.UpdateStatus = config.UpdatedFailed
+	domain.ErrorKind = util.ErrorKindOf(err)
+}
+
+// listRecordsRetry 查询记录, 临时错误时重试
+func listRecordsRetry(ctx context.Context, store RecordStore, domain *config.Domain, recordType string) (records []Record, err error) {
+	err = util.Retry(ctx, true, func() (err error) {
+		records, err = store.ListRecords(domain, recordType)
+		return
+	})
+	return
+}
+
+// filterTuples 仅保留 keep 返回 true 的域名, 用于跳过不由 Reconcile 处理的域名
+func filterTuples(tuples config.DomainTuples, keep func(domain *config.Domain, ipAddr string) bool) config.DomainTuples {
+	for key, tuple := range tuples {
+		var domains []*config.Domain
+		var ipAddrs []string
+		var ipAddrSets [][]string
+		for i, domain := range tuple.Domains {
+			if keep(domain, tuple.IpAddrs[i]) {
+				domains = append(domains, domain)
+				ipAddrs = append(ipAddrs, tuple.IpAddrs[i])
+				ipAddrSets = append(ipAddrSets, tuple.IpAddrSets[i])
+			}
+		}
+		if len(domains) == 0 {
+			delete(tuples, key)
+			continue
+		}
+		tuple.Domains, tuple.IpAddrs, tuple.IpAddrSets = domains, ipAddrs, ipAddrSets
+	}
+	return tuples
+}
+
+// desiredRecords 将域名元组展开为期望的记录, 按域名和记录类型排序
+func desiredRecords(tuples config.DomainTuples) []desiredRecord {
+	var desired []desiredRecord
+	for _, tuple := range tuples {
+		for i, domain := range tuple.Domains {
+			desired = append(desired, desiredRecord{
+				domain:     domain,
+				recordType: recordTypeOf(tuple.IpAddrs[i]),
+				value:      tuple.IpAddrs[i],
+				values:     tuple.IpAddrSets[i],
+			})
+		}
+	}
+	sort.SliceStable(desired, func(i, j int) bool {
+		if desired[i].recordType != desired[j].recordType {
+			return desired[i].recordType < desired[j].recordType
+		}
+		return desired[i].domain.String() < desired[j].domain.String()
+	})
+	return desired
+}
+
+// recordTypeOf 根据IP返回记录类型
+func recordTypeOf(ipAddr string) string {
+	if strings.Contains(ipAddr, ":") {
+		return "AAAA"
+	}
+	return "A"
+}
+
+func reconcileRecord(ctx context.Context, store RecordStore, d desiredRecord) {
+	if d.values != nil {
+		reconcileRecordSet(ctx, store, d)
+		return
+	}
+
+	domain := d.domain
+	records, err := listRecordsRetry(ctx, store, domain, d.recordType)
+	if err != nil {
+		util.Log("查询域名信息发生异常! %s", err)
+		failDomain(domain, err)
+		return
+	}
+
+	if len(records) == 0 {
+		if planRecord(ctx, domain, d.recordType, PlanCreate, "", d.value) {
+			return
+		}
+		err := util.Retry(ctx, false, func() error { return store.CreateRecord(domain, d.recordType, d.value) })
+		if err != nil {
+			util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
+			failDomain(domain, err)
+			return
+		}
+		util.Log("新增域名解析 %s 成功! IP: %s", domain, d.value)
+		saveRecordIDs(ctx, domain, d.recordType)
+		domain.UpdateStatus = config.UpdatedSuccess
+		return
+	}
+
+	// 存在多条记录时全部修改, 避免重复的记录仍指向旧地址
+	var failed error
+	updated, unchanged := false, true
+	selected := selectRecords(domain, records)
+	saveRecordIDs(ctx, domain, d.recordType, selected...)
+	for _, record := range selected {
+		if recordMatches(store, domain, record, d.value) {
+			planRecord(ctx, domain, d.recordType, PlanNoop, record.Value, d.value)
+			continue
+		}
+		unchanged = false
+		if planRecord(ctx, domain, d.recordType, PlanUpdate, record.Value, d.value) {
+			continue
+		}
+		err := util.Retry(ctx, true, func() error { return store.UpdateRecord(domain, record, d.value) })
+		if err != nil {
+			util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
+			failed = err
+			continue
+		}
+		util.Log("更新域名解析 %s 成功! IP: %s", domain, d.value)
+		updated = true
+	}
+
+	switch {
+	case failed != nil:
+		failDomain(domain, failed)
+	case updated:
+		domain.UpdateStatus = config.UpdatedSuccess
+	case unchanged:
+		markVerified(ctx, domain, d.recordType)
+		util.Log("你的IP %s 没有变化, 域名 %s", d.value, domain)
+	}
+}
+
+// selectRecords 默认使用全部记录, 自定义参数指定了记录ID时只使用对应的记录
+func selectRecords(domain *config.Domain, records []Record) []Record {
+	params := domain.GetCustomParams()
+	for _, key := range recordIDParams {
+		if !params.Has(key) {
+			continue
+		}
+		for _, r := range records {
+			if r.ID == params.Get(key) {
+				return []Record{r}
+			}
+		}
+		util.Log("域名 %s 中没有ID为 %s 的记录, 将使用全部记录", domain, params.Get(key))
+		break
+	}
+	return records
+}
+
+func recordMatches(store RecordStore, domain *config.Domain, record Record, value string) bool {
+	if m, ok := store.(RecordMatcher); ok {
+		return m.RecordMatches(domain, record, value)
+	}
+	return strings.EqualFold(record.Value, value)
+}
+
+// reconcileRecordSet 使服务商中的记录与期望的地址集合完全一致
+// 优先将多余的记录修改为缺少的地址, 其余的新增或删除
+func reconcileRecordSet(ctx context.Context, store RecordStore, d desiredRecord) {
+	domain := d.domain
+	records, err := listRecordsRetry(ctx, store, domain, d.recordType)
+	if err != nil {
+		util.Log("查询域名信息发生异常! %s", err)
+		failDomain(domain, err)
+		return
+	}
+
+	var missing []string
+	matched := make([]bool, len(records))
+	for _, value := range d.values {
+		found := false
+		for i, r := range records {
+			if !matched[i] && recordMatches(store, domain, r, value) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, value)
+		}
+	}
+	var extras []Record
+	for i, r := range records {
+		if !matched[i] {
+			extras = append(extras, r)
+		}
+	}
+
+	current := strings.Join(recordValues(records), ",")
+	desired := strings.Join(d.values, ",")
+	if len(missing) == 0 && len(extras) == 0 {
+		planRecord(ctx, domain, d.recordType, PlanNoop, current, desired)
+		markVerified(ctx, domain, d.recordType)
+		saveRecordIDs(ctx, domain, d.recordType, records...)
+		util.Log("你的IP %s 没有变化, 域名 %s", desired, domain)
+		return
+	}
+
+	if setStore, ok := store.(RecordSetStore); ok {
+		action := PlanUpdate
+		if len(records) == 0 {
+			action = PlanCreate
+		}
+		if planRecord(ctx, domain, d.recordType, action, current, desired) {
+			return
+		}
+		err := util.Retry(ctx, true, func() error { return setStore.SetRecords(domain, d.recordType, records, d.values) })
+		if err != nil {
+			util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
+			failDomain(domain, err)
+			return
+		}
+		util.Log("更新域名解析 %s 成功! IP: %s", domain, desired)
+		saveRecordIDs(ctx, domain, d.recordType)
+		domain.UpdateStatus = config.UpdatedSuccess
+		return
+	}
+
+	var failed error
+	written := false
+	for i, value := range missing {
+		if i < len(extras) {
+			if planRecord(ctx, domain, d.recordType, PlanUpdate, extras[i].Value, value) {
+				continue
+			}
+			err := util.Retry(ctx, true, func() error { return store.UpdateRecord(domain, extras[i], value) })
+			if err != nil {
+				util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
+				failed = err
+				continue
+			}
+			util.Log("更新域名解析 %s 成功! IP: %s", domain, value)
+		} else {
+			if planRecord(ctx, domain, d.recordType, PlanCreate, "", value) {
+				continue
+			}
+			err := util.Retry(ctx, false, func() error { return store.CreateRecord(domain, d.recordType, value) })
+			if err != nil {
+				util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
+				failed = err
+				continue
+			}
+			util.Log("新增域名解析 %s 成功! IP: %s", domain, value)
+		}
+		written = true
+	}
+	for i := len(missing); i < len(extras); i++ {
+		if planRecord(ctx, domain, d.recordType, PlanDelete, extras[i].Value, "") {
+			continue
+		}
+		err := util.Retry(ctx, true, func() error { return store.DeleteRecord(domain, extras[i]) })
+		if err != nil {
+			util.Log("删除域名解析 %s 失败! 异常信息: %s", domain, err)
+			failed = err
+			continue
+		}
+		util.Log("删除域名解析 %s 成功! IP: %s", domain, extras[i].Value)
+		written = true
+	}
+
+	if failed != nil {
+		failDomain(domain, failed)
+	} else if written {
+		// 保留的和被修改的记录ID不变
+		var kept []Record
+		for i, r := range records {
+			if matched[i] {
+				kept = append(kept, r)
+			}
+		}
+		kept = append(kept, extras[:min(len(missing), len(extras))]...)
+		saveRecordIDs(ctx, domain, d.recordType, kept...)
+		domain.UpdateStatus = config.UpdatedSuccess
+	}
+}
+
+// recordValues 返回记录的值
+func recordValues(records []Record) []string {
+	values := make([]string, 0, len(records))
+	for _, r := range records {
+		values = append(values, r.Value)
+	}
+	return values
+}
@@ -0,0 +1,158 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// fakeStore 记录 RecordStore 的调用, records 以 Domain.String()+类型 为键
+type fakeStore struct {
+	records map[string][]Record
+	listErr error
+	lists   int
+	writes  []string
+}
+
+func (f *fakeStore) ListRecords(domain *config.Domain, recordType string) ([]Record, error) {
+	f.lists++
+	return f.records[domain.String()+recordType], f.listErr
+}
+
+func (f *fakeStore) CreateRecord(domain *config.Domain, recordType string, value string) error {
+	f.writes = append(f.writes, "create "+domain.String()+" "+recordType+" "+value)
+	return nil
+}
+
+func (f *fakeStore) UpdateRecord(domain *config.Domain, record Record, value string) error {
+	f.writes = append(f.writes, "update "+record.ID+" "+value)
+	return nil
+}
+
+func (f *fakeStore) DeleteRecord(domain *config.Domain, record Record) error {
+	f.writes = append(f.writes, "delete "+record.ID)
+	return nil
+}
+
+func newTuples(ipv4 string, ipv4Domains []*config.Domain, ipv6 string, ipv6Domains []*config.Domain) config.DomainTuples {
+	domains := config.Domains{
+		Ipv4Addr:    ipv4,
+		Ipv4Cache:   &util.IpCache{},
+		Ipv4Domains: ipv4Domains,
+		Ipv6Addr:    ipv6,
+		Ipv6Cache:   &util.IpCache{},
+		Ipv6Domains: ipv6Domains,
+	}
+	return domains.GetAllNewIpResult("A/AAAA")
+}
+
+func TestReconcile(t *testing.T) {
+	created := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+	changed := &config.Domain{DomainName: "example.com", SubDomain: "b"}
+	unchanged := &config.Domain{DomainName: "example.com", SubDomain: "c"}
+	selected := &config.Domain{DomainName: "example.com", SubDomain: "d", CustomParams: "RecordId=2"}
+	duplicated := &config.Domain{DomainName: "example.com", SubDomain: "e"}
+	created6 := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+
+	store := &fakeStore{records: map[string][]Record{
+		"b.example.comA": {{ID: "b", Type: "A", Value: "192.0.2.1"}},
+		"c.example.comA": {{ID: "c", Type: "A", Value: "192.0.2.2"}},
+		"d.example.comA": {{ID: "1", Type: "A", Value: "192.0.2.2"}, {ID: "2", Type: "A", Value: "192.0.2.1"}},
+		// 未指定记录ID时修改全部记录
+		"e.example.comA": {{ID: "e1", Type: "A", Value: "192.0.2.1"}, {ID: "e2", Type: "A", Value: "192.0.2.2"}, {ID: "e3", Type: "A", Value: "192.0.2.3"}},
+	}}
+	Reconcile(context.Background(), store, newTuples(
+		"192.0.2.2", []*config.Domain{created, changed, unchanged, selected, duplicated},
+		"2001:db8::1", []*config.Domain{created6},
+	))
+
+	want := []string{
+		"create a.example.com A 192.0.2.2",
+		"update b 192.0.2.2",
+		"update 2 192.0.2.2",
+		"update e1 192.0.2.2",
+		"update e3 192.0.2.2",
+		"create a.example.com AAAA 2001:db8::1",
+	}
+	if len(store.writes) != len(want) {
+		t.Fatalf("writes = %q, want %q", store.writes, want)
+	}
+	for i := range want {
+		if store.writes[i] != want[i] {
+			t.Errorf("write %d = %q, want %q", i, store.writes[i], want[i])
+		}
+	}
+
+	for _, d := range []*config.Domain{created, changed, selected, duplicated, created6} {
+		if d.UpdateStatus != config.UpdatedSuccess {
+			t.Errorf("%s status = %q, want %q", d, d.UpdateStatus, config.UpdatedSuccess)
+		}
+	}
+	if unchanged.UpdateStatus != "" {
+		t.Errorf("%s status = %q, want empty", unchanged, unchanged.UpdateStatus)
+	}
+}
+
+func TestReconcileAuthError(t *testing.T) {
+	a := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+	b := &config.Domain{DomainName: "example.com", SubDomain: "b"}
+	store := &fakeStore{listErr: util.NewAPIError(util.ErrorAuth, errors.New("401"))}
+	Reconcile(context.Background(), store, newTuples("192.0.2.1", []*config.Domain{a, b}, "", nil))
+
+	// 认证失败后不再请求其余域名
+	if store.lists != 1 {
+		t.Errorf("lists = %d, want 1", store.lists)
+	}
+	for _, d := range []*config.Domain{a, b} {
+		if d.UpdateStatus != config.UpdatedFailed || d.ErrorKind != util.ErrorAuth {
+			t.Errorf("%s status = %q, kind = %q, want failed auth", d, d.UpdateStatus, d.ErrorKind)
+		}
+	}
+}
+
+func TestReconcileListError(t *testing.T) {
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+	store := &fakeStore{listErr: errors.New("boom")}
+	Reconcile(context.Background(), store, newTuples("192.0.2.1", []*config.Domain{domain}, "", nil))
+
+	if len(store.writes) != 0 {
+		t.Errorf("writes = %q, want none", store.writes)
+	}
+	if domain.UpdateStatus != config.UpdatedFailed {
+		t.Errorf("status = %q, want %q", domain.UpdateStatus, config.UpdatedFailed)
+	}
+}
+
+func TestReconcileRecordSet(t *testing.T) {
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "www"}
+	store := &fakeStore{records: map[string][]Record{
+		"www.example.comA": {
+			{ID: "1", Type: "A", Value: "192.0.2.1"},
+			{ID: "2", Type: "A", Value: "192.0.2.9"},
+			{ID: "3", Type: "A", Value: "192.0.2.8"},
+		},
+	}}
+
+	reconcileRecord(context.Background(), store, desiredRecord{
+		domain:     domain,
+		recordType: "A",
+		value:      "192.0.2.1",
+		values:     []string{"192.0.2.1", "192.0.2.2"},
+	})
+
+	want := []string{"update 2 192.0.2.2", "delete 3"}
+	if len(store.writes) != len(want) {
+		t.Fatalf("writes = %q, want %q", store.writes, want)
+	}
+	for i := range want {
+		if store.writes[i] != want[i] {
+			t.Errorf("write %d = %q, want %q", i, store.writes[i], want[i])
+		}
+	}
+	if domain.UpdateStatus != config.UpdatedSuccess {
+		t.Errorf("status = %q, want %q", domain.UpdateStatus, config.UpdatedSuccess)
+	}
+}
@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Provider DNS服务商的构造函数和元数据
+type Provider struct {
+	// Key 配置文件中使用的名称, 如 alidns
+	Key string `json:"key"`
+	// Order 在前端下拉框中的顺序, 从小到大排列, 相同时按 Key 排列
+	Order int `json:"-"`
+	// Name 显示名称, 按语言区分
+	Name map[string]string `json:"name"`
+	// IDLabel 为空时不需要填写ID
+	IDLabel     string            `json:"idLabel"`
+	SecretLabel string            `json:"secretLabel"`
+	HelpHTML    map[string]string `json:"helpHtml"`
+	// ExtParamLabel 为空时不显示扩展参数
+	ExtParamLabel    string            `json:"extParamLabel,omitempty"`
+	ExtParamHelpHTML map[string]string `json:"extParamHelpHtml,omitempty"`
+	// RecordTypes 支持的记录类型
+	RecordTypes []string `json:"recordTypes"`
+	// MinTTL 最小TTL, 0为不限制
+	MinTTL int `json:"minTTL"`
+	// DefaultTTL 未填写TTL时使用的值
+	DefaultTTL int `json:"defaultTTL"`
+	// MaxConcurrency 同时更新该服务商的配置数量上限, 0为不限制
+	MaxConcurrency int `json:"-"`
+	// Endpoints 用于 WaitInternet 检测网络
+	Endpoints []string `json:"-"`
+	// New 创建DNS实例
+	New func() DNS `json:"-"`
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]*Provider{}
+)
+
+// Register 注册DNS服务商, 一般在服务商文件的 init 中调用
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if p.Key == "" || p.New == nil {
+		panic("dns: Register provider with empty key or nil constructor")
+	}
+	if _, dup := providers[p.Key]; dup {
+		panic("dns: Register called twice for provider " + p.Key)
+	}
+	if len(p.RecordTypes) == 0 {
+		p.RecordTypes = []string{"A", "AAAA"}
+	}
+	providers[p.Key] = &p
+}
+
+// GetProvider 根据名称获取DNS服务商
+func GetProvider(key string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	p, ok := providers[key]
+	if !ok {
+		return Provider{}, false
+	}
+	return *p, true
+}
+
+// ManagesRecords 服务商是否可以查询和逐条修改记录, 处理旧记录和多地址需要该能力
+func (p Provider) ManagesRecords() bool {
+	_, ok := p.New().(RecordStore)
+	return ok
+}
+
+// Providers 按 Order 返回所有DNS服务商
+func Providers() []Provider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	list := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Order != list[j].Order {
+			return list[i].Order < list[j].Order
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// New 根据名称创建DNS实例, 名称不存在时返回错误
+func New(key string) (DNS, error) {
+	p, ok := GetProvider(key)
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider %q", key)
+	}
+	return p.New(), nil
+}
+
+// Addresses 所有DNS服务商的地址, 用于 WaitInternet 检测网络
+func Addresses() []string {
+	var addresses []string
+	for _, p := range Providers() {
+		addresses = append(addresses, p.Endpoints...)
+	}
+	return addresses
+}
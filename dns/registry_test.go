@@ -0,0 +1,46 @@
+package dns
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	keys := []string{
+		"alidns", "aliesa", "tencentcloud", "trafficroute", "dnspod", "dnsla",
+		"cloudflare", "huaweicloud", "callback", "baiducloud", "porkbun", "godaddy",
+		"namecheap", "namesilo", "vercel", "dynadot", "dynv6", "spaceship", "nowcn",
+		"eranet", "tnethk", "gcore", "edgeone", "nsone", "name_com", "rainyun",
+		"hipmdnsmgr", "cloudns", "desec",
+	}
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			p, ok := GetProvider(key)
+			if !ok {
+				t.Fatalf("provider %q not registered", key)
+			}
+			if p.Name["en"] == "" || p.SecretLabel == "" {
+				t.Errorf("provider %q missing metadata", key)
+			}
+			dns, err := New(key)
+			if err != nil || dns == nil {
+				t.Errorf("New(%q) = %v, %v", key, dns, err)
+			}
+		})
+	}
+
+	if len(Providers()) != len(keys) {
+		t.Errorf("Providers() returned %d providers, want %d", len(Providers()), len(keys))
+	}
+	// 按前端下拉框的顺序排列
+	list := Providers()
+	if list[0].Key != "alidns" || list[len(list)-1].Key != "desec" {
+		t.Errorf("Providers() order = %s ... %s, want alidns ... desec", list[0].Key, list[len(list)-1].Key)
+	}
+	if len(Addresses()) == 0 {
+		t.Error("Addresses() is empty")
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := New("aliyun-typo"); err == nil {
+		t.Error("New with unknown provider should return an error")
+	}
+}
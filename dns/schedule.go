@@ -0,0 +1,108 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// scheduleCheck 最长等待时间, 以便及时发现新增或修改的配置
+const scheduleCheck = time.Minute
+
+var (
+	// nextRuns 各配置的下次运行时间, 以配置的摘要为键
+	nextRuns   = map[string]time.Time{}
+	nextRunsMu sync.Mutex
+	// scheduleDelay 未设置更新计划的配置使用的间隔, RunTimer 运行前为 0
+	scheduleDelay time.Duration
+)
+
+// NextRun 配置的下次计划运行时间, 定时运行未启动时返回零值
+func NextRun(dc *config.DnsConfig) time.Time {
+	nextRunsMu.Lock()
+	defer nextRunsMu.Unlock()
+
+	if scheduleDelay <= 0 {
+		return time.Time{}
+	}
+	return nextRunLocked(dc, configKey(dc), time.Now())
+}
+
+// nextRunLocked 返回配置的下次运行时间, 新的配置从 now 开始计算, 需持有 nextRunsMu
+func nextRunLocked(dc *config.DnsConfig, key string, now time.Time) time.Time {
+	next, ok := nextRuns[key]
+	if !ok {
+		next = scheduleNext(dc, scheduleDelay, now)
+		nextRuns[key] = next
+	}
+	return next
+}
+
+// scheduleNext 计算 now 之后的下次运行时间, 未设置或无效时使用 delay
+func scheduleNext(dc *config.DnsConfig, delay time.Duration, now time.Time) time.Time {
+	schedule, err := dc.GetSchedule()
+	if err != nil {
+		util.Log("更新计划无效, 将使用默认间隔! 异常信息: %s", err)
+	}
+	if schedule == nil {
+		return now.Add(delay)
+	}
+	return schedule.Next(now)
+}
+
+// dueConfigs 返回到期的配置的摘要, 以及最早的下次运行时间
+func dueConfigs(conf *config.Config, now time.Time) (due map[string]bool, earliest time.Time) {
+	nextRunsMu.Lock()
+	defer nextRunsMu.Unlock()
+
+	due = map[string]bool{}
+	keys := map[string]bool{}
+	for i := range conf.DnsConf {
+		key := configKey(&conf.DnsConf[i])
+		keys[key] = true
+		next := nextRunLocked(&conf.DnsConf[i], key, now)
+		if !now.Before(next) {
+			due[key] = true
+			continue
+		}
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	// 清理已删除的配置
+	for key := range nextRuns {
+		if !keys[key] {
+			delete(nextRuns, key)
+		}
+	}
+	return
+}
+
+// runDue 运行到期的配置并计算下次运行时间, 返回距下次检查的等待时间
+func runDue(ctx context.Context) time.Duration {
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		return scheduleDelay
+	}
+
+	due, _ := dueConfigs(&conf, time.Now())
+	if len(due) > 0 {
+		runConfigs(ctx, func(dc *config.DnsConfig) bool { return due[configKey(dc)] })
+
+		nextRunsMu.Lock()
+		for key := range due {
+			delete(nextRuns, key)
+		}
+		nextRunsMu.Unlock()
+	}
+
+	_, earliest := dueConfigs(&conf, time.Now())
+	wait := scheduleCheck
+	if !earliest.IsZero() {
+		wait = min(time.Until(earliest), scheduleCheck)
+	}
+	return max(wait, time.Second)
+}
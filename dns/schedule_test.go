@@ -0,0 +1,48 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+)
+
+func TestDueConfigs(t *testing.T) {
+	scheduleDelay = 5 * time.Minute
+	t.Cleanup(func() {
+		scheduleDelay = 0
+		nextRuns = map[string]time.Time{}
+	})
+
+	conf := &config.Config{DnsConf: []config.DnsConfig{
+		{Name: "default"},
+		{Name: "hourly", Schedule: "0 * * * *"},
+		{Name: "invalid", Schedule: "61 * * * *"},
+	}}
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.Local)
+
+	// 新的配置从现在开始计算
+	due, earliest := dueConfigs(conf, now)
+	if len(due) != 0 {
+		t.Fatalf("due = %v, want none", due)
+	}
+	if want := now.Add(5 * time.Minute); !earliest.Equal(want) {
+		t.Errorf("earliest = %s, want %s", earliest, want)
+	}
+	if got, want := nextRuns[configKey(&conf.DnsConf[1])], time.Date(2024, 1, 1, 11, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("hourly next = %s, want %s", got, want)
+	}
+
+	// 无效的计划使用默认间隔
+	due, _ = dueConfigs(conf, now.Add(5*time.Minute))
+	if len(due) != 2 || !due[configKey(&conf.DnsConf[0])] || !due[configKey(&conf.DnsConf[2])] {
+		t.Errorf("due = %v, want default and invalid configs", due)
+	}
+
+	// 删除的配置不再计划
+	conf.DnsConf = conf.DnsConf[:1]
+	dueConfigs(conf, now)
+	if len(nextRuns) != 1 {
+		t.Errorf("len(nextRuns) = %d, want 1", len(nextRuns))
+	}
+}
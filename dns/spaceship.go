@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -21,12 +22,32 @@ type Spaceship struct {
 	header     http.Header
 	ttl        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
-func (s *Spaceship) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "spaceship",
+		Order: 17,
+		Name: map[string]string{
+			"en": "Spaceship",
+		},
+		IDLabel:     "API Key",
+		SecretLabel: "API Secret",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://www.spaceship.com/application/api-manager/'>Create API Key</a>",
+			"zh-cn": "<a target='_blank' href='https://www.spaceship.com/application/api-manager/'>创建 API 密钥</a>",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &Spaceship{} },
+	})
+}
+
+func (s *Spaceship) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	s.ctx = ctx
 	s.domains.Ipv4Cache = ipv4cache
 	s.domains.Ipv6Cache = ipv6cache
-	s.domains.GetNewIp(dnsConf)
+	s.domains.GetNewIp(ctx, dnsConf)
 
 	s.ttl = 600
 	if val, err := strconv.Atoi(dnsConf.TTL); err == nil {
@@ -41,32 +62,54 @@ func (s *Spaceship) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv
 }
 
 func (s *Spaceship) AddUpdateDomainRecords() (domains config.Domains) {
-	for _, recordType := range []string{"A", "AAAA"} {
-		ip, domains := s.domains.GetNewIpResult(recordType)
-		if ip == "" {
-			continue
-		}
-		for _, domain := range domains {
-			hasUpdated, err := s.updateRecord(recordType, ip, domain)
-			if err != nil {
-				util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-				domain.UpdateStatus = config.UpdatedFailed
-				continue
-			}
-			if !hasUpdated {
-				util.Log("你的IP %s 没有变化, 域名 %s", ip, domain)
-			} else {
-				util.Log("更新域名解析 %s 成功! IP: %s", domain, ip)
-				domain.UpdateStatus = config.UpdatedSuccess
-			}
-		}
-	}
+	Reconcile(s.ctx, s, s.domains.GetAllNewIpResult("A/AAAA"))
 	return s.domains
 }
 
+// ListRecords 每个IP返回一条记录
+func (s *Spaceship) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	ips, err := s.getRecords(recordType, domain)
+	for _, ip := range ips {
+		records = append(records, Record{Type: recordType, Value: ip})
+	}
+	return
+}
+
+// CreateRecord 新增记录
+func (s *Spaceship) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	return s.createRecords(recordType, []string{ipAddr}, domain)
+}
+
+// UpdateRecord 删除原有记录后重新创建
+func (s *Spaceship) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	err := s.deleteRecords(r.Type, domain, []string{r.Value})
+	if err != nil {
+		return err
+	}
+	return s.createRecords(r.Type, []string{ipAddr}, domain)
+}
+
+// DeleteRecord 删除记录
+func (s *Spaceship) DeleteRecord(domain *config.Domain, r Record) error {
+	return s.deleteRecords(r.Type, domain, []string{r.Value})
+}
+
+// SetRecords 删除全部原有记录后重新创建
+func (s *Spaceship) SetRecords(domain *config.Domain, recordType string, records []Record, values []string) error {
+	ips := make([]string, 0, len(records))
+	for _, r := range records {
+		ips = append(ips, r.Value)
+	}
+	err := s.deleteRecords(recordType, domain, ips)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	return s.createRecords(recordType, values, domain)
+}
+
 func (s *Spaceship) request(domain *config.Domain, method string, query url.Values, payload []byte) (response []byte, err error) {
 	url := fmt.Sprintf("%s/%s", spaceshipAPI, domain.DomainName)
-	req, err := http.NewRequest(method, url, bytes.NewBuffer([]byte(payload)))
+	req, err := http.NewRequestWithContext(s.ctx, method, url, bytes.NewBuffer([]byte(payload)))
 	if err != nil {
 		return
 	}
@@ -108,7 +151,7 @@ func (s *Spaceship) request(domain *config.Domain, method string, query url.Valu
 	return
 }
 
-func (s *Spaceship) createRecord(recordType string, ip string, domain *config.Domain) (err error) {
+func (s *Spaceship) createRecords(recordType string, ips []string, domain *config.Domain) (err error) {
 	type Item struct {
 		Type    string `json:"type"`
 		Address string `json:"address"`
@@ -121,16 +164,14 @@ func (s *Spaceship) createRecord(recordType string, ip string, domain *config.Do
 		Items []Item `json:"items"`
 	}
 
-	payload := Payload{
-		Force: true,
-		Items: []Item{
-			{
-				Type:    recordType,
-				Address: ip,
-				Name:    domain.SubDomain,
-				TTL:     s.ttl,
-			},
-		},
+	payload := Payload{Force: true}
+	for _, ip := range ips {
+		payload.Items = append(payload.Items, Item{
+			Type:    recordType,
+			Address: ip,
+			Name:    domain.SubDomain,
+			TTL:     s.ttl,
+		})
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -212,20 +253,3 @@ func (s *Spaceship) deleteRecords(recordType string, domain *config.Domain, ips
 	_, err = s.request(domain, "DELETE", url.Values{}, data)
 	return
 }
-
-func (s *Spaceship) updateRecord(recordType string, ip string, domain *config.Domain) (hasUpdated bool, err error) {
-	ips, err := s.getRecords(recordType, domain)
-	if err != nil {
-		return
-	}
-	if len(ips) == 1 && ips[0] == ip {
-		return
-	}
-	err = s.deleteRecords(recordType, domain, ips)
-	if err != nil {
-		return
-	}
-	err = s.createRecord(recordType, ip, domain)
-	hasUpdated = true
-	return
-}
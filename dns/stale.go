@@ -0,0 +1,162 @@
+package dns
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// reconcileStale 连续多次未能获取IP时, 按配置删除对应的记录或修改为备用地址
+// 同时清空IP缓存, 恢复获取IP后会重新比对并还原记录
+func reconcileStale(ctx context.Context, dnsSelected DNS, dc *config.DnsConfig, domains *config.Domains) {
+	times := dc.GetStaleTimes()
+	if times <= 0 {
+		return
+	}
+
+	families := []struct {
+		recordType string
+		cache      *util.IpCache
+		domains    []*config.Domain
+		fallback   string
+	}{
+		{"A", domains.Ipv4Cache, domains.Ipv4Domains, dc.Ipv4.Fallback},
+		{"AAAA", domains.Ipv6Cache, domains.Ipv6Domains, dc.Ipv6.Fallback},
+	}
+	for _, f := range families {
+		if f.cache == nil || f.cache.TimesFailedIP < times || len(f.domains) == 0 {
+			continue
+		}
+
+		store, ok := dnsSelected.(RecordStore)
+		if !ok {
+			// 只提示一次
+			if f.cache.TimesFailedIP == times {
+				util.Log("DNS服务商 %s 不支持处理失效记录", dc.DNS.Name)
+			}
+			continue
+		}
+
+		for _, domain := range f.domains {
+			if ctx.Err() != nil {
+				return
+			}
+			// 已处理过的域名在重新获取到地址并更新前不再处理
+			if staleHandled(dc, domain, f.recordType) {
+				continue
+			}
+			var handled bool
+			if dc.StaleAction == config.StaleActionFallback {
+				handled = fallbackRecord(ctx, store, domain, f.recordType, f.fallback)
+			} else {
+				handled = deleteRecords(ctx, store, domain, f.recordType)
+			}
+			if handled && !isDryRun(ctx) {
+				markStaleHandled(dc, domain, f.recordType)
+			}
+		}
+		f.cache.Reset()
+	}
+}
+
+// staleHandled 域名的记录是否已按失效处理
+func staleHandled(dc *config.DnsConfig, domain *config.Domain, recordType string) bool {
+	lastRecordsMu.Lock()
+	defer lastRecordsMu.Unlock()
+
+	for _, rs := range lastRecords[configKey(dc)] {
+		if rs.Domain == domain.String() && rs.RecordType == recordType {
+			return rs.Stale
+		}
+	}
+	return false
+}
+
+// markStaleHandled 记录域名的记录已按失效处理, 更新成功后清除
+func markStaleHandled(dc *config.DnsConfig, domain *config.Domain, recordType string) {
+	lastRecordsMu.Lock()
+	defer lastRecordsMu.Unlock()
+
+	key := configKey(dc)
+	records := lastRecords[key]
+	for i := range records {
+		if records[i].Domain == domain.String() && records[i].RecordType == recordType {
+			records[i].Stale = true
+			return
+		}
+	}
+	lastRecords[key] = append(records, RecordState{Domain: domain.String(), RecordType: recordType, Stale: true})
+}
+
+// deleteRecords 删除域名指定类型的全部记录, 返回是否全部处理成功
+func deleteRecords(ctx context.Context, store RecordStore, domain *config.Domain, recordType string) bool {
+	records, err := listRecordsRetry(ctx, store, domain, recordType)
+	if err != nil {
+		util.Log("查询域名信息发生异常! %s", err)
+		return false
+	}
+
+	if setStore, ok := store.(RecordSetStore); ok && len(records) > 0 {
+		current := strings.Join(recordValues(records), ",")
+		if planRecord(ctx, domain, recordType, PlanDelete, current, "") {
+			return true
+		}
+		err := util.Retry(ctx, true, func() error { return setStore.SetRecords(domain, recordType, records, nil) })
+		if err != nil {
+			util.Log("删除域名解析 %s 失败! 异常信息: %s", domain, err)
+			return false
+		}
+		util.Log("未能获取IP, 已删除域名 %s 的 %s 记录 %s", domain, recordType, current)
+		return true
+	}
+
+	ok := true
+	for _, record := range records {
+		if planRecord(ctx, domain, recordType, PlanDelete, record.Value, "") {
+			continue
+		}
+		err := util.Retry(ctx, true, func() error { return store.DeleteRecord(domain, record) })
+		if err != nil {
+			util.Log("删除域名解析 %s 失败! 异常信息: %s", domain, err)
+			ok = false
+			continue
+		}
+		util.Log("未能获取IP, 已删除域名 %s 的 %s 记录 %s", domain, recordType, record.Value)
+	}
+	return ok
+}
+
+// fallbackRecord 将域名的记录修改为备用地址, 记录不存在时不新增, 返回是否全部处理成功
+func fallbackRecord(ctx context.Context, store RecordStore, domain *config.Domain, recordType string, fallback string) bool {
+	if fallback == "" {
+		return false
+	}
+
+	records, err := listRecordsRetry(ctx, store, domain, recordType)
+	if err != nil {
+		util.Log("查询域名信息发生异常! %s", err)
+		return false
+	}
+
+	ok := true
+
+	for _, record := range selectRecords(domain, records) {
+		if recordMatches(store, domain, record, fallback) {
+			planRecord(ctx, domain, recordType, PlanNoop, record.Value, fallback)
+			continue
+		}
+		if planRecord(ctx, domain, recordType, PlanUpdate, record.Value, fallback) {
+			continue
+		}
+		err = util.Retry(ctx, true, func() error { return store.UpdateRecord(domain, record, fallback) })
+		if err != nil {
+			util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
+			ok = false
+			continue
+		}
+		util.Log("未能获取IP, 已将域名 %s 的 %s 记录修改为备用地址 %s", domain, recordType, fallback)
+	}
+	return ok
+}
@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// fakeStoreDNS 同时实现 DNS 和 RecordStore
+type fakeStoreDNS struct {
+	fakeStore
+}
+
+func (f *fakeStoreDNS) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+}
+
+func (f *fakeStoreDNS) AddUpdateDomainRecords() config.Domains {
+	return config.Domains{}
+}
+
+func TestReconcileStale(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		failedTimes int
+		want        []string
+		wantReset   bool
+	}{
+		{name: "below threshold", action: config.StaleActionDelete, failedTimes: 2},
+		{name: "delete", action: "", failedTimes: 3, want: []string{"delete 1", "delete 2"}, wantReset: true},
+		{name: "fallback", action: config.StaleActionFallback, failedTimes: 4, want: []string{"update 1 2001:db8::ffff", "update 2 2001:db8::ffff"}, wantReset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc := &config.DnsConfig{StaleTimes: "3", StaleAction: tt.action}
+			dc.Ipv6.Fallback = "2001:db8::ffff"
+			domain := &config.Domain{DomainName: "example.com", SubDomain: "www"}
+			store := &fakeStoreDNS{fakeStore{records: map[string][]Record{
+				"www.example.comAAAA": {{ID: "1", Type: "AAAA", Value: "2001:db8::1"}, {ID: "2", Type: "AAAA", Value: "2001:db8::2"}},
+			}}}
+			domains := &config.Domains{
+				Ipv4Cache:   &util.IpCache{Addr: "192.0.2.1", Times: 5},
+				Ipv6Cache:   &util.IpCache{Addr: "2001:db8::1", Times: 5, TimesFailedIP: tt.failedTimes},
+				Ipv6Domains: []*config.Domain{domain},
+			}
+
+			reconcileStale(context.Background(), store, dc, domains)
+
+			if len(store.writes) != len(tt.want) {
+				t.Fatalf("writes = %q, want %q", store.writes, tt.want)
+			}
+			for i := range tt.want {
+				if store.writes[i] != tt.want[i] {
+					t.Errorf("write %d = %q, want %q", i, store.writes[i], tt.want[i])
+				}
+			}
+			if reset := domains.Ipv6Cache.Addr == ""; reset != tt.wantReset {
+				t.Errorf("ipv6 cache reset = %v, want %v", reset, tt.wantReset)
+			}
+			if domains.Ipv4Cache.Addr == "" {
+				t.Errorf("ipv4 cache should not be reset")
+			}
+		})
+	}
+}
+
+func TestReconcileStaleOnce(t *testing.T) {
+	dc := &config.DnsConfig{StaleTimes: "1", StaleAction: config.StaleActionDelete, Name: "stale-once"}
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "once"}
+	store := &fakeStoreDNS{fakeStore{records: map[string][]Record{
+		"once.example.comAAAA": {{ID: "1", Type: "AAAA", Value: "2001:db8::1"}},
+	}}}
+	domains := &config.Domains{
+		Ipv6Cache:   &util.IpCache{TimesFailedIP: 1},
+		Ipv6Domains: []*config.Domain{domain},
+	}
+	t.Cleanup(func() { delete(lastRecords, configKey(dc)) })
+
+	reconcileStale(context.Background(), store, dc, domains)
+	domains.Ipv6Cache.TimesFailedIP++
+	reconcileStale(context.Background(), store, dc, domains)
+	if len(store.writes) != 1 {
+		t.Fatalf("writes = %q, want a single delete", store.writes)
+	}
+
+	// 恢复并更新成功后, 再次失效时重新处理
+	domain.UpdateStatus = config.UpdatedSuccess
+	domains.Ipv6Addr = "2001:db8::2"
+	updateLastRecords(dc, domains, &verifiedRecords{keys: map[string]bool{}})
+	reconcileStale(context.Background(), store, dc, domains)
+	if len(store.writes) != 2 {
+		t.Fatalf("writes = %q, want a second delete after recovery", store.writes)
+	}
+}
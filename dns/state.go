@@ -0,0 +1,290 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+// State 保存在状态文件中的运行状态, 重启后从中恢复, 避免重新比对全部域名
+type State struct {
+	Configs []ConfigState `json:"configs"`
+}
+
+// ConfigState 单个配置的状态
+type ConfigState struct {
+	// Key 配置的摘要, 配置被修改后不再使用旧的状态
+	Key       string        `json:"key"`
+	Ipv4Cache util.IpCache  `json:"ipv4_cache"`
+	Ipv6Cache util.IpCache  `json:"ipv6_cache"`
+	Records   []RecordState `json:"records,omitempty"`
+	// AddrHistory IPv4/IPv6地址变化的历史
+	AddrHistory [2]AddrHistory `json:"addr_history,omitzero"`
+}
+
+// RecordState 域名最后一次确认的记录
+type RecordState struct {
+	Domain     string `json:"domain"`
+	RecordType string `json:"record_type"`
+	// Value 最后一次更新成功或查询确认的值, dry-run 中作为无法查询的服务商的当前值
+	Value string `json:"value"`
+	// RecordIDs 服务商中对应记录的ID, 新增的记录在下次查询后才有ID
+	RecordIDs []string `json:"record_ids,omitempty"`
+	// LastUpdated 最后一次更新成功的时间
+	LastUpdated time.Time `json:"last_updated,omitzero"`
+	// Stale 连续未能获取IP, 记录已被删除或修改为备用地址, 再次更新成功后清除
+	Stale bool `json:"stale,omitempty"`
+}
+
+var (
+	// lastRecords 各配置的记录状态, 以配置的摘要为键, 强制比对时不会清空
+	lastRecords   = map[string][]RecordState{}
+	lastRecordsMu sync.Mutex
+)
+
+// stateFilePath 状态文件路径, 与配置文件在同一目录下
+func stateFilePath() string {
+	configFilePath := util.GetConfigFilePath()
+	return strings.TrimSuffix(configFilePath, filepath.Ext(configFilePath)) + ".state.json"
+}
+
+// configKey 配置的摘要
+func configKey(dc *config.DnsConfig) string {
+	return dc.Key()
+}
+
+// LoadState 启动时从状态文件恢复IP缓存和记录状态, 未修改的配置将继续使用上次的缓存
+func LoadState() {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		return
+	}
+	path := stateFilePath()
+	state, err := readState(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			util.Log("读取状态文件 %s 失败! 异常信息: %s", path, err)
+		}
+		return
+	}
+
+	byKey := make(map[string]ConfigState, len(state.Configs))
+	for _, cs := range state.Configs {
+		byKey[cs.Key] = cs
+	}
+
+	restored := 0
+	Ipcache = make([][2]util.IpCache, len(conf.DnsConf))
+	ipcacheKeys = make([]string, len(conf.DnsConf))
+	lastRecordsMu.Lock()
+	addrHistoryMu.Lock()
+	for i := range conf.DnsConf {
+		ipcacheKeys[i] = configKey(&conf.DnsConf[i])
+		cs, ok := byKey[ipcacheKeys[i]]
+		if !ok {
+			// 新增或修改过的配置, 使用空缓存重新比对
+			continue
+		}
+		Ipcache[i] = [2]util.IpCache{cs.Ipv4Cache, cs.Ipv6Cache}
+		lastRecords[cs.Key] = cs.Records
+		if !cs.AddrHistory[0].empty() || !cs.AddrHistory[1].empty() {
+			addrHistory[cs.Key] = cs.AddrHistory
+		}
+		restored++
+	}
+	addrHistoryMu.Unlock()
+	lastRecordsMu.Unlock()
+	util.ForceCompareGlobal = false
+
+	util.Log("已从状态文件 %s 恢复 %d 个配置的缓存", path, restored)
+}
+
+func readState(path string) (state State, err error) {
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(byt, &state)
+	return
+}
+
+// saveState 保存当前的IP缓存和记录状态, 需持有 runMu
+func saveState(conf *config.Config) {
+	state := State{Configs: make([]ConfigState, 0, len(conf.DnsConf))}
+	keys := make(map[string]bool, len(conf.DnsConf))
+
+	lastRecordsMu.Lock()
+	addrHistoryMu.Lock()
+	for i := range conf.DnsConf {
+		key := configKey(&conf.DnsConf[i])
+		keys[key] = true
+		cs := ConfigState{Key: key, Records: lastRecords[key], AddrHistory: addrHistory[key]}
+		if i < len(Ipcache) {
+			cs.Ipv4Cache, cs.Ipv6Cache = Ipcache[i][0], Ipcache[i][1]
+		}
+		state.Configs = append(state.Configs, cs)
+	}
+	// 清理已删除的配置
+	for key := range lastRecords {
+		if !keys[key] {
+			delete(lastRecords, key)
+		}
+	}
+	for key := range addrHistory {
+		if !keys[key] {
+			delete(addrHistory, key)
+		}
+	}
+	addrHistoryMu.Unlock()
+	lastRecordsMu.Unlock()
+
+	path := stateFilePath()
+	if err := writeState(path, &state); err != nil {
+		util.Log("保存状态文件 %s 失败! 异常信息: %s", path, err)
+	}
+}
+
+// writeState 先写入临时文件再重命名, 避免中断时状态文件不完整
+func writeState(path string, state *State) error {
+	byt, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(byt); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// verifiedRecords 本次运行中查询确认与期望一致的记录, 以及查询到的记录ID
+type verifiedRecords struct {
+	mu   sync.Mutex
+	keys map[string]bool
+	ids  map[string][]string
+}
+
+type verifiedRecordsKey struct{}
+
+func withVerifiedRecords(ctx context.Context, v *verifiedRecords) context.Context {
+	return context.WithValue(ctx, verifiedRecordsKey{}, v)
+}
+
+// markVerified 记录域名的记录已查询确认, 供状态文件使用
+func markVerified(ctx context.Context, domain *config.Domain, recordType string) {
+	v, _ := ctx.Value(verifiedRecordsKey{}).(*verifiedRecords)
+	if v == nil {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys[domain.String()+" "+recordType] = true
+}
+
+// saveRecordIDs 记录域名在服务商中对应的记录ID, records 为空表示记录已被新增或删除
+func saveRecordIDs(ctx context.Context, domain *config.Domain, recordType string, records ...Record) {
+	v, _ := ctx.Value(verifiedRecordsKey{}).(*verifiedRecords)
+	if v == nil {
+		return
+	}
+
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		if r.ID != "" {
+			ids = append(ids, r.ID)
+		}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.ids == nil {
+		v.ids = map[string][]string{}
+	}
+	v.ids[domain.String()+" "+recordType] = ids
+}
+
+// updateLastRecords 根据本次的更新结果更新配置的记录状态
+func updateLastRecords(dc *config.DnsConfig, domains *config.Domains, v *verifiedRecords) {
+	key := configKey(dc)
+
+	lastRecordsMu.Lock()
+	defer lastRecordsMu.Unlock()
+
+	now := time.Now()
+	records := lastRecords[key]
+	update := func(ds []*config.Domain, recordType, ipAddr string, ipAddrs []string) {
+		for _, d := range ds {
+			k := d.String() + " " + recordType
+			if d.UpdateStatus != config.UpdatedSuccess && !v.keys[k] {
+				continue
+			}
+
+			idx := -1
+			for i := range records {
+				if records[i].Domain == d.String() && records[i].RecordType == recordType {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				records = append(records, RecordState{Domain: d.String(), RecordType: recordType})
+				idx = len(records) - 1
+			}
+			records[idx].Value = joinAddrs(d.HostAddr(ipAddr), d.HostAddrs(ipAddrs))
+			if ids, ok := v.ids[k]; ok {
+				records[idx].RecordIDs = ids
+			}
+			if d.UpdateStatus == config.UpdatedSuccess {
+				records[idx].LastUpdated = now
+				records[idx].Stale = false
+			}
+		}
+	}
+	update(domains.Ipv4Domains, "A", domains.Ipv4Addr, domains.Ipv4Addrs)
+	update(domains.Ipv6Domains, "AAAA", domains.Ipv6Addr, domains.Ipv6Addrs)
+	lastRecords[key] = records
+}
+
+// lastRecordValue 域名最后一次确认的记录值
+func lastRecordValue(key string, domain *config.Domain, recordType string) string {
+	lastRecordsMu.Lock()
+	defer lastRecordsMu.Unlock()
+
+	for _, rs := range lastRecords[key] {
+		if rs.Domain == domain.String() && rs.RecordType == recordType {
+			return rs.Value
+		}
+	}
+	return ""
+}
+
+func joinAddrs(ipAddr string, ipAddrs []string) string {
+	if len(ipAddrs) > 0 {
+		return strings.Join(ipAddrs, ",")
+	}
+	return ipAddr
+}
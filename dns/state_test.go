@@ -0,0 +1,123 @@
+package dns
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestLoadSaveState(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(util.ConfigFilePathENV, filepath.Join(dir, "config.yaml"))
+	t.Cleanup(func() {
+		Ipcache = [][2]util.IpCache{}
+		ipcacheKeys = nil
+		lastRecords = map[string][]RecordState{}
+		util.ForceCompareGlobal = true
+	})
+
+	conf := config.Config{DnsConf: []config.DnsConfig{
+		{Name: "unchanged", DNS: config.DNS{Name: "cloudflare"}},
+		{Name: "changed", DNS: config.DNS{Name: "cloudflare"}},
+	}}
+	if err := conf.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stateFilePath(), filepath.Join(dir, "config.state.json"); got != want {
+		t.Fatalf("stateFilePath() = %q, want %q", got, want)
+	}
+
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []RecordState{{Domain: "a.example.com", RecordType: "A", Value: "192.0.2.1", RecordIDs: []string{"1", "2"}, LastUpdated: updatedAt, Stale: true}}
+	err = writeState(stateFilePath(), &State{Configs: []ConfigState{
+		{Key: configKey(&conf.DnsConf[0]), Ipv4Cache: util.IpCache{Addr: "192.0.2.1", Times: 3}, Records: records},
+		{Key: "outdated", Ipv4Cache: util.IpCache{Addr: "192.0.2.9", Times: 3}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	LoadState()
+	if util.ForceCompareGlobal {
+		t.Error("ForceCompareGlobal should be false after loading state")
+	}
+	if len(Ipcache) != 2 {
+		t.Fatalf("len(Ipcache) = %d, want 2", len(Ipcache))
+	}
+	if Ipcache[0][0].Addr != "192.0.2.1" || Ipcache[0][0].Times != 3 {
+		t.Errorf("Ipcache[0] = %+v, want restored cache", Ipcache[0])
+	}
+	if Ipcache[1] != [2]util.IpCache{} {
+		t.Errorf("Ipcache[1] = %+v, want empty cache for changed config", Ipcache[1])
+	}
+
+	Ipcache[1][1] = util.IpCache{Addr: "2001:db8::1", Times: 6}
+	saveState(&conf)
+	state, err := readState(stateFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Configs) != 2 {
+		t.Fatalf("got %d configs, want 2", len(state.Configs))
+	}
+	if state.Configs[1].Key != configKey(&conf.DnsConf[1]) || state.Configs[1].Ipv6Cache.Addr != "2001:db8::1" {
+		t.Errorf("configs[1] = %+v", state.Configs[1])
+	}
+	if len(state.Configs[0].Records) != 1 || !reflect.DeepEqual(state.Configs[0].Records[0], records[0]) {
+		t.Errorf("configs[0].Records = %+v, want %+v", state.Configs[0].Records, records)
+	}
+
+	// 不应残留临时文件
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("dir entries = %d, want config and state only", len(entries))
+	}
+}
+
+func TestUpdateLastRecords(t *testing.T) {
+	t.Cleanup(func() { lastRecords = map[string][]RecordState{} })
+
+	dc := &config.DnsConfig{Name: "test"}
+	updated := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+	unchanged := &config.Domain{DomainName: "example.com", SubDomain: "b"}
+	created := &config.Domain{DomainName: "example.com", SubDomain: "c"}
+	store := &fakeStore{records: map[string][]Record{
+		"a.example.comA": {{ID: "1", Type: "A", Value: "192.0.2.1"}},
+		"b.example.comA": {{ID: "2", Type: "A", Value: "192.0.2.2"}},
+	}}
+
+	verified := &verifiedRecords{keys: map[string]bool{}}
+	domains := &config.Domains{Ipv4Addr: "192.0.2.2", Ipv4Domains: []*config.Domain{updated, unchanged, created}}
+	Reconcile(withVerifiedRecords(context.Background(), verified), store, newTuples("192.0.2.2", domains.Ipv4Domains, "", nil))
+	updateLastRecords(dc, domains, verified)
+
+	records := lastRecords[configKey(dc)]
+	want := []RecordState{
+		{Domain: updated.String(), RecordType: "A", Value: "192.0.2.2", RecordIDs: []string{"1"}},
+		{Domain: unchanged.String(), RecordType: "A", Value: "192.0.2.2", RecordIDs: []string{"2"}},
+		{Domain: created.String(), RecordType: "A", Value: "192.0.2.2", RecordIDs: []string{}},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(records), len(want), records)
+	}
+	for i := range want {
+		// 只有更新成功的记录有更新时间
+		if records[i].LastUpdated.IsZero() != (i == 1) {
+			t.Errorf("record %d LastUpdated = %v", i, records[i].LastUpdated)
+		}
+		records[i].LastUpdated = time.Time{}
+		if !reflect.DeepEqual(records[i], want[i]) {
+			t.Errorf("record %d = %+v, want %+v", i, records[i], want[i])
+		}
+	}
+}
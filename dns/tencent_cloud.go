@@ -2,9 +2,12 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/jeessy2/ddns-go/v6/config"
 	"github.com/jeessy2/ddns-go/v6/util"
@@ -22,6 +25,7 @@ type TencentCloud struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // TencentCloudRecord 腾讯云记录
@@ -64,11 +68,32 @@ type TencentCloudStatus struct {
 	}
 }
 
-func (tc *TencentCloud) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "tencentcloud",
+		Order: 3,
+		Name: map[string]string{
+			"en":    "Tencent",
+			"zh-cn": "腾讯云",
+		},
+		IDLabel:     "SecretId",
+		SecretLabel: "SecretKey",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.dnspod.cn/account/token/apikey'>Create AccessKey</a>",
+			"zh-cn": "<a target='_blank' href='https://console.dnspod.cn/account/token/apikey'>创建腾讯云 API 密钥</a>",
+		},
+		DefaultTTL: 600,
+		Endpoints:  []string{tencentCloudEndPoint},
+		New:        func() DNS { return &TencentCloud{} },
+	})
+}
+
+func (tc *TencentCloud) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	tc.ctx = ctx
 	tc.Domains.Ipv4Cache = ipv4cache
 	tc.Domains.Ipv6Cache = ipv6cache
 	tc.DNS = dnsConf.DNS
-	tc.Domains.GetNewIp(dnsConf)
+	tc.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		// 默认 600s
 		tc.TTL = 600
@@ -85,50 +110,30 @@ func (tc *TencentCloud) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache,
 
 // AddUpdateDomainRecords 添加或更新 IPv4/IPv6 记录
 func (tc *TencentCloud) AddUpdateDomainRecords() config.Domains {
-	tc.addUpdateDomainRecords("A")
-	tc.addUpdateDomainRecords("AAAA")
+	Reconcile(tc.ctx, tc, tc.Domains.GetAllNewIpResult("A/AAAA"))
 	return tc.Domains
 }
 
-func (tc *TencentCloud) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := tc.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
+// ListRecords 查询记录
+func (tc *TencentCloud) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	result, err := tc.getRecordList(domain, recordType)
+	if err != nil || result.Response.RecordCountInfo.TotalCount == 0 {
 		return
 	}
-
-	for _, domain := range domains {
-		result, err := tc.getRecordList(domain, recordType)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			domain.UpdateStatus = config.UpdatedFailed
-			return
-		}
-
-		if result.Response.RecordCountInfo.TotalCount > 0 {
-			// 默认第一个
-			recordSelected := result.Response.RecordList[0]
-			params := domain.GetCustomParams()
-			if params.Has("RecordId") {
-				for i := 0; i < result.Response.RecordCountInfo.TotalCount; i++ {
-					if strconv.FormatInt(result.Response.RecordList[i].RecordId, 10) == params.Get("RecordId") {
-						recordSelected = result.Response.RecordList[i]
-					}
-				}
-			}
-
-			// 修改记录
-			tc.modify(recordSelected, domain, recordType, ipAddr)
-		} else {
-			// 添加记录
-			tc.create(domain, recordType, ipAddr)
-		}
+	for _, r := range result.Response.RecordList {
+		records = append(records, Record{
+			ID:    strconv.FormatInt(r.RecordId, 10),
+			Type:  r.RecordType,
+			Value: r.Value,
+			Raw:   r,
+		})
 	}
+	return
 }
 
-// create 添加记录
+// CreateRecord 添加记录
 // CreateRecord https://cloud.tencent.com/document/api/1427/56180
-func (tc *TencentCloud) create(domain *config.Domain, recordType string, ipAddr string) {
+func (tc *TencentCloud) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
 	record := &TencentCloudRecord{
 		Domain:     domain.DomainName,
 		SubDomain:  domain.GetSubDomain(),
@@ -137,63 +142,57 @@ func (tc *TencentCloud) create(domain *config.Domain, recordType string, ipAddr
 		Value:      ipAddr,
 		TTL:        tc.TTL,
 	}
-
-	var status TencentCloudStatus
-	err := tc.request(
-		"CreateRecord",
-		record,
-		&status,
-	)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if status.Response.Error.Code == "" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, status.Response.Error.Message)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return tc.write("CreateRecord", record)
 }
 
-// modify 修改记录
+// UpdateRecord 修改记录
 // ModifyRecord https://cloud.tencent.com/document/api/1427/56157
-func (tc *TencentCloud) modify(record TencentCloudRecord, domain *config.Domain, recordType string, ipAddr string) {
-	// 相同不修改
-	if record.Value == ipAddr {
-		util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-		return
-	}
-	var status TencentCloudStatus
+func (tc *TencentCloud) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	record := r.Raw.(TencentCloudRecord)
 	record.Domain = domain.DomainName
 	record.SubDomain = domain.GetSubDomain()
-	record.RecordType = recordType
+	record.RecordType = r.Type
 	record.RecordLine = tc.getRecordLine(domain)
 	record.Value = ipAddr
 	record.TTL = tc.TTL
-	err := tc.request(
-		"ModifyRecord",
-		record,
-		&status,
-	)
+	return tc.write("ModifyRecord", record)
+}
 
-	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
+// DeleteRecord 删除记录
+// DeleteRecord https://cloud.tencent.com/document/api/1427/56176
+func (tc *TencentCloud) DeleteRecord(domain *config.Domain, r Record) error {
+	record := r.Raw.(TencentCloudRecord)
+	return tc.write("DeleteRecord", struct {
+		Domain   string `json:"Domain"`
+		RecordId int64  `json:"RecordId"`
+	}{
+		Domain:   domain.DomainName,
+		RecordId: record.RecordId,
+	})
+}
 
-	if status.Response.Error.Code == "" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, status.Response.Error.Message)
-		domain.UpdateStatus = config.UpdatedFailed
+// write 新增/修改/删除, 返回错误码时视为失败
+func (tc *TencentCloud) write(action string, data interface{}) error {
+	return tc.request(action, data, nil)
+}
+
+// tencentCloudError 根据错误码返回带类型的错误
+// https://cloud.tencent.com/document/api/1427/56192
+func tencentCloudError(code, message string) error {
+	err := errors.New(message)
+	switch {
+	case strings.HasPrefix(code, "AuthFailure"), strings.HasPrefix(code, "UnauthorizedOperation"):
+		return util.NewAPIError(util.ErrorAuth, err)
+	case strings.HasPrefix(code, "RequestLimitExceeded"), strings.HasPrefix(code, "LimitExceeded"):
+		return util.NewAPIError(util.ErrorRateLimited, err)
+	case strings.HasPrefix(code, "InternalError"), strings.HasPrefix(code, "ResourceUnavailable"):
+		return util.NewAPIError(util.ErrorTransient, err)
+	case strings.HasPrefix(code, "ResourceNotFound"):
+		return util.NewAPIError(util.ErrorNotFound, err)
+	case strings.HasPrefix(code, "InvalidParameter"), strings.HasPrefix(code, "MissingParameter"):
+		return util.NewAPIError(util.ErrorValidation, err)
 	}
+	return err
 }
 
 // getRecordList 获取域名的解析记录列表
@@ -228,7 +227,8 @@ func (tc *TencentCloud) request(action string, data interface{}, result interfac
 	if data != nil {
 		jsonStr, _ = json.Marshal(data)
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		tc.ctx,
 		"POST",
 		tencentCloudEndPoint,
 		bytes.NewBuffer(jsonStr),
@@ -244,7 +244,24 @@ func (tc *TencentCloud) request(action string, data interface{}, result interfac
 
 	client := tc.httpClient
 	resp, err := client.Do(req)
-	err = util.GetHTTPResponse(resp, err, result)
+	body, err := util.GetHTTPResponseOrg(resp, err)
+	if err != nil {
+		return
+	}
 
+	var status TencentCloudStatus
+	if err = json.Unmarshal(body, &status); err != nil {
+		return
+	}
+	if code := status.Response.Error.Code; code != "" {
+		// 没有记录时也会返回错误码, 视为空结果
+		if action == "DescribeRecordList" && code == "ResourceNotFound.NoDataOfRecord" {
+			return nil
+		}
+		return tencentCloudError(code, status.Response.Error.Message)
+	}
+	if result != nil {
+		err = json.Unmarshal(body, result)
+	}
 	return
 }
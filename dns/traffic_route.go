@@ -1,7 +1,9 @@
 package dns
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -15,6 +17,9 @@ type TrafficRoute struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
+	// zoneIDs 域名对应的 ZID 缓存
+	zoneIDs map[string]int
 }
 
 // TrafficRouteMeta 解析记录
@@ -70,11 +75,31 @@ type TrafficRouteListZonesResp struct {
 	ZID int `json:"ZID"` // 域名ID
 }
 
-func (tr *TrafficRoute) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "trafficroute",
+		Order: 15,
+		Name: map[string]string{
+			"en":    "TrafficRoute",
+			"zh-cn": "火山引擎",
+		},
+		IDLabel:     "AccessKey",
+		SecretLabel: "SecretAccessKey",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://console.volcengine.com/iam/keymanage/'>Create AccessKey</a>",
+			"zh-cn": "<a target='_blank' href='https://console.volcengine.com/iam/keymanage/'>创建火山引擎 API 密钥</a>",
+		},
+		DefaultTTL: 600,
+		New:        func() DNS { return &TrafficRoute{} },
+	})
+}
+
+func (tr *TrafficRoute) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	tr.ctx = ctx
 	tr.Domains.Ipv4Cache = ipv4cache
 	tr.Domains.Ipv6Cache = ipv6cache
 	tr.DNS = dnsConf.DNS
-	tr.Domains.GetNewIp(dnsConf)
+	tr.Domains.GetNewIp(ctx, dnsConf)
 	if dnsConf.TTL == "" {
 		tr.TTL = 600
 	} else {
@@ -90,54 +115,49 @@ func (tr *TrafficRoute) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache,
 
 // AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
 func (tr *TrafficRoute) AddUpdateDomainRecords() config.Domains {
-	tr.addUpdateDomainRecords("A")
-	tr.addUpdateDomainRecords("AAAA")
+	Reconcile(tr.ctx, tr, tr.Domains.GetAllNewIpResult("A/AAAA"))
 	return tr.Domains
 }
 
-func (tr *TrafficRoute) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := tr.Domains.GetNewIpResult(recordType)
-	if ipAddr == "" {
+// ListRecords 查询解析记录
+func (tr *TrafficRoute) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
+	zoneID, err := tr.getZID(domain)
+	if err != nil {
 		return
 	}
 
-	for _, domain := range domains {
-		resp := TrafficRouteListZonesResp{}
-		tr.getZID(domain, &resp)
-		zoneID := resp.ZID
-
-		var recordResp TrafficRouteResp
-		tr.request(
-			"GET",
-			"ListRecords",
-			map[string][]string{
-				"ZID":        {strconv.Itoa(zoneID)},
-				"Type":       {recordType},
-				"Host":       {domain.GetSubDomain()},
-				"SearchMode": {"exact"},
-				"PageNumber": {"1"},
-				"PageSize":   {"500"},
-			},
-			&recordResp,
-		)
-
-		found := false
-		for _, record := range recordResp.Result.Records {
-			if record.Type == recordType && record.Host == domain.GetSubDomain() {
-				tr.modify(record, domain, ipAddr)
-				found = true
-				break
-			}
-		}
+	var recordResp TrafficRouteResp
+	err = tr.request(
+		"GET",
+		"ListRecords",
+		map[string][]string{
+			"ZID":        {strconv.Itoa(zoneID)},
+			"Type":       {recordType},
+			"Host":       {domain.GetSubDomain()},
+			"SearchMode": {"exact"},
+			"PageNumber": {"1"},
+			"PageSize":   {"500"},
+		},
+		&recordResp,
+	)
+	if err != nil {
+		return
+	}
 
-		if !found {
-			tr.create(zoneID, domain, recordType, ipAddr)
+	for _, record := range recordResp.Result.Records {
+		if record.Type == recordType && record.Host == domain.GetSubDomain() {
+			records = append(records, Record{ID: record.RecordID, Type: record.Type, Value: record.Value, Raw: record})
 		}
 	}
+	return
 }
 
 // getZID 获取域名的ZID
-func (tr *TrafficRoute) getZID(domain *config.Domain, resp *TrafficRouteListZonesResp) {
+func (tr *TrafficRoute) getZID(domain *config.Domain) (int, error) {
+	if zoneID, ok := tr.zoneIDs[domain.DomainName]; ok {
+		return zoneID, nil
+	}
+
 	var result TrafficRouteResp
 	err := tr.request(
 		"GET",
@@ -145,29 +165,29 @@ func (tr *TrafficRoute) getZID(domain *config.Domain, resp *TrafficRouteListZone
 		map[string][]string{"Key": {domain.DomainName}},
 		&result,
 	)
-
 	if err != nil {
-		util.Log("查询域名信息发生异常! %s", err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if len(result.Result.Zones) == 0 {
-		util.Log("在DNS服务商中未找到域名: %s", domain.DomainName)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return 0, err
 	}
 
 	for _, zone := range result.Result.Zones {
 		if zone.ZoneName == domain.DomainName {
-			resp.ZID = zone.ZID
-			return
+			if tr.zoneIDs == nil {
+				tr.zoneIDs = map[string]int{}
+			}
+			tr.zoneIDs[domain.DomainName] = zone.ZID
+			return zone.ZID, nil
 		}
 	}
+	return 0, errors.New(util.LogStr("在DNS服务商中未找到域名: %s", domain.DomainName))
 }
 
-// create 添加解析记录
-func (tr *TrafficRoute) create(zoneID int, domain *config.Domain, recordType, ipAddr string) {
+// CreateRecord 添加解析记录
+func (tr *TrafficRoute) CreateRecord(domain *config.Domain, recordType string, ipAddr string) error {
+	zoneID, err := tr.getZID(domain)
+	if err != nil {
+		return err
+	}
+
 	record := &TrafficRouteMeta{
 		ZID:   zoneID,
 		Host:  domain.GetSubDomain(),
@@ -176,62 +196,35 @@ func (tr *TrafficRoute) create(zoneID int, domain *config.Domain, recordType, ip
 		TTL:   tr.TTL,
 		Line:  "default",
 	}
-
-	var result TrafficRouteResp
-	err := tr.request(
-		"POST",
-		"CreateRecord",
-		record,
-		&result,
-	)
-
-	if err != nil {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
-	}
-
-	if result.ResponseMetadata.Error.Code == "" {
-		util.Log("新增域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("新增域名解析 %s 失败! 异常信息: %s", domain, result.ResponseMetadata.Error.Message)
-		domain.UpdateStatus = config.UpdatedFailed
-	}
+	return tr.write("CreateRecord", record)
 }
 
-// modify 修改解析记录
-func (tr *TrafficRoute) modify(record TrafficRouteMeta, domain *config.Domain, ipAddr string) {
-	if record.Value == ipAddr {
-		util.Log("IP %s 没有变化，域名 %s", ipAddr, domain)
-		domain.UpdateStatus = config.UpdatedNothing
-		return
-	}
-
+// UpdateRecord 修改解析记录
+func (tr *TrafficRoute) UpdateRecord(domain *config.Domain, r Record, ipAddr string) error {
+	record := r.Raw.(TrafficRouteMeta)
 	record.Value = ipAddr
 	record.TTL = tr.TTL
+	return tr.write("UpdateRecord", &record)
+}
 
-	var result TrafficRouteResp
-	err := tr.request(
-		"POST",
-		"UpdateRecord",
-		record,
-		&result,
-	)
+// DeleteRecord 删除解析记录
+func (tr *TrafficRoute) DeleteRecord(domain *config.Domain, r Record) error {
+	return tr.write("DeleteRecord", struct {
+		RecordID string `json:"RecordID"`
+	}{RecordID: r.ID})
+}
 
+// write 新增/修改/删除, 返回错误码时视为失败
+func (tr *TrafficRoute) write(action string, data interface{}) error {
+	var result TrafficRouteResp
+	err := tr.request("POST", action, data, &result)
 	if err != nil {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, err)
-		domain.UpdateStatus = config.UpdatedFailed
-		return
+		return err
 	}
-
-	if result.ResponseMetadata.Error.Code == "" {
-		util.Log("更新域名解析 %s 成功! IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		util.Log("更新域名解析 %s 失败! 异常信息: %s", domain, result.ResponseMetadata.Error.Message)
-		domain.UpdateStatus = config.UpdatedFailed
+	if result.ResponseMetadata.Error.Code != "" {
+		return errors.New(result.ResponseMetadata.Error.Message)
 	}
+	return nil
 }
 
 // parseRequestParams 解析请求参数
@@ -284,6 +277,7 @@ func (tr *TrafficRoute) request(method string, action string, data interface{},
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(tr.ctx)
 
 	client := tr.httpClient
 	resp, err := client.Do(req)
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -17,13 +18,14 @@ type Vercel struct {
 	Domains    config.Domains
 	TTL        int
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 type ListExistingRecordsResponse struct {
-	Records []Record `json:"records"`
+	Records []VercelRecord `json:"records"`
 }
 
-type Record struct {
+type VercelRecord struct {
 	ID        string  `json:"id"` // 记录ID
 	Slug      string  `json:"slug"`
 	Name      string  `json:"name"`  // 记录名称
@@ -38,11 +40,35 @@ type Record struct {
 	Comment   *string `json:"comment,omitempty"`
 }
 
-func (v *Vercel) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+func init() {
+	Register(Provider{
+		Key:   "vercel",
+		Order: 13,
+		Name: map[string]string{
+			"en": "Vercel",
+		},
+		IDLabel:     "",
+		SecretLabel: "Token",
+		HelpHTML: map[string]string{
+			"en":    "<a target='_blank' href='https://vercel.com/account/tokens'>Create Token</a>",
+			"zh-cn": "<a target='_blank' href='https://vercel.com/account/tokens'>创建令牌</a>",
+		},
+		ExtParamLabel: "Team ID",
+		ExtParamHelpHTML: map[string]string{
+			"en":    "Optional. If you are using a Vercel Team account, please fill in the Team ID",
+			"zh-cn": "可选项，如果您使用的是 Vercel 团队账户，请填写团队 ID",
+		},
+		DefaultTTL: 60,
+		New:        func() DNS { return &Vercel{} },
+	})
+}
+
+func (v *Vercel) Init(ctx context.Context, dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6cache *util.IpCache) {
+	v.ctx = ctx
 	v.Domains.Ipv4Cache = ipv4cache
 	v.Domains.Ipv6Cache = ipv6cache
 	v.DNS = dnsConf.DNS
-	v.Domains.GetNewIp(dnsConf)
+	v.Domains.GetNewIp(ctx, dnsConf)
 
 	// Must be greater than 60
 	ttl, err := strconv.Atoi(dnsConf.TTL)
@@ -57,76 +83,27 @@ func (v *Vercel) Init(dnsConf *config.DnsConfig, ipv4cache *util.IpCache, ipv6ca
 }
 
 func (v *Vercel) AddUpdateDomainRecords() (domains config.Domains) {
-	v.addUpdateDomainRecords("A")
-	v.addUpdateDomainRecords("AAAA")
+	Reconcile(v.ctx, v, v.Domains.GetAllNewIpResult("A/AAAA"))
 	return v.Domains
 }
 
-func (v *Vercel) addUpdateDomainRecords(recordType string) {
-	ipAddr, domains := v.Domains.GetNewIpResult(recordType)
-
-	if ipAddr == "" {
-		return
-	}
-
-	ipAddr = strings.ToLower(ipAddr)
-
-	var (
-		records []Record
-		err     error
-	)
-	for _, domain := range domains {
-		records, err = v.listExistingRecords(domain)
-		if err != nil {
-			util.Log("查询域名信息发生异常! %s", err)
-			continue
-		}
-
-		var targetRecord *Record
-		for _, record := range records {
-			if record.Name == domain.SubDomain {
-				targetRecord = &record
-				break
-			}
-		}
-
-		if targetRecord == nil {
-			err = v.createRecord(domain, recordType, ipAddr)
-		} else {
-			if strings.ToLower(targetRecord.Value) == ipAddr {
-				util.Log("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-				domain.UpdateStatus = config.UpdatedNothing
-				continue
-			} else {
-				err = v.updateRecord(targetRecord, recordType, ipAddr)
-			}
-		}
-
-		operation := "新增"
-		if targetRecord != nil {
-			operation = "更新"
-		}
-		if err == nil {
-			util.Log(operation+"域名解析 %s 成功! IP: %s", domain, ipAddr)
-			domain.UpdateStatus = config.UpdatedSuccess
-		} else {
-			util.Log(operation+"域名解析 %s 失败! 异常信息: %s", domain, err)
-			domain.UpdateStatus = config.UpdatedFailed
-		}
-	}
-}
-
-func (v *Vercel) listExistingRecords(domain *config.Domain) (records []Record, err error) {
+// ListRecords 查询记录
+func (v *Vercel) ListRecords(domain *config.Domain, recordType string) (records []Record, err error) {
 	var result ListExistingRecordsResponse
 	err = v.request(http.MethodGet, "https://api.vercel.com/v4/domains/"+domain.DomainName+"/records", nil, &result)
 	if err != nil {
 		return
 	}
-	records = result.Records
+	for _, r := range result.Records {
+		if r.Name == domain.SubDomain && r.Type == recordType {
+			records = append(records, Record{ID: r.ID, Type: r.Type, Value: r.Value})
+		}
+	}
 	return
 }
 
-func (v *Vercel) createRecord(domain *config.Domain, recordType string, recordValue string) (err error) {
+// CreateRecord 创建记录
+func (v *Vercel) CreateRecord(domain *config.Domain, recordType string, recordValue string) (err error) {
 	err = v.request(http.MethodPost, "https://api.vercel.com/v2/domains/"+domain.DomainName+"/records", map[string]interface{}{
 		"name":    domain.SubDomain,
 		"type":    recordType,
@@ -137,15 +114,22 @@ func (v *Vercel) createRecord(domain *config.Domain, recordType string, recordVa
 	return
 }
 
-func (v *Vercel) updateRecord(record *Record, recordType string, recordValue string) (err error) {
+// UpdateRecord 更新记录
+func (v *Vercel) UpdateRecord(domain *config.Domain, record Record, recordValue string) (err error) {
 	err = v.request(http.MethodPatch, "https://api.vercel.com/v1/domains/records/"+record.ID, map[string]interface{}{
-		"type":  recordType,
+		"type":  record.Type,
 		"value": recordValue,
 		"ttl":   v.TTL,
 	}, nil)
 	return
 }
 
+// DeleteRecord 删除记录
+func (v *Vercel) DeleteRecord(domain *config.Domain, record Record) (err error) {
+	err = v.request(http.MethodDelete, "https://api.vercel.com/v2/domains/"+domain.DomainName+"/records/"+record.ID, nil, nil)
+	return
+}
+
 func (v *Vercel) request(method, api string, data, result interface{}) (err error) {
 	var payload []byte
 	if data != nil {
@@ -161,7 +145,8 @@ func (v *Vercel) request(method, api string, data, result interface{}) (err erro
 		}
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		v.ctx,
 		method,
 		api,
 		bytes.NewBuffer(payload),
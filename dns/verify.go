@@ -0,0 +1,136 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+var (
+	// verifyInterval 两次查询权威DNS的间隔
+	verifyInterval = 5 * time.Second
+	// verifyTimeout 等待记录在权威DNS中生效的最长时间
+	verifyTimeout = time.Minute
+
+	// newAuthoritativeDNS 可在测试中替换
+	newAuthoritativeDNS = util.NewAuthoritativeDNS
+)
+
+// verifyTarget 待验证的域名
+type verifyTarget struct {
+	domain  *config.Domain
+	network string
+	values  []string
+}
+
+// verifyPublished 直接查询权威DNS, 等待更新成功的域名生效, 超时则标记为未生效
+// ctx 为配置的超时 ctx, 等待时间不超过配置的超时时间
+func verifyPublished(ctx context.Context, dc *config.DnsConfig, domains *config.Domains) {
+	if !dc.VerifyPublish || isDryRun(ctx) || ctx.Err() != nil {
+		return
+	}
+
+	var pending []*verifyTarget
+	add := func(ds []*config.Domain, network string, values []string) {
+		for _, d := range ds {
+			if d.UpdateStatus != config.UpdatedSuccess || len(values) == 0 {
+				continue
+			}
+			// 开启代理的域名解析到服务商的地址, 无法验证
+			if isProxied(d) {
+				util.Log("域名 %s 已开启代理, 跳过权威DNS验证", d)
+				continue
+			}
+			pending = append(pending, &verifyTarget{domain: d, network: network, values: d.HostAddrs(values)})
+		}
+	}
+	add(domains.Ipv4Domains, "ip4", expectedAddrs(domains.Ipv4Addr, domains.Ipv4Addrs))
+	add(domains.Ipv6Domains, "ip6", expectedAddrs(domains.Ipv6Addr, domains.Ipv6Addrs))
+	if len(pending) == 0 {
+		return
+	}
+
+	// 同一区域的权威DNS只查询一次
+	auth := newAuthoritativeDNS()
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	for {
+		var remaining []*verifyTarget
+		for _, t := range pending {
+			if t.published(ctx, auth) {
+				t.domain.PublishStatus = config.Published
+				util.Log("域名 %s 的记录已在权威DNS中生效", t.domain)
+				continue
+			}
+			remaining = append(remaining, t)
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			// 停止或重新加载配置时不标记
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			for _, t := range pending {
+				t.domain.PublishStatus = config.NotPropagated
+				util.Log("域名 %s 的记录在 %s 内未在权威DNS中生效", t.domain, time.Since(start).Round(time.Second))
+			}
+			return
+		case <-time.After(verifyInterval):
+		}
+	}
+}
+
+// published 响应的权威DNS都已返回期望的地址
+func (t *verifyTarget) published(ctx context.Context, auth *util.AuthoritativeDNS) bool {
+	name := t.domain.LookupName()
+	servers, err := auth.Servers(ctx, name)
+	if err != nil {
+		util.Log("查询域名 %s 的权威DNS失败! 异常信息: %s", t.domain, err)
+		return false
+	}
+
+	answered := 0
+	for _, server := range servers {
+		ips, err := auth.LookupIP(ctx, server, t.network, name)
+		if err != nil {
+			// 无法连接的权威DNS不计入
+			continue
+		}
+		answered++
+		if !util.ContainsAddrs(ips, t.values) {
+			return false
+		}
+	}
+	return answered > 0
+}
+
+// isProxied 域名是否通过自定义参数开启了代理, 如 Cloudflare 的 proxied=true
+func isProxied(d *config.Domain) bool {
+	for key, values := range d.GetCustomParams() {
+		if strings.EqualFold(key, "proxied") && len(values) > 0 && values[0] != "false" {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedAddrs 期望的地址, 启用多地址时为全部地址
+func expectedAddrs(ipAddr string, ipAddrs []string) []string {
+	if len(ipAddrs) > 0 {
+		return ipAddrs
+	}
+	if ipAddr == "" {
+		return nil
+	}
+	return []string{ipAddr}
+}
@@ -0,0 +1,96 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/util"
+)
+
+func TestVerifyPublished(t *testing.T) {
+	verifyInterval, verifyTimeout = 10*time.Millisecond, 100*time.Millisecond
+	t.Cleanup(func() {
+		verifyInterval, verifyTimeout = 5*time.Second, time.Minute
+		newAuthoritativeDNS = util.NewAuthoritativeDNS
+	})
+
+	// ns1 第二次查询时才返回新地址, ns2 不可用
+	queries := map[string]int{}
+	lookupNS := func(ctx context.Context, name string) (string, []string, error) {
+		return "example.com", []string{"ns1.example.net", "ns2.example.net"}, nil
+	}
+	lookupIPAt := func(ctx context.Context, server, network, name string) ([]net.IP, error) {
+		if server == "ns2.example.net" {
+			return nil, &net.DNSError{Err: "i/o timeout", IsTimeout: true}
+		}
+		queries[name]++
+		switch name {
+		case "a.example.com":
+			if queries[name] > 1 {
+				return []net.IP{net.ParseIP("192.0.2.2")}, nil
+			}
+			return []net.IP{net.ParseIP("192.0.2.1")}, nil
+		case "ddns-go-check.example.com":
+			return []net.IP{net.ParseIP("2001:db8::1")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", IsNotFound: true}
+	}
+	newAuthoritativeDNS = func() *util.AuthoritativeDNS {
+		return &util.AuthoritativeDNS{LookupNS: lookupNS, LookupIPAt: lookupIPAt}
+	}
+
+	published := &config.Domain{DomainName: "example.com", SubDomain: "a", UpdateStatus: config.UpdatedSuccess}
+	missing := &config.Domain{DomainName: "example.com", SubDomain: "b", UpdateStatus: config.UpdatedSuccess}
+	unchanged := &config.Domain{DomainName: "example.com", SubDomain: "c"}
+	proxied := &config.Domain{DomainName: "example.com", SubDomain: "d", CustomParams: "proxied=true", UpdateStatus: config.UpdatedSuccess}
+	wildcard := &config.Domain{DomainName: "example.com", SubDomain: "*", UpdateStatus: config.UpdatedSuccess}
+	domains := &config.Domains{
+		Ipv4Addr:    "192.0.2.2",
+		Ipv4Domains: []*config.Domain{published, missing, unchanged, proxied},
+		Ipv6Addr:    "2001:db8:0::1",
+		Ipv6Domains: []*config.Domain{wildcard},
+	}
+
+	// 未开启时不验证
+	verifyPublished(context.Background(), &config.DnsConfig{}, domains)
+	if len(queries) != 0 {
+		t.Fatalf("queries = %v, want none when disabled", queries)
+	}
+
+	verifyPublished(context.Background(), &config.DnsConfig{VerifyPublish: true}, domains)
+	tests := []struct {
+		domain *config.Domain
+		want   string
+	}{
+		{published, string(config.Published)},
+		{missing, string(config.NotPropagated)},
+		{unchanged, ""},
+		{proxied, ""},
+		{wildcard, string(config.Published)},
+	}
+	for _, tt := range tests {
+		if string(tt.domain.PublishStatus) != tt.want {
+			t.Errorf("%s PublishStatus = %q, want %q", tt.domain, tt.domain.PublishStatus, tt.want)
+		}
+	}
+	if queries["c.example.com"] != 0 || queries["d.example.com"] != 0 {
+		t.Error("unchanged and proxied domains should not be verified")
+	}
+
+	// 超时 ctx 先于 verifyTimeout 到期时提前结束
+	missing.PublishStatus = ""
+	domains.Ipv4Domains, domains.Ipv6Domains = []*config.Domain{missing}, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	verifyPublished(ctx, &config.DnsConfig{VerifyPublish: true}, domains)
+	if elapsed := time.Since(start); elapsed >= verifyTimeout {
+		t.Errorf("verify took %s, want bounded by the config timeout", elapsed)
+	}
+	if missing.PublishStatus != config.NotPropagated {
+		t.Errorf("%s PublishStatus = %q, want %q", missing, missing.PublishStatus, config.NotPropagated)
+	}
+}
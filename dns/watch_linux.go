@@ -0,0 +1,109 @@
+//go:build linux
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchInterfaces 通过 rtnetlink 监听网卡和地址变化, 返回发生变化的网卡名称, ctx 取消后关闭
+func watchInterfaces(ctx context.Context) (<-chan string, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	sa := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	}
+	if err := unix.Bind(fd, sa); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	// 非阻塞的 fd 交由 runtime 轮询, Close 时会中断进行中的 Read
+	f := os.NewFile(uintptr(fd), "netlink")
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	changes := make(chan string, 16)
+	go func() {
+		defer close(changes)
+		buf := make([]byte, os.Getpagesize()*4)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, name := range interfaceEvents(msgs) {
+				select {
+				case changes <- name:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// interfaceEvents 解析 netlink 消息, 返回发生变化的网卡名称
+func interfaceEvents(msgs []syscall.NetlinkMessage) (names []string) {
+	for i := range msgs {
+		m := &msgs[i]
+		switch m.Header.Type {
+		case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+			if len(m.Data) < unix.SizeofIfInfomsg {
+				continue
+			}
+			// 删除的网卡无法通过索引查询名称, 优先使用消息中的 IFLA_IFNAME
+			if name := linkName(m); name != "" {
+				names = append(names, name)
+				continue
+			}
+		case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+			if len(m.Data) < unix.SizeofIfAddrmsg {
+				continue
+			}
+		default:
+			continue
+		}
+		// ifinfomsg 和 ifaddrmsg 的网卡索引都位于第 4 个字节
+		index := int(binary.NativeEndian.Uint32(m.Data[4:8]))
+		if iface, err := net.InterfaceByIndex(index); err == nil {
+			names = append(names, iface.Name)
+		}
+	}
+	return
+}
+
+func linkName(m *syscall.NetlinkMessage) string {
+	attrs, err := syscall.ParseNetlinkRouteAttr(m)
+	if err != nil {
+		return ""
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type == unix.IFLA_IFNAME {
+			// 以 \0 结尾
+			for i, b := range attr.Value {
+				if b == 0 {
+					return string(attr.Value[:i])
+				}
+			}
+			return string(attr.Value)
+		}
+	}
+	return ""
+}
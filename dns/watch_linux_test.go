@@ -0,0 +1,69 @@
+//go:build linux
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestInterfaceEvents(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface")
+	}
+
+	addr := make([]byte, unix.SizeofIfAddrmsg)
+	binary.NativeEndian.PutUint32(addr[4:8], uint32(lo.Index))
+
+	// 已删除的网卡, 名称来自 IFLA_IFNAME
+	link := make([]byte, unix.SizeofIfInfomsg, unix.SizeofIfInfomsg+12)
+	binary.NativeEndian.PutUint32(link[4:8], 1<<30)
+	attr := make([]byte, 4)
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(4+len("ppp0")+1))
+	binary.NativeEndian.PutUint16(attr[2:4], unix.IFLA_IFNAME)
+	link = append(link, attr...)
+	link = append(link, "ppp0\x00\x00\x00\x00"...)
+
+	msgs := []syscall.NetlinkMessage{
+		{Header: syscall.NlMsghdr{Type: unix.RTM_NEWADDR}, Data: addr},
+		{Header: syscall.NlMsghdr{Type: unix.RTM_DELLINK}, Data: link},
+		{Header: syscall.NlMsghdr{Type: unix.RTM_NEWROUTE}, Data: addr},
+		{Header: syscall.NlMsghdr{Type: unix.RTM_DELADDR}, Data: addr[:4]},
+	}
+	got := interfaceEvents(msgs)
+	want := []string{lo.Name, "ppp0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("interfaceEvents() = %q, want %q", got, want)
+	}
+}
+
+func TestWatchInterfacesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := watchInterfaces(ctx)
+	if err != nil {
+		cancel()
+		t.Skipf("netlink unavailable: %s", err)
+	}
+	cancel()
+
+	// 取消后应关闭 channel
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("changes not closed after cancel")
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package dns
+
+import "context"
+
+// watchInterfaces 仅支持 Linux, 其他系统只定时检测
+func watchInterfaces(ctx context.Context) (<-chan string, error) {
+	return nil, nil
+}
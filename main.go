@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"flag"
@@ -10,9 +11,11 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/jeessy2/ddns-go/v6/config"
@@ -40,6 +43,12 @@ var every = flag.Int("f", 300, "Update frequency(seconds)")
 // 缓存次数
 var ipCacheTimes = flag.Int("cacheTimes", 5, "Cache times")
 
+// 同时更新的配置数量
+var workers = flag.Int("workers", 4, "Max number of configs updated concurrently")
+
+// 只查询并打印计划, 不修改任何记录
+var dryRun = flag.Bool("dryRun", false, "Print planned record changes without applying them")
+
 // 服务管理
 var serviceType = flag.String("s", "", "Service management (install|uninstall|restart)")
 
@@ -122,6 +131,11 @@ func main() {
 		util.SetDNS(*customDNS)
 	}
 	os.Setenv(util.IPCacheTimesENV, strconv.Itoa(*ipCacheTimes))
+	dns.Workers = *workers
+	if *dryRun {
+		runDryRun()
+		return
+	}
 	switch *serviceType {
 	case "install":
 		installService()
@@ -131,7 +145,7 @@ func main() {
 		restartService()
 	default:
 		if util.IsRunInDocker() || os.Getenv("DDNS_GO_DAEMON") == "1" {
-			run()
+			runUntilSignal()
 		} else {
 			s := getService()
 			status, _ := s.Status()
@@ -146,13 +160,13 @@ func main() {
 				default:
 					util.Log("可使用 sudo ./ddns-go -s install 安装服务运行")
 				}
-				run()
+				runUntilSignal()
 			}
 		}
 	}
 }
 
-func run() {
+func run(ctx context.Context) {
 	// 兼容之前的配置文件
 	conf, _ := config.GetConfigCached()
 	conf.CompatibleConfig()
@@ -175,10 +189,50 @@ func run() {
 	util.InitBackupDNS(*customDNS, conf.Lang)
 
 	// 等待网络连接
-	util.WaitInternet(dns.Addresses)
+	util.WaitInternet(ctx, dns.Addresses())
+
+	// 恢复上次运行的缓存
+	dns.LoadState()
 
 	// 定时运行
-	dns.RunTimer(time.Duration(*every) * time.Second)
+	dns.RunTimer(ctx, time.Duration(*every)*time.Second)
+}
+
+// runUntilSignal 运行直到收到 SIGINT/SIGTERM, 收到后取消进行中的更新
+func runUntilSignal() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		util.Log("收到退出信号, 正在停止...")
+	}()
+	run(ctx)
+}
+
+// runDryRun 获取IP并查询DNS服务商, 打印每个域名的计划后退出
+func runDryRun() {
+	conf, err := config.GetConfigCached()
+	if err != nil {
+		util.Log("配置文件 %s 不存在, 可通过-c指定配置文件", *configFilePath)
+		return
+	}
+	// 只在内存中兼容旧的配置, dry-run 不修改配置文件
+	conf.CompatibleConfigInMemory()
+	util.InitLogLang(conf.Lang)
+	util.InitBackupDNS(*customDNS, conf.Lang)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 从状态文件恢复地址的稳定状态和最后确认的记录
+	dns.LoadState()
+	entries, err := dns.DryRun(ctx)
+	if err != nil {
+		util.Log("dry-run 失败! %s", err)
+		return
+	}
+	util.Log("dry-run 完成, 共 %d 条记录, 未修改任何记录", len(entries))
 }
 
 func staticFsFunc(writer http.ResponseWriter, request *http.Request) {
@@ -199,6 +253,8 @@ func runWebServer() error {
 	http.HandleFunc("/", web.Auth(web.Writing))
 	http.HandleFunc("/save", web.Auth(web.Save))
 	http.HandleFunc("/setLang", web.Auth(web.SetLang))
+	http.HandleFunc("/providers", web.Auth(web.Providers))
+	http.HandleFunc("/dryRun", web.Auth(web.DryRun))
 	http.HandleFunc("/logs", web.Auth(web.Logs))
 	http.HandleFunc("/clearLog", web.Auth(web.ClearLog))
 	http.HandleFunc("/webhookTest", web.Auth(web.WebhookTest))
@@ -246,18 +302,34 @@ func runAsDaemon() error {
 	return proc.Release()
 }
 
-type program struct{}
+type program struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
 
 func (p *program) Start(s service.Service) error {
 	// Start should not block. Do the actual work async.
-	go p.run()
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx)
 	return nil
 }
-func (p *program) run() {
-	run()
+func (p *program) run(ctx context.Context) {
+	defer close(p.done)
+	run(ctx)
 }
 func (p *program) Stop(s service.Service) error {
 	// Stop should not block. Return with a few seconds.
+	if p.cancel == nil {
+		return nil
+	}
+	util.Log("收到退出信号, 正在停止...")
+	p.cancel()
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+	}
 	return nil
 }
 
@@ -282,7 +354,7 @@ func getService() service.Service {
 		Name:         "ddns-go",
 		DisplayName:  "ddns-go",
 		Description:  "Simple and easy to use DDNS. Automatically update domain name resolution to public IP (Support Aliyun, Tencent Cloud, Dnspod, Cloudflare, Callback, Huawei Cloud, Baidu Cloud, Porkbun, GoDaddy...)",
-		Arguments:    []string{"-l", *listen, "-f", strconv.Itoa(*every), "-cacheTimes", strconv.Itoa(*ipCacheTimes), "-c", *configFilePath},
+		Arguments:    []string{"-l", *listen, "-f", strconv.Itoa(*every), "-cacheTimes", strconv.Itoa(*ipCacheTimes), "-workers", strconv.Itoa(*workers), "-c", *configFilePath},
 		Dependencies: depends,
 		Option:       options,
 	}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrorKind 错误类型, 用于判断是否重试
+type ErrorKind string
+
+const (
+	// ErrorAuth 认证失败, 如 401/403
+	ErrorAuth ErrorKind = "auth"
+	// ErrorRateLimited 请求过于频繁, 如 429
+	ErrorRateLimited ErrorKind = "rate_limited"
+	// ErrorNotFound 资源不存在, 如 404
+	ErrorNotFound ErrorKind = "not_found"
+	// ErrorTransient 网络异常或服务端错误, 可重试
+	ErrorTransient ErrorKind = "transient"
+	// ErrorValidation 请求参数错误, 如 400/422
+	ErrorValidation ErrorKind = "validation"
+)
+
+var errorKindNames = map[ErrorKind]string{
+	ErrorAuth:        "认证失败",
+	ErrorRateLimited: "请求过于频繁",
+	ErrorNotFound:    "资源不存在",
+	ErrorTransient:   "临时错误",
+	ErrorValidation:  "请求参数错误",
+}
+
+// Retryable 是否可以重试
+func (k ErrorKind) Retryable() bool {
+	return k == ErrorRateLimited || k == ErrorTransient
+}
+
+// Permanent 重试也不会成功的错误
+func (k ErrorKind) Permanent() bool {
+	return k == ErrorAuth || k == ErrorNotFound || k == ErrorValidation
+}
+
+// APIError 带类型的请求错误
+type APIError struct {
+	Kind ErrorKind
+	// StatusCode HTTP状态码, 非HTTP错误时为 0
+	StatusCode int
+	// RetryAfter 服务端要求的等待时间, 来自 Retry-After
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return LogStr(errorKindNames[e.Kind]) + ": " + e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// NewAPIError 返回指定类型的错误, 供服务商根据返回的错误码分类
+func NewAPIError(kind ErrorKind, err error) error {
+	return &APIError{Kind: kind, Err: err}
+}
+
+// ErrorKindOf 返回错误类型, 未分类的错误返回空
+func ErrorKindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return ""
+}
+
+// statusError 根据HTTP状态码返回带类型的错误
+func statusError(resp *http.Response, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Err:        fmt.Errorf("%s", LogStr("返回内容: %s ,返回状态码: %d", string(body), resp.StatusCode)),
+	}
+	switch code := resp.StatusCode; {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		apiErr.Kind = ErrorAuth
+	case code == http.StatusNotFound || code == http.StatusGone:
+		apiErr.Kind = ErrorNotFound
+	case code == http.StatusTooManyRequests:
+		apiErr.Kind = ErrorRateLimited
+	case code == http.StatusRequestTimeout || code >= 500:
+		apiErr.Kind = ErrorTransient
+	case code >= 400:
+		apiErr.Kind = ErrorValidation
+	default:
+		// 3xx 等意外的状态码, 不分类
+		return apiErr.Err
+	}
+	apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return apiErr
+}
+
+// parseRetryAfter 解析 Retry-After, 支持秒数和HTTP日期
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// AuthoritativeDNS 直接查询域名所在区域的权威DNS, 同一区域的权威DNS只查询一次
+type AuthoritativeDNS struct {
+	// LookupNS 和 LookupIPAt 默认为 util.LookupNS 和 util.LookupIPAt, 可在测试中替换
+	LookupNS   func(ctx context.Context, name string) (zone string, servers []string, err error)
+	LookupIPAt func(ctx context.Context, server, network, name string) ([]net.IP, error)
+
+	mu    sync.Mutex
+	zones map[string][]string
+}
+
+// NewAuthoritativeDNS 创建使用系统DNS查找权威DNS的实例
+func NewAuthoritativeDNS() *AuthoritativeDNS {
+	return &AuthoritativeDNS{LookupNS: LookupNS, LookupIPAt: LookupIPAt}
+}
+
+// Servers 域名所在区域的权威DNS, 已查询过所在区域时直接使用缓存
+// 使用最长的已缓存区域, 缓存了上级区域后不再查找其中委派的下级区域
+func (a *AuthoritativeDNS) Servers(ctx context.Context, name string) ([]string, error) {
+	name = strings.TrimSuffix(name, ".")
+
+	a.mu.Lock()
+	zone := ""
+	for z := range a.zones {
+		if (name == z || strings.HasSuffix(name, "."+z)) && len(z) > len(zone) {
+			zone = z
+		}
+	}
+	servers, ok := a.zones[zone]
+	a.mu.Unlock()
+	if ok {
+		return servers, nil
+	}
+
+	zone, servers, err := a.LookupNS(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	if a.zones == nil {
+		a.zones = map[string][]string{}
+	}
+	a.zones[zone] = servers
+	a.mu.Unlock()
+	return servers, nil
+}
+
+// LookupIP 向DNS服务器 server 查询地址, 域名不存在时返回空结果
+func (a *AuthoritativeDNS) LookupIP(ctx context.Context, server, network, name string) ([]net.IP, error) {
+	ips, err := a.LookupIPAt(ctx, server, network, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	return ips, err
+}
+
+// SameAddrs 解析结果与期望的地址集合完全一致
+func SameAddrs(ips []net.IP, values []string) bool {
+	return SameIPs(ips, parseIPs(values))
+}
+
+// ContainsAddrs 解析结果是否包含全部期望的地址
+func ContainsAddrs(ips []net.IP, values []string) bool {
+	for _, want := range parseIPs(values) {
+		if !containsIP(ips, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// SameIPs 两组地址是否相同, 与顺序和重复无关
+func SameIPs(a, b []net.IP) bool {
+	for _, ip := range a {
+		if !containsIP(b, ip) {
+			return false
+		}
+	}
+	for _, ip := range b {
+		if !containsIP(a, ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, v := range ips {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIPs(values []string) []net.IP {
+	ips := make([]net.IP, 0, len(values))
+	for _, v := range values {
+		ips = append(ips, net.ParseIP(v))
+	}
+	return ips
+}
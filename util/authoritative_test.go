@@ -0,0 +1,65 @@
+package util
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestAuthoritativeDNSServers(t *testing.T) {
+	var queried []string
+	a := &AuthoritativeDNS{LookupNS: func(ctx context.Context, name string) (string, []string, error) {
+		queried = append(queried, name)
+		if name == "a.sub.example.com" {
+			return "sub.example.com", []string{"ns.sub.example.net"}, nil
+		}
+		return "example.com", []string{"ns.example.net"}, nil
+	}}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.sub.example.com", "ns.sub.example.net"},
+		{"www.example.com", "ns.example.net"},
+		{"example.com.", "ns.example.net"},
+		{"b.sub.example.com", "ns.sub.example.net"},
+		{"notexample.com", "ns.example.net"},
+	}
+	for _, tt := range tests {
+		servers, err := a.Servers(context.Background(), tt.name)
+		if err != nil || len(servers) != 1 || servers[0] != tt.want {
+			t.Errorf("Servers(%q) = %v, %v, want %s", tt.name, servers, err, tt.want)
+		}
+	}
+	// 同一区域只查询一次, 不同区域分别查询
+	want := []string{"a.sub.example.com", "www.example.com", "notexample.com"}
+	if len(queried) != len(want) {
+		t.Fatalf("queried = %v, want %v", queried, want)
+	}
+	for i := range want {
+		if queried[i] != want[i] {
+			t.Errorf("queried = %v, want %v", queried, want)
+		}
+	}
+}
+
+func TestSameIPs(t *testing.T) {
+	ip := net.ParseIP
+	tests := []struct {
+		a, b []net.IP
+		want bool
+	}{
+		{nil, nil, true},
+		{[]net.IP{ip("1.1.1.1")}, nil, false},
+		{[]net.IP{ip("1.1.1.1"), ip("2.2.2.2")}, []net.IP{ip("2.2.2.2"), ip("1.1.1.1")}, true},
+		{[]net.IP{ip("1.1.1.1"), ip("1.1.1.1")}, []net.IP{ip("1.1.1.1")}, true},
+		{[]net.IP{ip("1.1.1.1")}, []net.IP{ip("1.1.1.1"), ip("2.2.2.2")}, false},
+		{[]net.IP{ip("2001:db8::1")}, []net.IP{ip("2001:db8:0::1")}, true},
+	}
+	for _, tt := range tests {
+		if got := SameIPs(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameIPs(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package util
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// doDigest 发送请求, 服务端要求 HTTP Digest 认证 (RFC 7616, MD5) 时带上认证信息重新发送
+// newRequest 每次调用需返回新的请求, 以便重新发送请求体
+func doDigest(client *http.Client, newRequest func() (*http.Request, error), username, password string) (*http.Response, error) {
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(strings.ToLower(challenge), "digest ") {
+		return nil, NewAPIError(ErrorAuth, fmt.Errorf("unsupported authentication %q", challenge))
+	}
+
+	req, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := digestAuthorization(parseDigestChallenge(challenge[len("digest "):]), req.Method, req.URL.RequestURI(), username, password)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth)
+	return client.Do(req)
+}
+
+// parseDigestChallenge 解析 WWW-Authenticate 中的参数
+func parseDigestChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.IndexByte(s, ','); comma >= 0 {
+			value, s = strings.TrimSpace(s[:comma]), s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		params[key] = value
+		s = strings.TrimPrefix(strings.TrimSpace(s), ",")
+	}
+	return params
+}
+
+// digestAuthorization 计算 Authorization 请求头
+func digestAuthorization(params map[string]string, method, uri, username, password string) (string, error) {
+	if algorithm := params["algorithm"]; algorithm != "" && !strings.EqualFold(algorithm, "MD5") {
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	realm, nonce := params["realm"], params["nonce"]
+	ha1 := md5hex(username + ":" + realm + ":" + password)
+	ha2 := md5hex(method + ":" + uri)
+	auth := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, username, realm, nonce, uri)
+
+	qop := ""
+	for _, q := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	if qop == "" {
+		auth += fmt.Sprintf(`, response="%s"`, md5hex(ha1+":"+nonce+":"+ha2))
+	} else {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		cnonce, nc := hex.EncodeToString(b), "00000001"
+		response := md5hex(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+		auth += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s", response="%s"`, qop, nc, cnonce, response)
+	}
+	if opaque := params["opaque"]; opaque != "" {
+		auth += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	if params["algorithm"] != "" {
+		auth += ", algorithm=MD5"
+	}
+	return auth, nil
+}
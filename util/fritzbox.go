@@ -0,0 +1,121 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultFritzboxURL FRITZ!Box TR-064 的默认地址
+const DefaultFritzboxURL = "http://fritz.box:49000"
+
+// fritzboxService 可获取WAN地址的服务
+type fritzboxService struct {
+	serviceType string
+	controlPath string
+}
+
+var (
+	// fritzboxTR064Services TR-064 服务, 需要认证, 依次尝试IP连接和PPP连接
+	fritzboxTR064Services = []fritzboxService{
+		{"urn:dslforum-org:service:WANIPConnection:1", "/upnp/control/wanipconnection1"},
+		{"urn:dslforum-org:service:WANPPPConnection:1", "/upnp/control/wanpppconn1"},
+	}
+	// fritzboxIGDServices 未填写用户名时使用的UPnP服务, 需要在FRITZ!Box中开启 "通过UPnP传输状态信息"
+	fritzboxIGDServices = []fritzboxService{
+		{"urn:schemas-upnp-org:service:WANIPConnection:1", "/igdupnp/control/WANIPConn1"},
+	}
+)
+
+// FritzboxIPv6 FRITZ!Box 的IPv6地址及分配给局域网的前缀
+type FritzboxIPv6 struct {
+	Addr   net.IP
+	Prefix *net.IPNet
+}
+
+// FritzboxIPv4 通过 TR-064 的 GetExternalIPAddress 获取 FRITZ!Box 的公网IPv4地址
+func FritzboxIPv4(ctx context.Context, baseURL, username, password, ifaceName string) (net.IP, error) {
+	var ip net.IP
+	err := fritzboxCall(ctx, baseURL, username, password, ifaceName, "GetExternalIPAddress", func(args map[string]string) error {
+		ip = net.ParseIP(args["NewExternalIPAddress"])
+		if ip == nil || ip.To4() == nil || ip.IsUnspecified() {
+			return fmt.Errorf("GetExternalIPAddress returned %q", args["NewExternalIPAddress"])
+		}
+		ip = ip.To4()
+		return nil
+	})
+	return ip, err
+}
+
+// FritzboxIPv6Addr 通过 X_AVM_DE_GetExternalIPv6Address 和 X_AVM_DE_GetIPv6Prefix 获取 FRITZ!Box 的公网IPv6地址和前缀
+// 路由器没有公网IPv6地址时只返回前缀, 两者都获取失败时返回错误
+func FritzboxIPv6Addr(ctx context.Context, baseURL, username, password, ifaceName string) (result FritzboxIPv6, err error) {
+	addrErr := fritzboxCall(ctx, baseURL, username, password, ifaceName, "X_AVM_DE_GetExternalIPv6Address", func(args map[string]string) error {
+		ip := net.ParseIP(args["NewExternalIPv6Address"])
+		if ip == nil || ip.To4() != nil || ip.IsUnspecified() {
+			return fmt.Errorf("X_AVM_DE_GetExternalIPv6Address returned %q", args["NewExternalIPv6Address"])
+		}
+		result.Addr = ip
+		return nil
+	})
+	if ErrorKindOf(addrErr) == ErrorAuth {
+		return result, addrErr
+	}
+	prefixErr := fritzboxCall(ctx, baseURL, username, password, ifaceName, "X_AVM_DE_GetIPv6Prefix", func(args map[string]string) error {
+		prefix, err := parsePrefix(args["NewIPv6Prefix"], args["NewPrefixLength"])
+		if err != nil {
+			return err
+		}
+		result.Prefix = prefix
+		return nil
+	})
+	if result.Addr == nil && result.Prefix == nil {
+		return result, errors.Join(addrErr, prefixErr)
+	}
+	return result, nil
+}
+
+// fritzboxCall 依次调用可用的服务, 直到 handle 成功
+func fritzboxCall(ctx context.Context, baseURL, username, password, ifaceName, action string, handle func(map[string]string) error) error {
+	if baseURL == "" {
+		baseURL = DefaultFritzboxURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	services := fritzboxIGDServices
+	if username != "" || password != "" {
+		services = fritzboxTR064Services
+	}
+
+	client := CreateBoundNoProxyHTTPClient("tcp4", ifaceName)
+	client.Timeout = RouterTimeout
+	var errs []error
+	for _, s := range services {
+		args, err := soapCall(ctx, client, baseURL+s.controlPath, s.serviceType, action, username, password)
+		if err == nil {
+			err = handle(args)
+		}
+		if err == nil {
+			return nil
+		}
+		// 认证失败时其他服务同样会失败
+		if ErrorKindOf(err) == ErrorAuth {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", s.controlPath, err))
+	}
+	return errors.Join(errs...)
+}
+
+// parsePrefix 解析前缀及前缀长度
+func parsePrefix(prefix, length string) (*net.IPNet, error) {
+	ip := net.ParseIP(prefix)
+	bits, err := strconv.Atoi(length)
+	if ip == nil || ip.To4() != nil || ip.IsUnspecified() || err != nil || bits <= 0 || bits > 128 {
+		return nil, fmt.Errorf("invalid IPv6 prefix %q/%q", prefix, length)
+	}
+	mask := net.CIDRMask(bits, 128)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
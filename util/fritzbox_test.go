@@ -0,0 +1,121 @@
+package util
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeFritzbox 模拟 FRITZ!Box 的 TR-064 接口, 校验 Digest 认证
+type fakeFritzbox struct {
+	username, password string
+	// actions 各服务支持的操作及返回的参数
+	actions map[string]map[string]string
+}
+
+func (f *fakeFritzbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const realm, nonce = "F!Box SOAP-Auth", "B2B8E4A3F3D4"
+	if f.username != "" && !f.checkAuth(r, realm, nonce) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", nonce="%s", algorithm=MD5, qop="auth"`, realm, nonce))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	soapAction := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+	serviceType, action, _ := strings.Cut(soapAction, "#")
+	args, ok := f.actions[r.URL.Path+" "+action]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring></s:Fault></s:Body></s:Envelope>`)
+		return
+	}
+	var b strings.Builder
+	for k, v := range args {
+		fmt.Fprintf(&b, "<%s>%s</%s>", k, v, k)
+	}
+	fmt.Fprintf(w, `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:%sResponse xmlns:u="%s">%s</u:%sResponse></s:Body></s:Envelope>`, action, serviceType, b.String(), action)
+}
+
+func (f *fakeFritzbox) checkAuth(r *http.Request, realm, nonce string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Digest ") {
+		return false
+	}
+	p := parseDigestChallenge(auth[len("Digest "):])
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	ha1 := md5hex(f.username + ":" + realm + ":" + f.password)
+	ha2 := md5hex(r.Method + ":" + p["uri"])
+	want := md5hex(ha1 + ":" + nonce + ":" + p["nc"] + ":" + p["cnonce"] + ":" + p["qop"] + ":" + ha2)
+	return p["username"] == f.username && p["nonce"] == nonce && p["uri"] == r.URL.RequestURI() && p["response"] == want
+}
+
+func TestFritzbox(t *testing.T) {
+	box := &fakeFritzbox{
+		username: "ddns",
+		password: "secret",
+		actions: map[string]map[string]string{
+			// 只有PPP连接
+			"/upnp/control/wanpppconn1 GetExternalIPAddress": {"NewExternalIPAddress": "203.0.113.10"},
+			"/upnp/control/wanpppconn1 X_AVM_DE_GetExternalIPv6Address": {
+				"NewExternalIPv6Address": "2001:db8::1", "NewPrefixLength": "64",
+			},
+			"/upnp/control/wanpppconn1 X_AVM_DE_GetIPv6Prefix": {"NewIPv6Prefix": "2001:db8:1:ff00::", "NewPrefixLength": "56"},
+			// UPnP 状态接口
+			"/igdupnp/control/WANIPConn1 GetExternalIPAddress": {"NewExternalIPAddress": "203.0.113.11"},
+		},
+	}
+	srv := httptest.NewServer(box)
+	defer srv.Close()
+	ctx := context.Background()
+
+	ip, err := FritzboxIPv4(ctx, srv.URL, "ddns", "secret", "")
+	if err != nil || ip.String() != "203.0.113.10" {
+		t.Errorf("FritzboxIPv4() = %v, %v, want 203.0.113.10", ip, err)
+	}
+
+	v6, err := FritzboxIPv6Addr(ctx, srv.URL+"/", "ddns", "secret", "")
+	if err != nil {
+		t.Fatalf("FritzboxIPv6Addr() error: %v", err)
+	}
+	if v6.Addr.String() != "2001:db8::1" || v6.Prefix.String() != "2001:db8:1:ff00::/56" {
+		t.Errorf("FritzboxIPv6Addr() = %s, %s", v6.Addr, v6.Prefix)
+	}
+
+	_, err = FritzboxIPv4(ctx, srv.URL, "ddns", "wrong", "")
+	if ErrorKindOf(err) != ErrorAuth {
+		t.Errorf("FritzboxIPv4() with wrong password error = %v, want auth error", err)
+	}
+
+	// 未填写用户名时使用 UPnP 状态接口
+	box.username = ""
+	ip, err = FritzboxIPv4(ctx, srv.URL, "", "", "")
+	if err != nil || ip.String() != "203.0.113.11" {
+		t.Errorf("FritzboxIPv4() without login = %v, %v, want 203.0.113.11", ip, err)
+	}
+	if _, err := FritzboxIPv6Addr(ctx, srv.URL, "", "", ""); err == nil {
+		t.Error("FritzboxIPv6Addr() without IPv6 expected error")
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	got := parseDigestChallenge(`realm="F!Box SOAP-Auth", nonce="abc,def", algorithm=MD5, qop="auth,auth-int"`)
+	want := map[string]string{"realm": "F!Box SOAP-Auth", "nonce": "abc,def", "algorithm": "MD5", "qop": "auth,auth-int"}
+	if len(got) != len(want) {
+		t.Fatalf("parseDigestChallenge() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseDigestChallenge()[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+}
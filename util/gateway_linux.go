@@ -0,0 +1,63 @@
+//go:build linux
+
+package util
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultGatewayIPv4 从 /proc/net/route 读取默认网关, ifaceName 不为空时只查找该网卡
+func defaultGatewayIPv4(ifaceName string) (net.IP, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRouteTable(f, ifaceName)
+}
+
+// parseRouteTable 解析 /proc/net/route, 返回默认路由的网关
+func parseRouteTable(r io.Reader, ifaceName string) (net.IP, error) {
+	const rtfGateway = 0x2
+	scanner := bufio.NewScanner(r)
+	// 跳过表头
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "00000000" {
+			continue
+		}
+		if ifaceName != "" && fields[0] != ifaceName {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+		if gw, err := parseHexIPv4(fields[2]); err == nil {
+			return gw, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("no default gateway found")
+}
+
+// parseHexIPv4 解析 /proc/net/route 中的十六进制地址, 地址为主机字节序
+func parseHexIPv4(s string) (net.IP, error) {
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.NativeEndian.PutUint32(ip, uint32(v))
+	return ip, nil
+}
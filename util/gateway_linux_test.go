@@ -0,0 +1,35 @@
+//go:build linux
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// hexIPv4 按主机字节序输出 /proc/net/route 中的地址
+func hexIPv4(s string) string {
+	return fmt.Sprintf("%08X", binary.NativeEndian.Uint32(net.ParseIP(s).To4()))
+}
+
+func TestParseRouteTable(t *testing.T) {
+	table := fmt.Sprintf(`Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth1	%s	00000000	0001	0	0	0	%s	0	0	0
+eth0	00000000	%s	0003	0	0	100	00000000	0	0	0
+eth1	00000000	%s	0003	0	0	200	00000000	0	0	0
+`, hexIPv4("192.168.1.0"), hexIPv4("255.255.255.0"), hexIPv4("192.168.1.1"), hexIPv4("192.168.1.254"))
+	gw, err := parseRouteTable(strings.NewReader(table), "")
+	if err != nil || gw.String() != "192.168.1.1" {
+		t.Errorf("parseRouteTable() = %v, %v, want 192.168.1.1", gw, err)
+	}
+	gw, err = parseRouteTable(strings.NewReader(table), "eth1")
+	if err != nil || gw.String() != "192.168.1.254" {
+		t.Errorf("parseRouteTable(eth1) = %v, %v, want 192.168.1.254", gw, err)
+	}
+	if _, err := parseRouteTable(strings.NewReader(table), "eth2"); err == nil {
+		t.Error("parseRouteTable(eth2) expected error")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package util
+
+import (
+	"errors"
+	"net"
+)
+
+// defaultGatewayIPv4 非Linux系统不读取路由表, 只能通过SSDP发现路由器
+func defaultGatewayIPv4(ifaceName string) (net.IP, error) {
+	return nil, errors.New("default gateway lookup is not supported on this platform")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -70,6 +71,31 @@ func newHTTPDialer(localAddr *net.TCPAddr) *net.Dialer {
 	}
 }
 
+// newBoundDialer 创建绑定指定网卡的 Dialer, 用于 udp4/udp6/tcp4/tcp6, 绑定失败时使用默认网卡
+func newBoundDialer(network, ifaceName string) *net.Dialer {
+	d := &net.Dialer{Resolver: dialer.Resolver}
+	if ifaceName == "" {
+		return d
+	}
+	family := "tcp4"
+	if strings.HasSuffix(network, "6") {
+		family = "tcp6"
+	}
+	localIP, err := getLocalAddrFromInterfaceByNetwork(ifaceName, family)
+	if err != nil {
+		Log("绑定网卡失败, 将使用默认网卡. 网卡: %s, 错误: %v", ifaceName, err)
+		return d
+	}
+	ip := net.ParseIP(localIP)
+	if strings.HasPrefix(network, "udp") {
+		d.LocalAddr = &net.UDPAddr{IP: ip}
+	} else {
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	setLinuxBindToDevice(d, ifaceName)
+	return d
+}
+
 var defaultTransport = &http.Transport{
 	// from http.DefaultTransport
 	Proxy: http.ProxyFromEnvironment,
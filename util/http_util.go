@@ -1,10 +1,14 @@
 package util
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 // GetHTTPResponse 处理HTTP结果，返回序列化的json
@@ -25,7 +29,7 @@ func GetHTTPResponse(resp *http.Response, err error, result interface{}) error {
 // GetHTTPResponseOrg 处理HTTP结果，返回byte
 func GetHTTPResponseOrg(resp *http.Response, err error) ([]byte, error) {
 	if err != nil {
-		return nil, err
+		return nil, contextErr(err)
 	}
 
 	defer resp.Body.Close()
@@ -33,13 +37,35 @@ func GetHTTPResponseOrg(resp *http.Response, err error) ([]byte, error) {
 	body, err := io.ReadAll(lr)
 
 	if err != nil {
-		return nil, err
+		return nil, contextErr(err)
 	}
 
 	// 300及以上状态码都算异常
 	if resp.StatusCode >= 300 {
-		err = fmt.Errorf("%s", LogStr("返回内容: %s ,返回状态码: %d", string(body), resp.StatusCode))
+		err = statusError(resp, body)
 	}
 
 	return body, err
 }
+
+// PostForm 与 http.Client.PostForm 相同, 请求会随 ctx 取消
+func PostForm(ctx context.Context, client *http.Client, url string, data url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return client.Do(req)
+}
+
+// contextErr 请求被取消或超时时返回更明确的错误, 仍可通过 errors.Is 判断
+// 其他网络错误视为临时错误
+func contextErr(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%s: %w", LogStr("请求已取消"), err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%s: %w", LogStr("请求超时"), err)
+	}
+	return &APIError{Kind: ErrorTransient, Err: err}
+}
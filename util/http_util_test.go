@@ -0,0 +1,101 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetHTTPResponseOrgCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := server.Client().Do(req)
+	_, err = GetHTTPResponseOrg(resp, err)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPostForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("key") != "value" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := PostForm(context.Background(), server.Client(), server.URL, map[string][]string{"key": {"value"}})
+	if _, err := GetHTTPResponseOrg(resp, err); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGetHTTPResponseOrgErrorKind(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		kind       ErrorKind
+		wait       time.Duration
+	}{
+		{http.StatusUnauthorized, "", ErrorAuth, 0},
+		{http.StatusForbidden, "", ErrorAuth, 0},
+		{http.StatusNotFound, "", ErrorNotFound, 0},
+		{http.StatusTooManyRequests, "7", ErrorRateLimited, 7 * time.Second},
+		{http.StatusServiceUnavailable, "", ErrorTransient, 0},
+		{http.StatusBadRequest, "", ErrorValidation, 0},
+		{http.StatusUnprocessableEntity, "", ErrorValidation, 0},
+		{http.StatusMovedPermanently, "", "", 0},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.retryAfter != "" {
+				w.Header().Set("Retry-After", tt.retryAfter)
+			}
+			w.WriteHeader(tt.status)
+		}))
+		client := server.Client()
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		resp, err := client.Get(server.URL)
+		_, err = GetHTTPResponseOrg(resp, err)
+		server.Close()
+
+		if err == nil {
+			t.Errorf("status %d: expected error", tt.status)
+			continue
+		}
+		if kind := ErrorKindOf(err); kind != tt.kind {
+			t.Errorf("status %d: kind = %q, want %q", tt.status, kind, tt.kind)
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter != tt.wait {
+			t.Errorf("status %d: RetryAfter = %s, want %s", tt.status, apiErr.RetryAfter, tt.wait)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"Mon, 01 Jan 2024 00:00:30 GMT": 30 * time.Second,
+		"Sun, 31 Dec 2023 00:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for v, want := range tests {
+		if got := parseRetryAfter(v, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", v, got, want)
+		}
+	}
+}
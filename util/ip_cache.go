@@ -3,6 +3,7 @@ package util
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 const IPCacheTimesENV = "DDNS_IP_CACHE_TIMES"
@@ -12,6 +13,12 @@ type IpCache struct {
 	Addr          string // 缓存地址
 	Times         int    // 剩余次数
 	TimesFailedIP int    // 获取ip失败的次数
+	// StableAddr 最后一次稳定的地址, 与 Addr 分开保存, 重置缓存以便重新比对时不影响稳定判断
+	StableAddr string
+	// Pending 等待稳定的新地址, PendingTimes 为连续获取到的次数, PendingSince 为首次获取到的时间
+	Pending      string
+	PendingTimes int
+	PendingSince time.Time
 }
 
 var ForceCompareGlobal = true
@@ -34,3 +41,40 @@ func (d *IpCache) Check(newAddr string) bool {
 	d.Times--
 	return false
 }
+
+// Stable 新地址需连续获取到 times 次或持续 window 后才视为稳定, 两者满足其一即可
+// times 不大于 1 且 window 为 0 时不等待; 还没有稳定的地址时也不等待, 以便启动后立即更新
+// 返回新地址是否可以更新, 以及等待稳定的地址是否在稳定前又发生了变化, 即发生了抖动
+func (d *IpCache) Stable(newAddr string, times int, window time.Duration, now time.Time) (stable, flapped bool) {
+	if newAddr == "" {
+		return true, false
+	}
+	if newAddr == d.StableAddr || d.StableAddr == "" || (times <= 1 && window <= 0) {
+		flapped = d.Pending != "" && d.Pending != newAddr
+		d.clearPending()
+		d.StableAddr = newAddr
+		return true, flapped
+	}
+
+	if newAddr != d.Pending {
+		flapped = d.Pending != ""
+		d.Pending, d.PendingTimes, d.PendingSince = newAddr, 0, now
+	}
+	d.PendingTimes++
+	if (times > 1 && d.PendingTimes >= times) || (window > 0 && now.Sub(d.PendingSince) >= window) {
+		d.clearPending()
+		d.StableAddr = newAddr
+		return true, flapped
+	}
+	return false, flapped
+}
+
+// Reset 清空缓存的地址, 下次获取后重新与DNS服务商比对; 保留获取IP失败的次数和稳定状态
+func (d *IpCache) Reset() {
+	d.Addr = ""
+	d.Times = 0
+}
+
+func (d *IpCache) clearPending() {
+	d.Pending, d.PendingTimes, d.PendingSince = "", 0, time.Time{}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIpCacheStable 测试新地址稳定后才更新
+func TestIpCacheStable(t *testing.T) {
+	now := time.Now()
+	cache := IpCache{}
+
+	// 还没有缓存地址时立即更新
+	if stable, _ := cache.Stable("192.0.2.1", 3, 0, now); !stable {
+		t.Fatal("first address should be stable")
+	}
+	cache.Check("192.0.2.1")
+
+	// 连续获取到 3 次后更新
+	for i := 1; i <= 3; i++ {
+		stable, flapped := cache.Stable("192.0.2.2", 3, 0, now)
+		if stable != (i == 3) || flapped {
+			t.Fatalf("check %d: stable = %v, flapped = %v", i, stable, flapped)
+		}
+	}
+	if cache.Pending != "" {
+		t.Errorf("Pending = %q after becoming stable", cache.Pending)
+	}
+	cache.Check("192.0.2.2")
+
+	// 稳定前回到原地址, 视为抖动
+	cache.Stable("192.0.2.3", 3, 0, now)
+	if stable, flapped := cache.Stable("192.0.2.2", 3, 0, now); !stable || !flapped {
+		t.Errorf("returning to the cached address: stable = %v, flapped = %v", stable, flapped)
+	}
+
+	// 稳定前改变为另一个地址, 重新计数
+	cache.Stable("192.0.2.3", 3, 0, now)
+	if stable, flapped := cache.Stable("192.0.2.4", 3, 0, now); stable || !flapped || cache.PendingTimes != 1 {
+		t.Errorf("changing pending address: stable = %v, flapped = %v, PendingTimes = %d", stable, flapped, cache.PendingTimes)
+	}
+
+	// 持续时间满足后更新
+	if stable, _ := cache.Stable("192.0.2.4", 10, time.Minute, now.Add(time.Minute)); !stable {
+		t.Error("address should be stable after the window")
+	}
+
+	// 未设置时立即更新
+	if stable, _ := cache.Stable("192.0.2.5", 0, 0, now); !stable {
+		t.Error("address should be stable without a window")
+	}
+}
+
+// TestIpCacheResetKeepsStable 测试重置缓存后仍需等待新地址稳定
+func TestIpCacheResetKeepsStable(t *testing.T) {
+	now := time.Now()
+	cache := IpCache{TimesFailedIP: 2}
+	cache.Stable("192.0.2.1", 3, 0, now)
+	cache.Check("192.0.2.1")
+	cache.Stable("192.0.2.2", 3, 0, now)
+
+	cache.Reset()
+	if cache.Addr != "" || cache.Times != 0 || cache.TimesFailedIP != 2 {
+		t.Fatalf("Reset() = %+v", cache)
+	}
+	if stable, _ := cache.Stable("192.0.2.2", 3, 0, now); stable || cache.PendingTimes != 2 {
+		t.Errorf("stable = %v, PendingTimes = %d, want waiting after reset", stable, cache.PendingTimes)
+	}
+}
@@ -19,13 +19,17 @@ func init() {
 	message.SetString(language.English, "配置文件已保存在: %s", "Config file has been saved to: %s")
 
 	message.SetString(language.English, "你的IP %s 没有变化, 域名 %s", "Your IP %s has not changed! Domain: %s")
-	message.SetString(language.English, "DNS 记录没有变化, 域名 %s", "DNS record has not changed! Domain: %s")
 	message.SetString(language.English, "Cloudflare 代理状态发生变化: %v -> %v! 域名 %s", "Cloudflare proxy status changed: %v -> %v! Domain: %s")
 	message.SetString(language.English, "新增域名解析 %s 成功! IP: %s", "Added domain %s successfully! IP: %s")
 	message.SetString(language.English, "新增域名解析 %s 失败! 异常信息: %s", "Failed to add domain %s! Result: %s")
 
 	message.SetString(language.English, "更新域名解析 %s 成功! IP: %s", "Updated domain %s successfully! IP: %s")
 	message.SetString(language.English, "更新域名解析 %s 失败! 异常信息: %s", "Failed to update domain %s! Result: %s")
+	message.SetString(language.English, "删除域名解析 %s 成功! IP: %s", "Deleted domain %s successfully! IP: %s")
+	message.SetString(language.English, "删除域名解析 %s 失败! 异常信息: %s", "Failed to delete domain %s! Result: %s")
+	message.SetString(language.English, "未能获取IP, 已删除域名 %s 的 %s 记录 %s", "Failed to get IP, deleted %[2]s record %[3]s of domain %[1]s")
+	message.SetString(language.English, "未能获取IP, 已将域名 %s 的 %s 记录修改为备用地址 %s", "Failed to get IP, changed %[2]s record of domain %[1]s to fallback address %[3]s")
+	message.SetString(language.English, "DNS服务商 %s 不支持处理失效记录", "DNS provider %s does not support handling stale records")
 
 	message.SetString(language.English, "你的IPv4未变化, 未触发 %s 请求", "Your IPv4 has not changed, %s request has not been triggered")
 	message.SetString(language.English, "你的IPv6未变化, 未触发 %s 请求", "Your IPv6 has not changed, %s request has not been triggered")
@@ -37,8 +41,15 @@ func init() {
 	message.SetString(language.English, "异常信息: %s", "Exception: %s")
 	message.SetString(language.English, "查询域名信息发生异常! %s", "Failed to query domain info! %s")
 	message.SetString(language.English, "返回内容: %s ,返回状态码: %d", "Response body: %s, Response status code: %d")
-	message.SetString(language.English, "通过接口获取IPv4失败! 接口地址: %s", "Failed to get IPv4 from %s")
-	message.SetString(language.English, "通过接口获取IPv6失败! 接口地址: %s", "Failed to get IPv6 from %s")
+	message.SetString(language.English, "请求已取消", "Request canceled")
+	message.SetString(language.English, "请求超时", "Request timed out")
+	message.SetString(language.English, "认证失败", "Authentication failed")
+	message.SetString(language.English, "请求过于频繁", "Rate limited")
+	message.SetString(language.English, "资源不存在", "Not found")
+	message.SetString(language.English, "临时错误", "Temporary error")
+	message.SetString(language.English, "请求参数错误", "Invalid request")
+	message.SetString(language.English, "请求失败, %s 后进行第 %d 次重试: %s", "Request failed, retry #%[2]d after %[1]s: %[3]s")
+	message.SetString(language.English, "通过接口获取%s失败! 接口地址: %s", "Failed to get %s from %s")
 	message.SetString(language.English, "将不会触发Webhook, 仅在第 3 次失败时触发一次Webhook, 当前失败次数：%d", "Webhook will not be triggered, only trigger once when the third failure, current failure times: %d")
 	message.SetString(language.English, "在DNS服务商中未找到根域名: %s", "Root domain not found in DNS provider: %s")
 
@@ -60,16 +71,27 @@ func init() {
 	message.SetString(language.English, "密码不安全！尝试使用更复杂的密码", "Password is not secure! Try using a more complex password")
 	message.SetString(language.English, "数据解析失败, 请刷新页面重试", "Data parsing failed, please refresh the page and try again")
 	message.SetString(language.English, "第 %s 个配置未填写域名", "The %s config does not fill in the domain")
+	message.SetString(language.English, "第 %s 个配置的DNS服务商 %s 不存在", "The DNS provider %[2]s of the %[1]s config does not exist")
+	message.SetString(language.English, "第 %s 个配置的DNS服务商 %s 不支持处理旧记录或多地址", "The DNS provider %[2]s of the %[1]s config does not support handling stale records or multiple addresses")
+	message.SetString(language.English, "第 %s 个配置的%s备用地址不能为空", "The %[2]s fallback address of the %[1]s config cannot be empty")
+	message.SetString(language.English, "第 %s 个配置的%s备用地址 %s 无效", "The %[2]s fallback address %[3]s of the %[1]s config is invalid")
+	message.SetString(language.English, "已为升级前的配置设置为允许私有地址和运营商级NAT地址, 可在地址校验中修改", "Configs saved before the upgrade now allow private and CGNAT addresses; change this under address validation")
+	message.SetString(language.English, "第 %s 个配置的更新计划无效: %s", "Invalid schedule in the %s config: %s")
+	message.SetString(language.English, "第 %s 个配置的IPv6选择策略无效: %s", "Invalid IPv6 selection in the %s config: %s")
+	message.SetString(language.English, "第 %s 个配置认证失败, 已暂停更新至 %s, 修改配置后恢复", "Authentication failed for the %s config, updates are paused until %s or until the config is changed")
+	message.SetString(language.English, "第 %s 个配置的DNS服务商 %s 不存在, 已跳过", "The DNS provider %[2]s of the %[1]s config does not exist, skipped")
+	message.SetString(language.English, "第 %s 个配置的更新已取消, 部分结果: %s", "The update of the %s config was canceled, partial result: %s")
+	message.SetString(language.English, "第 %s 个配置的更新超时, 部分结果: %s", "The update of the %s config timed out, partial result: %s")
 
 	// config
 	message.SetString(language.English, "从网卡获得IPv4失败", "Failed to get IPv4 from network card")
 	message.SetString(language.English, "从网卡中获得IPv4失败! 网卡名: %s", "Failed to get IPv4 from network card! Network card name: %s")
-	message.SetString(language.English, "获取IPv4结果失败! 接口: %s ,返回值: %s", "Failed to get IPv4 result! Interface: %s ,Result: %s")
+	message.SetString(language.English, "获取%s结果失败! 接口: %s ,返回值: %s", "Failed to get %s result! Interface: %s ,Result: %s")
 	message.SetString(language.English, "获取%s结果失败! 未能成功执行命令：%s, 错误：%q, 退出状态码：%s", "Failed to get %s result! Command: %s, Error: %q, Exit status code: %s")
 	message.SetString(language.English, "获取%s结果失败! 命令: %s, 标准输出: %q", "Failed to get %s result! Command: %s, Stdout: %q")
 	message.SetString(language.English, "从网卡获得IPv6失败", "Failed to get IPv6 from network card")
 	message.SetString(language.English, "从网卡中获得IPv6失败! 网卡名: %s", "Failed to get IPv6 from network card! Network card name: %s")
-	message.SetString(language.English, "获取IPv6结果失败! 接口: %s ,返回值: %s", "Failed to get IPv6 result! Interface: %s ,Result: %s")
+	message.SetString(language.English, "按选择策略 %s 从网卡 %s 获得IPv6失败! 错误: %s", "Failed to get IPv6 from network card %[2]s with selection %[1]s! Error: %[3]s")
 	message.SetString(language.English, "未找到第 %d 个IPv6地址! 将使用第一个IPv6地址", "%dth IPv6 address not found! Will use the first IPv6 address")
 	message.SetString(language.English, "IPv6匹配表达式 %s 不正确! 最小从1开始", "IPv6 match expression %s is incorrect! Minimum start from 1")
 	message.SetString(language.English, "IPv6将使用正则表达式 %s 进行匹配", "IPv6 will use regular expression %s for matching")
@@ -81,6 +103,10 @@ func init() {
 	// domains
 	message.SetString(language.English, "域名: %s 不正确", "The domain %s is incorrect")
 	message.SetString(language.English, "域名: %s 解析失败", "The domain %s resolution failed")
+	message.SetString(language.English, "域名: %s 的参数不正确: %s", "Invalid parameters for domain %s: %s")
+	message.SetString(language.English, "读取邻居表失败! 异常信息: %s", "Failed to read the neighbor table! Exception: %s")
+	message.SetString(language.English, "邻居表中未找到 %s 的%s地址, 将沿用上次的地址 %s", "No %[2]s address for %[1]s in the neighbor table, keeping the last address %[3]s")
+	message.SetString(language.English, "邻居表中未找到 %s 的%s地址, 将不会更新域名 %s", "No %[2]s address for %[1]s in the neighbor table, domain %[3]s will not be updated")
 	message.SetString(language.English, "域名 %s 解析未找到，且因添加了参数 %s=%s 导致无法创建。本次更新已被忽略", "DNS resolution for domain %s was not found, and the creation failed due to the added parameter %s=%s. This update has been ignored.")
 	message.SetString(language.English, "IPv6未改变, 将等待 %d 次后与DNS服务商进行比对", "IPv6 has not changed, will wait %d times to compare with DNS provider")
 	message.SetString(language.English, "IPv4未改变, 将等待 %d 次后与DNS服务商进行比对", "IPv4 has not changed, will wait %d times to compare with DNS provider")
@@ -100,11 +126,54 @@ func init() {
 	message.SetString(language.English, "重启 ddns-go 服务成功", "Restarted ddns-go service successfully")
 	message.SetString(language.English, "启动 ddns-go 服务成功", "Started ddns-go service successfully")
 	message.SetString(language.English, "ddns-go 服务未安装, 请先安装服务", "ddns-go service is not installed, please install the service first")
+	message.SetString(language.English, "收到退出信号, 正在停止...", "Received exit signal, stopping...")
+	message.SetString(language.English, "dry-run 失败! %s", "Dry-run failed! %s")
+	message.SetString(language.English, "dry-run 完成, 共 %d 条记录, 未修改任何记录", "Dry-run finished, %d records planned, nothing was changed")
+	message.SetString(language.English, "监听网卡变化失败, 将只定时检测IP变化: %s", "Failed to watch network interface changes, only checking IP periodically: %s")
+	message.SetString(language.English, "更新计划无效, 将使用默认间隔! 异常信息: %s", "Invalid schedule, using the default interval! Exception: %s")
+	message.SetString(language.English, "网卡 %s 发生变化, 立即更新", "Network interface %s changed, updating now")
+	message.SetString(language.English, "已从状态文件 %s 恢复 %d 个配置的缓存", "Restored the cache of %[2]d configurations from state file %[1]s")
+	message.SetString(language.English, "读取状态文件 %s 失败! 异常信息: %s", "Failed to read state file %s! Exception: %s")
+	message.SetString(language.English, "保存状态文件 %s 失败! 异常信息: %s", "Failed to save state file %s! Exception: %s")
 
 	// webhook通知
 	message.SetString(language.English, "未改变", "unchanged")
 	message.SetString(language.English, "失败", "failed")
 	message.SetString(language.English, "成功", "success")
+	message.SetString(language.English, "未处理", "not processed")
+	message.SetString(language.English, "已生效", "published")
+	message.SetString(language.English, "未生效", "not propagated")
+	message.SetString(language.English, "域名 %s 的记录已在权威DNS中生效", "The record of domain %s is published on the authoritative nameservers")
+	message.SetString(language.English, "域名 %s 的记录在 %s 内未在权威DNS中生效", "The record of domain %s was not propagated to the authoritative nameservers within %s")
+	message.SetString(language.English, "域名 %s 已开启代理, 跳过权威DNS验证", "Domain %s is proxied, skipping the authoritative DNS check")
+	message.SetString(language.English, "域名 %s 中没有ID为 %s 的记录, 将使用全部记录", "No record with ID %[2]s in domain %[1]s, all records will be used")
+	message.SetString(language.English, "通过STUN获取%s失败! 服务器: %s", "Failed to get %s from STUN server %s")
+	message.SetString(language.English, "通过DNS获取%s失败! 查询: %s", "Failed to get %s by DNS query %s")
+	message.SetString(language.English, "从FRITZ!Box获取%s失败! 异常信息: %s", "Failed to get %s from the FRITZ!Box! Exception: %s")
+	message.SetString(language.English, "使用 FRITZ!Box 的IPv6前缀 %s 组成局域网主机的地址", "Using IPv6 prefix %s of the FRITZ!Box for the LAN host addresses")
+	message.SetString(language.English, "FRITZ!Box 没有公网IPv6地址, 为域名设置 Ipv6Suffix 后可使用前缀 %s", "The FRITZ!Box has no public IPv6 address, set Ipv6Suffix on the domains to use prefix %s")
+	message.SetString(language.English, "FRITZ!Box 没有公网IPv6地址", "The FRITZ!Box has no public IPv6 address")
+	message.SetString(language.English, "从路由器获取IPv4失败! 异常信息: %s", "Failed to get IPv4 from the router! Exception: %s")
+	message.SetString(language.English, "路由器的WAN口地址 %s 是私有地址或运营商级NAT地址, 路由器可能没有公网IP", "The router's WAN address %s is a private or CGNAT address, the router may not have a public IP")
+	message.SetString(language.English, "接口 %s 返回的%s %s 与多数结果 %s 不一致", "%[1]s returned %[2]s %[3]s, which disagrees with the majority result %[4]s")
+	message.SetString(language.English, "获取%s失败! 需要至少 %d 个接口返回相同的地址, 实际最多 %d 个", "Failed to get %s! At least %d sources must return the same address, but at most %d agreed")
+	message.SetString(language.English, "第 %s 个配置的%s接口一致数量 %s 无效", "The %s config has an invalid %s source quorum %s")
+	message.SetString(language.English, "第 %s 个配置的%s接口不正确: %s", "The %s config has an invalid %s source: %s")
+	message.SetString(language.English, "第 %s 个配置的%sDNS查询不正确: %s", "The %[2]s DNS query of the %[1]s config is invalid: %[3]s")
+	message.SetString(language.English, "接口 %s 的配置不正确: %s", "Invalid source %s: %s")
+	message.SetString(language.English, "%s地址 %s 在拒绝的网段中, 已忽略", "%s address %s is in a denied range, ignored")
+	message.SetString(language.English, "%s地址 %s 是运营商级NAT地址, 已忽略. 可在地址校验中允许", "%s address %s is a carrier-grade NAT address, ignored. It can be allowed in the address filter")
+	message.SetString(language.English, "%s地址 %s 是私有或保留地址, 已忽略. 可在地址校验中允许", "%s address %s is a private or reserved address, ignored. It can be allowed in the address filter")
+	message.SetString(language.English, "获取到的IPv4地址 %s 是运营商级NAT地址, 当前网络可能没有公网IPv4", "The IPv4 address %s is a carrier-grade NAT address, this network may not have a public IPv4")
+	message.SetString(language.English, "第 %s 个配置的地址校验规则无效: %s", "Invalid address filter in the %s config: %s")
+	message.SetString(language.English, "第 %s 个配置的%s接口一致数量 %d 大于接口的数量 %d", "The %s config's %s source quorum %d is greater than the number of URLs %d")
+	message.SetString(language.English, "预检查: 查询域名 %s 失败, 将请求DNS服务商! 异常信息: %s", "Precheck: failed to resolve domain %s, the DNS provider will be requested! Exception: %s")
+	message.SetString(language.English, "预检查: 域名 %s 已解析到 %s, 跳过请求DNS服务商", "Precheck: domain %s already resolves to %s, skipping the DNS provider")
+	message.SetString(language.English, "预检查: 域名 %s 解析到 %s, 与 %s 不一致, 将请求DNS服务商", "Precheck: domain %s resolves to %s instead of %s, the DNS provider will be requested")
+	message.SetString(language.English, "%s地址在稳定前再次改变", "%s address changed again before becoming stable")
+	message.SetString(language.English, "%s地址 %s 尚未稳定, 已连续获取到 %d 次, 将不会更新", "%s address %s is not stable yet, obtained %d times in a row, will not update")
+	message.SetString(language.English, "%s的获取方式与其他配置相同, 使用本轮已获取的地址: %s", "%s is obtained the same way as another config, using the address obtained in this run: %s")
+	message.SetString(language.English, "查询域名 %s 的权威DNS失败! 异常信息: %s", "Failed to look up the authoritative nameservers of domain %s! Exception: %s")
 
 	// Login
 	message.SetString(language.English, "%q 配置文件为空, 超过3小时禁止从公网访问", "%q configuration file is empty, public network access is prohibited for more than 3 hours")
@@ -118,9 +187,7 @@ func init() {
 	message.SetString(language.English, "配置文件 %s 不存在, 可通过-c指定配置文件", "Config file %s does not exist, you can specify the configuration file through -c")
 
 	// 新增 DNS 提供商相关日志
-	message.SetString(language.English, "查询域名 %s 信息发生异常! %v", "Failed to query domain %s info! %v")
 	message.SetString(language.English, "在DNS服务商中未找到域名: %s", "Domain not found in DNS provider: %s")
-	message.SetString(language.English, "IP %s 没有变化，域名 %s", "IP %s has not changed, domain %s")
 
 }
 
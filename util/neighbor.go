@@ -0,0 +1,68 @@
+package util
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"time"
+)
+
+// NeighborState 邻居表项的可达状态, 值越小越可信
+type NeighborState int
+
+const (
+	// NeighborReachable 最近已确认可达, 或为静态表项
+	NeighborReachable NeighborState = iota
+	// NeighborProbe 正在确认可达性
+	NeighborProbe
+	// NeighborStale 一段时间未确认, 设备可能已离线或更换了地址
+	NeighborStale
+)
+
+// Neighbor 内核邻居表(ARP/NDP)中的一项
+type Neighbor struct {
+	IP    net.IP
+	MAC   net.HardwareAddr
+	State NeighborState
+	// Confirmed 距上次确认可达的时间, 未知时为 0
+	Confirmed time.Duration
+}
+
+// NeighborStaleAge 状态为 NeighborStale 且超过该时间未确认的表项视为设备已离线
+var NeighborStaleAge = 30 * time.Minute
+
+// Neighbors 读取内核邻居表
+func Neighbors() ([]Neighbor, error) {
+	return neighbors()
+}
+
+// NeighborAddrs 从邻居表中查找 MAC 地址当前使用的地址
+// 只使用全局单播地址, 存在多个状态的表项时只使用最可信的状态
+func NeighborAddrs(table []Neighbor, mac net.HardwareAddr, ipv6 bool) []string {
+	var found []Neighbor
+	for _, n := range table {
+		if !bytes.Equal(n.MAC, mac) || (n.IP.To4() == nil) != ipv6 || !n.IP.IsGlobalUnicast() {
+			continue
+		}
+		if n.State == NeighborStale && n.Confirmed > NeighborStaleAge {
+			continue
+		}
+		found = append(found, n)
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	best := found[0].State
+	for _, n := range found {
+		best = min(best, n.State)
+	}
+	var addrs []string
+	for _, n := range found {
+		if n.State == best {
+			addrs = append(addrs, n.IP.String())
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package util
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// clockTicks 邻居表缓存信息中时间的单位 (USER_HZ)
+const clockTicks = 100
+
+// neighbors 通过 netlink RTM_GETNEIGH 读取IPv4和IPv6的邻居表
+func neighbors() ([]Neighbor, error) {
+	rib, err := syscall.NetlinkRIB(unix.RTM_GETNEIGH, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, err
+	}
+	return parseNeighMessages(msgs), nil
+}
+
+// parseNeighMessages 解析 RTM_NEWNEIGH 消息, 忽略未完成或失败的表项
+func parseNeighMessages(msgs []syscall.NetlinkMessage) (table []Neighbor) {
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWNEIGH || len(m.Data) < unix.SizeofNdMsg {
+			continue
+		}
+		family := m.Data[0]
+		if family != unix.AF_INET && family != unix.AF_INET6 {
+			continue
+		}
+		n := Neighbor{}
+		switch state := binary.NativeEndian.Uint16(m.Data[8:10]); {
+		case state&(unix.NUD_REACHABLE|unix.NUD_PERMANENT|unix.NUD_NOARP) != 0:
+			n.State = NeighborReachable
+		case state&(unix.NUD_DELAY|unix.NUD_PROBE) != 0:
+			n.State = NeighborProbe
+		case state&unix.NUD_STALE != 0:
+			n.State = NeighborStale
+		default:
+			continue
+		}
+
+		// ParseNetlinkRouteAttr 不支持邻居消息, 手动解析属性
+		attrs := m.Data[unix.SizeofNdMsg:]
+		for len(attrs) >= unix.SizeofRtAttr {
+			l := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			if l < unix.SizeofRtAttr || l > len(attrs) {
+				break
+			}
+			value := attrs[unix.SizeofRtAttr:l]
+			switch binary.NativeEndian.Uint16(attrs[2:4]) {
+			case unix.NDA_DST:
+				n.IP = net.IP(value)
+			case unix.NDA_LLADDR:
+				n.MAC = net.HardwareAddr(value)
+			case unix.NDA_CACHEINFO:
+				// struct nda_cacheinfo 的第一个字段 ndm_confirmed
+				if len(value) >= 4 {
+					n.Confirmed = time.Duration(binary.NativeEndian.Uint32(value)) * time.Second / clockTicks
+				}
+			}
+			next := (l + unix.NLMSG_ALIGNTO - 1) &^ (unix.NLMSG_ALIGNTO - 1)
+			if next > len(attrs) {
+				break
+			}
+			attrs = attrs[next:]
+		}
+		if n.IP == nil || len(n.MAC) == 0 {
+			continue
+		}
+		table = append(table, n)
+	}
+	return
+}
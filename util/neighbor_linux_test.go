@@ -0,0 +1,66 @@
+package util
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// neighMessage 构造 RTM_NEWNEIGH 消息
+func neighMessage(family byte, state uint16, ip net.IP, mac string, confirmed uint32) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofNdMsg)
+	data[0] = family
+	binary.NativeEndian.PutUint16(data[8:], state)
+	hw, _ := net.ParseMAC(mac)
+	cacheInfo := make([]byte, 16)
+	binary.NativeEndian.PutUint32(cacheInfo, confirmed)
+	for _, attr := range []struct {
+		typ   uint16
+		value []byte
+	}{{unix.NDA_DST, ip}, {unix.NDA_LLADDR, hw}, {unix.NDA_CACHEINFO, cacheInfo}} {
+		b := make([]byte, unix.SizeofRtAttr+len(attr.value))
+		binary.NativeEndian.PutUint16(b[0:], uint16(len(b)))
+		binary.NativeEndian.PutUint16(b[2:], attr.typ)
+		copy(b[unix.SizeofRtAttr:], attr.value)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+		data = append(data, b...)
+	}
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: unix.RTM_NEWNEIGH}, Data: data}
+}
+
+func TestNeighborAddrs(t *testing.T) {
+	const mac = "aa:bb:cc:dd:ee:ff"
+	table := parseNeighMessages([]syscall.NetlinkMessage{
+		neighMessage(unix.AF_INET6, unix.NUD_REACHABLE, net.ParseIP("2001:db8::2"), mac, 0),
+		neighMessage(unix.AF_INET6, unix.NUD_DELAY, net.ParseIP("2001:db8::1"), mac, 0),
+		neighMessage(unix.AF_INET6, unix.NUD_REACHABLE, net.ParseIP("2001:db8::3"), mac, 0),
+		neighMessage(unix.AF_INET6, unix.NUD_REACHABLE, net.ParseIP("fe80::1"), mac, 0),
+		neighMessage(unix.AF_INET6, unix.NUD_FAILED, net.ParseIP("2001:db8::4"), mac, 0),
+		neighMessage(unix.AF_INET6, unix.NUD_REACHABLE, net.ParseIP("2001:db8::5"), "11:22:33:44:55:66", 0),
+		neighMessage(unix.AF_INET, unix.NUD_STALE, net.ParseIP("192.168.1.10").To4(), mac, 60*clockTicks),
+		neighMessage(unix.AF_INET, unix.NUD_STALE, net.ParseIP("192.168.1.11").To4(), mac, 3600*clockTicks),
+	})
+	if len(table) != 7 {
+		t.Fatalf("got %d neighbors, want 7 (failed entries are skipped)", len(table))
+	}
+	if table[6].Confirmed != time.Hour {
+		t.Errorf("Confirmed = %s, want 1h", table[6].Confirmed)
+	}
+
+	hw, _ := net.ParseMAC(mac)
+	// 只使用最可信的状态, 忽略链路本地地址
+	if got, want := NeighborAddrs(table, hw, true), []string{"2001:db8::2", "2001:db8::3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IPv6 = %v, want %v", got, want)
+	}
+	// 长时间未确认的 stale 表项视为离线
+	if got, want := NeighborAddrs(table, hw, false), []string{"192.168.1.10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IPv4 = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package util
+
+import "errors"
+
+// neighbors 非Linux系统不读取邻居表
+func neighbors() ([]Neighbor, error) {
+	return nil, errors.New("neighbor table lookup is not supported on this platform")
+}
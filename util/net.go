@@ -31,6 +31,14 @@ func IsPrivateNetwork(remoteAddr string) bool {
 	return false
 }
 
+// cgnatNet 运营商级NAT地址 100.64.0.0/10, RFC 6598
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0).To4(), Mask: net.CIDRMask(10, 32)}
+
+// IsCGNAT 是否为运营商级NAT地址
+func IsCGNAT(ip net.IP) bool {
+	return cgnatNet.Contains(ip)
+}
+
 // GetRequestIPStr get IP string from request
 func GetRequestIPStr(r *http.Request) (addr string) {
 	addr = "Remote: " + r.RemoteAddr
@@ -2,10 +2,17 @@ package util
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
 	"net"
 	"net/url"
 	"strings"
+	"time"
 
+	"golang.org/x/net/dns/dnsmessage"
 	"golang.org/x/text/language"
 )
 
@@ -24,15 +31,17 @@ func InitBackupDNS(customDNS, lang string) {
 
 }
 
-// SetDNS sets the dialer.Resolver to use the given DNS server.
-func SetDNS(dns string) {
-
+// parseDNSServer 解析DNS服务器, 支持 tcp:// 和 udp:// 前缀, 默认为 udp, 端口默认为 53
+func parseDNSServer(dns string) (network, addr string) {
+	// 不带端口的IPv6地址
+	if ip := net.ParseIP(dns); ip != nil {
+		return "udp", net.JoinHostPort(dns, "53")
+	}
 	if !strings.Contains(dns, "://") {
 		dns = "udp://" + dns
 	}
 	svrParse, _ := url.Parse(dns)
 
-	var network string
 	switch strings.ToLower(svrParse.Scheme) {
 	case "tcp":
 		network = "tcp"
@@ -41,10 +50,16 @@ func SetDNS(dns string) {
 	}
 
 	if svrParse.Port() == "" {
-		dns = net.JoinHostPort(svrParse.Host, "53")
+		addr = net.JoinHostPort(svrParse.Hostname(), "53")
 	} else {
-		dns = svrParse.Host
+		addr = svrParse.Host
 	}
+	return
+}
+
+// SetDNS sets the dialer.Resolver to use the given DNS server.
+func SetDNS(dns string) {
+	network, dns := parseDNSServer(dns)
 
 	dialer.Resolver = &net.Resolver{
 		PreferGo: true,
@@ -62,3 +77,246 @@ func LookupHost(url string) error {
 	_, err := dialer.Resolver.LookupHost(context.Background(), name)
 	return err
 }
+
+// LookupNS 查找 name 所在区域的权威DNS服务器, 从 name 开始逐级向上查找
+func LookupNS(ctx context.Context, name string) (zone string, servers []string, err error) {
+	name = strings.TrimSuffix(name, ".")
+	for zone = name; strings.Contains(zone, "."); zone = zone[strings.Index(zone, ".")+1:] {
+		var nss []*net.NS
+		nss, err = dialer.Resolver.LookupNS(ctx, zone+".")
+		if err != nil || len(nss) == 0 {
+			if ctx.Err() != nil {
+				return "", nil, ctx.Err()
+			}
+			continue
+		}
+		for _, ns := range nss {
+			servers = append(servers, strings.TrimSuffix(ns.Host, "."))
+		}
+		return zone, servers, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no NS records found for %s", name)
+	}
+	return "", nil, err
+}
+
+// LookupIPAt 直接向DNS服务器 server 查询 name 的地址, network 为 ip4 或 ip6
+func LookupIPAt(ctx context.Context, server, network, name string) ([]net.IP, error) {
+	d := &net.Dialer{Resolver: dialer.Resolver}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, net.JoinHostPort(server, "53"))
+		},
+	}
+	return resolver.LookupIP(ctx, network, strings.TrimSuffix(name, ".")+".")
+}
+
+// MyIPTimeout 通过DNS查询本机公网IP的超时时间
+var MyIPTimeout = 3 * time.Second
+
+// myIPQuery 通过DNS查询本机公网IP的模板, 格式为 name[/type[/class]]@server
+// 如 myip.opendns.com@resolver1.opendns.com, whoami.cloudflare/TXT/CH@tcp://1.1.1.1
+type myIPQuery struct {
+	name    dnsmessage.Name
+	qtype   dnsmessage.Type
+	class   dnsmessage.Class
+	network string
+	server  string
+}
+
+// parseMyIPQuery 解析查询模板, 未指定类型时IPv4查询A记录, IPv6查询AAAA记录
+func parseMyIPQuery(template string, ipv6 bool) (q myIPQuery, err error) {
+	at := strings.LastIndex(template, "@")
+	if at <= 0 || at == len(template)-1 {
+		return q, fmt.Errorf("invalid query %q, expected name@server", template)
+	}
+	q.network, q.server = parseDNSServer(strings.TrimSpace(template[at+1:]))
+
+	parts := strings.Split(strings.TrimSpace(template[:at]), "/")
+	// 超过 255 字节的名称返回错误
+	q.name, err = dnsmessage.NewName(strings.TrimSuffix(parts[0], ".") + ".")
+	if err != nil {
+		return q, fmt.Errorf("invalid query name %q: %w", parts[0], err)
+	}
+	q.qtype, q.class = dnsmessage.TypeA, dnsmessage.ClassINET
+	if ipv6 {
+		q.qtype = dnsmessage.TypeAAAA
+	}
+	if len(parts) > 1 {
+		switch strings.ToUpper(parts[1]) {
+		case "A":
+			q.qtype = dnsmessage.TypeA
+		case "AAAA":
+			q.qtype = dnsmessage.TypeAAAA
+		case "TXT":
+			q.qtype = dnsmessage.TypeTXT
+		default:
+			return q, fmt.Errorf("unsupported query type %q", parts[1])
+		}
+	}
+	if len(parts) > 2 {
+		switch strings.ToUpper(parts[2]) {
+		case "IN":
+			q.class = dnsmessage.ClassINET
+		case "CH":
+			q.class = dnsmessage.ClassCHAOS
+		default:
+			return q, fmt.Errorf("unsupported query class %q", parts[2])
+		}
+	}
+	if len(parts) > 3 {
+		return q, fmt.Errorf("invalid query %q", template)
+	}
+	return q, nil
+}
+
+// CheckMyIPQuery 检查通过DNS查询本机公网IP的模板, 保存配置时调用
+func CheckMyIPQuery(template string) error {
+	_, err := parseMyIPQuery(template, false)
+	return err
+}
+
+// LookupMyIP 按模板向DNS服务器查询本机的公网IP, 如 OpenDNS 的 myip.opendns.com 或 Cloudflare 的 whoami.cloudflare
+// 查询通过 IPv4 或 IPv6 发送, 以获得对应协议的地址; ifaceName 不为空时绑定到指定网卡
+func LookupMyIP(ctx context.Context, template string, ipv6 bool, ifaceName string) (net.IP, error) {
+	q, err := parseMyIPQuery(template, ipv6)
+	if err != nil {
+		return nil, err
+	}
+	network := q.network + "4"
+	if ipv6 {
+		network = q.network + "6"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, MyIPTimeout)
+	defer cancel()
+	conn, err := newBoundDialer(network, ifaceName).DialContext(ctx, network, q.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id := uint16(rand.N(1 << 16))
+	msg, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  q.name,
+			Type:  q.qtype,
+			Class: q.class,
+		}},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []byte
+	if q.network == "tcp" {
+		resp, err = dnsExchangeTCP(conn, msg)
+	} else {
+		resp, err = dnsExchangeUDP(conn, msg, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseMyIPResponse(resp, ipv6)
+}
+
+// dnsExchangeUDP 发送查询并读取ID相同的响应
+func dnsExchangeUDP(conn net.Conn, msg []byte, id uint16) ([]byte, error) {
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// dnsExchangeTCP 发送带长度前缀的查询并读取响应
+func dnsExchangeTCP(conn net.Conn, msg []byte) ([]byte, error) {
+	req := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	if _, err := conn.Write(append(req, msg...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// parseMyIPResponse 返回应答中第一个对应协议的地址, TXT记录中的地址同样有效
+func parseMyIPResponse(resp []byte, ipv6 bool) (net.IP, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(resp)
+	if err != nil {
+		return nil, err
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DNS response code %s", header.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+
+	match := func(ip net.IP) bool {
+		return ip != nil && (ip.To4() == nil) == ipv6
+	}
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch h.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return nil, err
+			}
+			if ip := net.IP(r.A[:]); match(ip) {
+				return ip, nil
+			}
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return nil, err
+			}
+			if ip := net.IP(r.AAAA[:]); match(ip) {
+				return ip, nil
+			}
+		case dnsmessage.TypeTXT:
+			r, err := p.TXTResource()
+			if err != nil {
+				return nil, err
+			}
+			for _, txt := range r.TXT {
+				if ip := net.ParseIP(strings.Trim(strings.TrimSpace(txt), `"`)); match(ip) {
+					return ip, nil
+				}
+			}
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, errors.New("no address found in the DNS response")
+}
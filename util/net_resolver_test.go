@@ -1,6 +1,15 @@
 package util
 
-import "testing"
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
 
 const (
 	testDNS = "1.1.1.1"
@@ -36,3 +45,140 @@ func TestLookupHost(t *testing.T) {
 		}
 	})
 }
+
+// startFakeDNS 启动本地DNS服务器, 按查询的名称、类型和类别应答, 返回监听的地址
+func startFakeDNS(t *testing.T, network, addr string) string {
+	t.Helper()
+	answer := func(req []byte, remote net.IP) []byte {
+		var p dnsmessage.Parser
+		h, err := p.Start(req)
+		if err != nil {
+			return nil
+		}
+		q, err := p.Question()
+		if err != nil {
+			return nil
+		}
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true})
+		b.EnableCompression()
+		b.StartQuestions()
+		b.Question(q)
+		b.StartAnswers()
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 0}
+		switch {
+		case q.Name.String() == "myip.example." && q.Type == dnsmessage.TypeA && remote.To4() != nil:
+			var a [4]byte
+			copy(a[:], remote.To4())
+			b.AResource(rh, dnsmessage.AResource{A: a})
+		case q.Name.String() == "myip.example." && q.Type == dnsmessage.TypeAAAA:
+			var a [16]byte
+			copy(a[:], remote.To16())
+			b.AAAAResource(rh, dnsmessage.AAAAResource{AAAA: a})
+		case q.Name.String() == "whoami.example." && q.Type == dnsmessage.TypeTXT && q.Class == dnsmessage.ClassCHAOS:
+			b.TXTResource(rh, dnsmessage.TXTResource{TXT: []string{remote.String()}})
+		}
+		msg, _ := b.Finish()
+		return msg
+	}
+
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			t.Skipf("listen udp %s: %v", addr, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				n, from, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				conn.WriteTo(answer(buf[:n], from.(*net.UDPAddr).IP), from)
+			}
+		}()
+		return conn.LocalAddr().String()
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("listen tcp %s: %v", addr, err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				req := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, req); err == nil {
+					resp := answer(req, conn.RemoteAddr().(*net.TCPAddr).IP)
+					conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestLookupMyIP(t *testing.T) {
+	udp4 := startFakeDNS(t, "udp", "127.0.0.1:0")
+	tcp4 := startFakeDNS(t, "tcp", "127.0.0.1:0")
+
+	tests := []struct {
+		template string
+		ipv6     bool
+		want     string
+		wantErr  bool
+	}{
+		{"myip.example@" + udp4, false, "127.0.0.1", false},
+		{"whoami.example/TXT/CH@" + udp4, false, "127.0.0.1", false},
+		{"myip.example/A@tcp://" + tcp4, false, "127.0.0.1", false},
+		{"whoami.example/TXT/CH@tcp://" + tcp4, false, "127.0.0.1", false},
+		// 类别不匹配时没有应答
+		{"whoami.example/TXT@" + udp4, false, "", true},
+		{"myip.example/MX@" + udp4, false, "", true},
+		{"myip.example", false, "", true},
+		// 名称过长时返回错误
+		{strings.Repeat("a.", 130) + "example@" + udp4, false, "", true},
+	}
+	for _, tt := range tests {
+		ip, err := LookupMyIP(context.Background(), tt.template, tt.ipv6, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("LookupMyIP(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			continue
+		}
+		if err == nil && ip.String() != tt.want {
+			t.Errorf("LookupMyIP(%q) = %s, want %s", tt.template, ip, tt.want)
+		}
+	}
+
+	udp6 := startFakeDNS(t, "udp", "[::1]:0")
+	for _, template := range []string{"myip.example@" + udp6, "whoami.example/TXT/CH@" + udp6} {
+		ip, err := LookupMyIP(context.Background(), template, true, "")
+		if err != nil || ip.String() != "::1" {
+			t.Errorf("LookupMyIP(%q, ipv6) = %v, %v, want ::1", template, ip, err)
+		}
+	}
+}
+
+func TestParseDNSServer(t *testing.T) {
+	tests := []struct{ in, network, addr string }{
+		{"1.1.1.1", "udp", "1.1.1.1:53"},
+		{"tcp://1.1.1.1", "tcp", "1.1.1.1:53"},
+		{"udp://1.1.1.1:5353", "udp", "1.1.1.1:5353"},
+		{"2606:4700:4700::1111", "udp", "[2606:4700:4700::1111]:53"},
+		{"tcp://[2606:4700:4700::1111]", "tcp", "[2606:4700:4700::1111]:53"},
+		{"resolver1.opendns.com", "udp", "resolver1.opendns.com:53"},
+	}
+	for _, tt := range tests {
+		network, addr := parseDNSServer(tt.in)
+		if network != tt.network || addr != tt.addr {
+			t.Errorf("parseDNSServer(%q) = %s, %s, want %s, %s", tt.in, network, addr, tt.network, tt.addr)
+		}
+	}
+}
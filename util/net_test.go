@@ -1,6 +1,7 @@
 package util
 
 import (
+	"net"
 	"net/http"
 	"testing"
 )
@@ -42,3 +43,19 @@ func TestGetRequestIPStr(t *testing.T) {
 		t.Errorf("GetRequestIPStr failed")
 	}
 }
+
+func TestIsCGNAT(t *testing.T) {
+	data := map[string]bool{
+		"100.64.0.1":      true,
+		"100.127.255.254": true,
+		"100.63.255.255":  false,
+		"100.128.0.1":     false,
+		"192.168.1.1":     false,
+		"2001:db8::1":     false,
+	}
+	for key, value := range data {
+		if IsCGNAT(net.ParseIP(key)) != value {
+			t.Errorf("%s 校验失败\n", key)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	// RetryAttempts 可重试错误的最大尝试次数
+	RetryAttempts = 3
+	// retryBaseDelay 首次重试前的等待时间, 之后每次翻倍
+	retryBaseDelay = time.Second
+	// retryMaxDelay 未指定 Retry-After 时的最长等待时间
+	retryMaxDelay = 30 * time.Second
+)
+
+// Retry 执行 op, 遇到可重试的错误时按带随机抖动的指数退避重试, 服务端返回 Retry-After 时以其为准
+// 非幂等的操作 (如新增记录) 只在请求过于频繁时重试, 避免请求实际已成功时重复执行
+func Retry(ctx context.Context, idempotent bool, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= RetryAttempts || ctx.Err() != nil {
+			return err
+		}
+		kind := ErrorKindOf(err)
+		if !kind.Retryable() || (!idempotent && kind != ErrorRateLimited) {
+			return err
+		}
+
+		delay := retryDelay(err, attempt)
+		// 超时前来不及重试
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		Log("请求失败, %s 后进行第 %d 次重试: %s", delay.Round(time.Millisecond), attempt, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryDelay 第 attempt 次失败后的等待时间
+func retryDelay(err error, attempt int) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	delay := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	if delay < 2 {
+		return delay
+	}
+	// 在 [delay/2, delay) 之间随机, 避免多个配置同时重试
+	return delay/2 + rand.N(delay/2)
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = time.Second })
+
+	transient := NewAPIError(ErrorTransient, errors.New("503"))
+	tests := []struct {
+		name       string
+		idempotent bool
+		err        error
+		want       int
+	}{
+		{"transient", true, transient, RetryAttempts},
+		{"rate limited", false, NewAPIError(ErrorRateLimited, errors.New("429")), RetryAttempts},
+		{"transient not idempotent", false, transient, 1},
+		{"auth", true, NewAPIError(ErrorAuth, errors.New("401")), 1},
+		{"unclassified", true, errors.New("boom"), 1},
+	}
+	for _, tt := range tests {
+		calls := 0
+		err := Retry(context.Background(), tt.idempotent, func() error {
+			calls++
+			return tt.err
+		})
+		if calls != tt.want {
+			t.Errorf("%s: calls = %d, want %d", tt.name, calls, tt.want)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	// 重试成功
+	calls := 0
+	err := Retry(context.Background(), true, func() error {
+		calls++
+		if calls == 1 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("calls = %d, err = %v, want success on second call", calls, err)
+	}
+
+	// Retry-After 超过剩余时间时不再等待
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	calls = 0
+	Retry(ctx, true, func() error {
+		calls++
+		return &APIError{Kind: ErrorRateLimited, RetryAfter: time.Minute, Err: errors.New("429")}
+	})
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 when Retry-After exceeds the deadline", calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := retryDelay(errors.New("boom"), attempt)
+		want := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+		if d < want/2 || d >= want {
+			t.Errorf("retryDelay(attempt %d) = %s, want [%s, %s)", attempt, d, want/2, want)
+		}
+	}
+	if d := retryDelay(&APIError{Kind: ErrorRateLimited, RetryAfter: 42 * time.Second, Err: errors.New("429")}, 1); d != 42*time.Second {
+		t.Errorf("retryDelay with Retry-After = %s, want 42s", d)
+	}
+}
@@ -0,0 +1,194 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// RouterTimeout 每种方式查询路由器的超时时间
+	RouterTimeout = 3 * time.Second
+	// natpmpPort NAT-PMP/PCP 端口, 可在测试中替换
+	natpmpPort = 5351
+	// natpmpRetransmit 首次重发请求前的等待时间, 之后每次翻倍
+	natpmpRetransmit = 250 * time.Millisecond
+	// defaultGateway 可在测试中替换
+	defaultGateway = defaultGatewayIPv4
+)
+
+// RouterAddr 从路由器获取WAN地址
+// 先通过SSDP发现UPnP IGD并调用 GetExternalIPAddress, 失败时向网关发送 NAT-PMP 请求, 网关只支持PCP时使用PCP
+func RouterAddr(ctx context.Context, ifaceName string) (net.IP, error) {
+	ip, gateway, upnpErr := upnpExternalAddr(ctx, ifaceName)
+	if upnpErr == nil {
+		return ip, nil
+	}
+	if gateway == nil {
+		var err error
+		if gateway, err = defaultGateway(ifaceName); err != nil {
+			return nil, fmt.Errorf("UPnP: %v; NAT-PMP/PCP: %v", upnpErr, err)
+		}
+	}
+	ip, err := natpmpExternalAddr(ctx, gateway, ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("UPnP: %v; NAT-PMP/PCP: %v", upnpErr, err)
+	}
+	return ip, nil
+}
+
+// natpmpExternalAddr 发送 NAT-PMP 公网地址请求 (RFC 6886), 网关返回PCP版本时改用PCP (RFC 6887)
+func natpmpExternalAddr(ctx context.Context, gateway net.IP, ifaceName string) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, RouterTimeout)
+	defer cancel()
+
+	conn, err := listenUDP(ctx, "udp4", ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	dst := &net.UDPAddr{IP: gateway, Port: natpmpPort}
+
+	resp, err := udpExchange(ctx, conn, dst, []byte{0, 0}, func(resp []byte) bool {
+		return len(resp) >= 2 && (resp[0] == 2 || resp[1] == 128)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// PCP服务器以自己的版本响应不支持的版本
+	if resp[0] == 2 {
+		return pcpExternalAddr(ctx, conn, dst)
+	}
+	if len(resp) < 12 {
+		return nil, errors.New("NAT-PMP response too short")
+	}
+	if code := binary.BigEndian.Uint16(resp[2:]); code != 0 {
+		return nil, fmt.Errorf("NAT-PMP result code %d", code)
+	}
+	return net.IP(bytes.Clone(resp[8:12])), nil
+}
+
+const (
+	pcpVersion    = 2
+	pcpOpMap      = 1
+	pcpHeaderSize = 24
+	pcpMapSize    = 36
+	// pcpLifetime 临时映射的有效期(秒), 获取地址后立即删除
+	pcpLifetime = 30
+)
+
+// pcpExternalAddr 为本地端口请求一个临时的UDP映射, 从响应中获取分配的公网地址, 之后删除该映射
+func pcpExternalAddr(ctx context.Context, conn net.PacketConn, dst *net.UDPAddr) (net.IP, error) {
+	local, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, errors.New("PCP requires a UDP socket")
+	}
+	clientIP := local.IP
+	if clientIP.IsUnspecified() {
+		// 未绑定地址时, 使用连接网关的本地地址
+		c, err := net.DialUDP("udp4", nil, dst)
+		if err != nil {
+			return nil, err
+		}
+		clientIP = c.LocalAddr().(*net.UDPAddr).IP
+		c.Close()
+	}
+
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	req := pcpMapRequest(clientIP, nonce, uint16(local.Port), pcpLifetime)
+	resp, err := udpExchange(ctx, conn, dst, req, func(resp []byte) bool {
+		return len(resp) >= pcpHeaderSize+pcpMapSize && resp[1] == 0x80|pcpOpMap &&
+			bytes.Equal(resp[pcpHeaderSize:pcpHeaderSize+12], nonce)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code := resp[3]; code != 0 {
+		return nil, fmt.Errorf("PCP result code %d", code)
+	}
+	ip := net.IP(bytes.Clone(resp[pcpHeaderSize+20 : pcpHeaderSize+36]))
+
+	// 删除临时映射, 失败时等待其过期
+	conn.WriteTo(pcpMapRequest(clientIP, nonce, uint16(local.Port), 0), dst)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return ip, nil
+}
+
+// pcpMapRequest 构造 MAP 请求, lifetime 为 0 时删除映射
+func pcpMapRequest(clientIP net.IP, nonce []byte, port uint16, lifetime uint32) []byte {
+	req := make([]byte, pcpHeaderSize+pcpMapSize)
+	req[0] = pcpVersion
+	req[1] = pcpOpMap
+	binary.BigEndian.PutUint32(req[4:], lifetime)
+	copy(req[8:24], clientIP.To16())
+
+	m := req[pcpHeaderSize:]
+	copy(m[0:12], nonce)
+	m[12] = 17 // UDP
+	binary.BigEndian.PutUint16(m[16:], port)
+	binary.BigEndian.PutUint16(m[18:], port)
+	// 建议的公网地址为 ::ffff:0.0.0.0, 由网关分配
+	copy(m[20:36], net.IPv4zero.To16())
+	return req
+}
+
+// udpExchange 发送请求并等待 accept 接受的响应, 未收到响应时按退避时间重发
+func udpExchange(ctx context.Context, conn net.PacketConn, dst net.Addr, req []byte, accept func([]byte) bool) ([]byte, error) {
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	rto := natpmpRetransmit
+	buf := make([]byte, 1100)
+	for {
+		if _, err := conn.WriteTo(req, dst); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(readDeadline(ctx, rto))
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+					break
+				}
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("%s: %w", dst, ctx.Err())
+				}
+				return nil, err
+			}
+			if accept(buf[:n]) {
+				return bytes.Clone(buf[:n]), nil
+			}
+		}
+		rto *= 2
+	}
+}
+
+// listenUDP 监听UDP, ifaceName 不为空时绑定到指定网卡
+func listenUDP(ctx context.Context, network, ifaceName string) (net.PacketConn, error) {
+	var lc net.ListenConfig
+	addr := ""
+	if ifaceName != "" {
+		localIP, err := getLocalAddrFromInterfaceByNetwork(ifaceName, strings.Replace(network, "udp", "tcp", 1))
+		if err != nil {
+			Log("绑定网卡失败, 将使用默认网卡. 网卡: %s, 错误: %v", ifaceName, err)
+		} else {
+			addr = net.JoinHostPort(localIP, "0")
+			d := &net.Dialer{}
+			setLinuxBindToDevice(d, ifaceName)
+			lc.Control = d.Control
+		}
+	}
+	return lc.ListenPacket(ctx, network, addr)
+}
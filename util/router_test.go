@@ -0,0 +1,169 @@
+package util
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const igdDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl/IPConn</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+// startFakeIGD 启动本地的UPnP IGD, 包括SSDP响应和设备描述及控制接口
+func startFakeIGD(t *testing.T, externalIP string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rootDesc.xml", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, igdDescription)
+	})
+	mux.HandleFunc("/ctl/IPConn", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("SOAPAction") != `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"` {
+			http.Error(w, "unknown action", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+<NewExternalIPAddress>%s</NewExternalIPAddress>
+</u:GetExternalIPAddressResponse></s:Body></s:Envelope>`, externalIP)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(string(buf[:n]), "M-SEARCH") {
+				continue
+			}
+			resp := "HTTP/1.1 200 OK\r\n" +
+				"CACHE-CONTROL: max-age=120\r\n" +
+				"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+				"LOCATION: " + srv.URL + "/rootDesc.xml\r\n\r\n"
+			conn.WriteTo([]byte(resp), from)
+		}
+	}()
+
+	orig := ssdpAddr
+	ssdpAddr = conn.LocalAddr().String()
+	t.Cleanup(func() { ssdpAddr = orig })
+}
+
+// startFakeNATPMP 启动本地的 NAT-PMP 或 PCP 网关, 无SSDP响应
+func startFakeNATPMP(t *testing.T, pcp bool, externalIP net.IP) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1100)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req := buf[:n]
+			switch {
+			case pcp && req[0] == 0:
+				// 只支持PCP, 返回 UNSUPP_VERSION
+				conn.WriteTo([]byte{2, 0x80, 0, 1, 0, 0, 0, 0}, from)
+			case pcp && req[0] == 2 && len(req) == pcpHeaderSize+pcpMapSize:
+				resp := make([]byte, pcpHeaderSize+pcpMapSize)
+				resp[0], resp[1] = 2, 0x80|pcpOpMap
+				copy(resp[4:8], req[4:8])
+				copy(resp[pcpHeaderSize:], req[pcpHeaderSize:])
+				copy(resp[pcpHeaderSize+20:], externalIP.To16())
+				conn.WriteTo(resp, from)
+			case !pcp && n == 2 && req[0] == 0 && req[1] == 0:
+				resp := make([]byte, 12)
+				resp[1] = 128
+				binary.BigEndian.PutUint32(resp[4:], 100)
+				copy(resp[8:], externalIP.To4())
+				conn.WriteTo(resp, from)
+			}
+		}
+	}()
+
+	origSSDP, origPort, origGateway := ssdpAddr, natpmpPort, defaultGateway
+	// SSDP 发往未监听的端口, 没有响应
+	ssdpAddr = "127.0.0.1:9"
+	natpmpPort = conn.LocalAddr().(*net.UDPAddr).Port
+	defaultGateway = func(string) (net.IP, error) { return net.IPv4(127, 0, 0, 1), nil }
+	t.Cleanup(func() { ssdpAddr, natpmpPort, defaultGateway = origSSDP, origPort, origGateway })
+}
+
+func TestRouterAddr(t *testing.T) {
+	defer func(d time.Duration) { RouterTimeout = d }(RouterTimeout)
+	RouterTimeout = 300 * time.Millisecond
+
+	tests := []struct {
+		name  string
+		start func(t *testing.T)
+		want  string
+	}{
+		{"UPnP", func(t *testing.T) { startFakeIGD(t, "203.0.113.7") }, "203.0.113.7"},
+		{"NAT-PMP", func(t *testing.T) { startFakeNATPMP(t, false, net.ParseIP("203.0.113.8")) }, "203.0.113.8"},
+		{"PCP", func(t *testing.T) { startFakeNATPMP(t, true, net.ParseIP("203.0.113.9")) }, "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.start(t)
+			ip, err := RouterAddr(context.Background(), "")
+			if err != nil {
+				t.Fatalf("RouterAddr() error: %v", err)
+			}
+			if ip.String() != tt.want {
+				t.Errorf("RouterAddr() = %s, want %s", ip, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterAddrNoGateway(t *testing.T) {
+	defer func(d time.Duration) { RouterTimeout = d }(RouterTimeout)
+	RouterTimeout = 200 * time.Millisecond
+	startFakeNATPMP(t, false, net.ParseIP("203.0.113.8"))
+	defaultGateway = func(string) (net.IP, error) { return nil, fmt.Errorf("no default gateway found") }
+
+	if _, err := RouterAddr(context.Background(), ""); err == nil {
+		t.Error("RouterAddr() expected error without a gateway")
+	}
+}
@@ -0,0 +1,158 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 更新计划
+type Schedule interface {
+	// Next 返回 t 之后的下次运行时间
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule 解析更新计划, 支持间隔 (如 300, 90s, 1h) 或 5 段 cron 表达式 (分 时 日 月 周)
+func ParseSchedule(s string) (Schedule, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.Atoi(s); err == nil {
+		return intervalSchedule(time.Duration(seconds) * time.Second).check(s)
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return intervalSchedule(d).check(s)
+	}
+	return parseCron(s)
+}
+
+// intervalSchedule 固定间隔
+type intervalSchedule time.Duration
+
+func (i intervalSchedule) check(s string) (Schedule, error) {
+	if i < intervalSchedule(time.Second) {
+		return nil, fmt.Errorf("invalid interval %q", s)
+	}
+	return i, nil
+}
+
+func (i intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// cronSchedule cron 表达式, 每段为允许的值的位图
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// 日和周都被限制时, 满足其一即可, 与 cron 一致
+	domStar, dowStar bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseCron(s string) (Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: want an interval or 5 cron fields", s)
+	}
+
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in schedule %q: %w", cronFields[i].name, s, err)
+		}
+		bits[i] = b
+	}
+	// 周日可以是 0 或 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	c := &cronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*",
+	}
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", s)
+	}
+	return c, nil
+}
+
+// parseCronField 解析单段, 支持 *, a, a-b, */n, a-b/n 以及逗号分隔的列表
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	for part := range strings.SplitSeq(field, ",") {
+		rangePart, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			rangePart = before
+			if step, err = strconv.Atoi(after); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			before, after, isRange := strings.Cut(rangePart, "-")
+			if lo, err = strconv.Atoi(before); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(after); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				// a/n 表示从 a 开始到最大值
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// 最多查找 5 年, 如 2 月 30 日这样的表达式不会匹配
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
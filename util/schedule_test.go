@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC) // 周三
+
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{"60", base.Add(time.Minute)},
+		{"90s", base.Add(90 * time.Second)},
+		{"1h", base.Add(time.Hour)},
+		{"* * * * *", time.Date(2024, 1, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"5,45 9-17 * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2024, 2, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 2, 4, 12, 0, 0, 0, time.UTC)},
+		// 日和周都被限制时满足其一即可
+		{"0 0 15 * 5", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.schedule)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) error: %s", tt.schedule, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("ParseSchedule(%q).Next() = %s, want %s", tt.schedule, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "0", "-5", "500ms", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "0 0 30 2 *", "a * * * *"} {
+		if _, err := ParseSchedule(invalid); err == nil {
+			t.Errorf("ParseSchedule(%q) should fail", invalid)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// soapCall 调用UPnP/TR-064的SOAP接口, 返回响应中的参数
+// 只供从路由器获取IP (UPnP IGD) 和 FRITZ!Box 使用, 不作为单独的获取方式
+// username 不为空时使用 HTTP Digest 认证
+func soapCall(ctx context.Context, client *http.Client, controlURL, serviceType, action, username, password string) (map[string]string, error) {
+	body := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + action + ` xmlns:u="` + serviceType + `"/></s:Body></s:Envelope>`
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+		req.Header.Set("SOAPAction", `"`+serviceType+`#`+action+`"`)
+		return req, nil
+	}
+
+	var resp *http.Response
+	var err error
+	if username != "" {
+		resp, err = doDigest(client, newRequest, username, password)
+	} else {
+		var req *http.Request
+		if req, err = newRequest(); err == nil {
+			resp, err = client.Do(req)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024000))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, bytes.TrimSpace(data))
+	}
+	return parseSoapResponse(data, action)
+}
+
+// parseSoapResponse 读取 <actionResponse> 中的参数
+func parseSoapResponse(data []byte, action string) (map[string]string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("SOAP response has no %sResponse: %w", action, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != action+"Response" {
+			continue
+		}
+
+		var resp struct {
+			Args []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		}
+		if err := dec.DecodeElement(&resp, &start); err != nil {
+			return nil, err
+		}
+		args := make(map[string]string, len(resp.Args))
+		for _, arg := range resp.Args {
+			args[arg.XMLName.Local] = strings.TrimSpace(arg.Value)
+		}
+		return args, nil
+	}
+}
@@ -36,6 +36,16 @@ func SplitLines(s string) []string {
 	return strings.Split(s, "\n")
 }
 
+// SplitComma splits a comma-separated list, trimming spaces and dropping empty items.
+func SplitComma(s string) (result []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return
+}
+
 func PercentEncode(value string) string {
 	if value == "" {
 		return ""
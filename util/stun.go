@@ -0,0 +1,159 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112A442
+	stunHeaderSize      = 20
+	stunXorMappedAddr   = 0x0020
+	stunDefaultPort     = "3478"
+)
+
+var (
+	// StunTimeout 单个STUN服务器的超时时间
+	StunTimeout = 3 * time.Second
+	// stunRetransmit 首次重发请求前的等待时间, 之后每次翻倍
+	stunRetransmit = 500 * time.Millisecond
+)
+
+// StunAddr 向STUN服务器发送 Binding 请求 (RFC 5389), 返回 XOR-MAPPED-ADDRESS 中的公网地址
+// network 为 udp4 或 udp6, ifaceName 不为空时绑定到指定网卡
+func StunAddr(ctx context.Context, network, ifaceName, server string) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, StunTimeout)
+	defer cancel()
+
+	conn, err := newBoundDialer(network, ifaceName).DialContext(ctx, network, stunServerAddr(server))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// ctx 取消时立即结束读取
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	req := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+	if _, err := rand.Read(req[8:stunHeaderSize]); err != nil {
+		return nil, err
+	}
+
+	// UDP可能丢包, 未收到响应时按退避时间重发请求
+	rto := stunRetransmit
+	buf := make([]byte, 1500)
+	for {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(readDeadline(ctx, rto))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+					break
+				}
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("STUN %s: %w", server, ctx.Err())
+				}
+				return nil, err
+			}
+			ip, err := parseStunResponse(buf[:n], req[8:stunHeaderSize])
+			if err != nil {
+				// 忽略其他请求的响应
+				continue
+			}
+			return ip, nil
+		}
+		rto *= 2
+	}
+}
+
+// readDeadline 等待 rto 后重发, 不超过 ctx 的截止时间
+func readDeadline(ctx context.Context, rto time.Duration) time.Time {
+	t := time.Now().Add(rto)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(t) {
+		return deadline
+	}
+	return t
+}
+
+// stunServerAddr 补全STUN服务器的端口, 支持 stun: 前缀
+func stunServerAddr(server string) string {
+	server = strings.TrimPrefix(strings.TrimSpace(server), "stun:")
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), stunDefaultPort)
+}
+
+// parseStunResponse 解析 Binding 成功响应, 返回 XOR-MAPPED-ADDRESS 中的地址
+func parseStunResponse(msg, txID []byte) (net.IP, error) {
+	if len(msg) < stunHeaderSize {
+		return nil, errors.New("STUN response too short")
+	}
+	if binary.BigEndian.Uint16(msg[0:]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie ||
+		string(msg[8:stunHeaderSize]) != string(txID) {
+		return nil, errors.New("unexpected STUN message")
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:]))
+	if stunHeaderSize+length > len(msg) {
+		return nil, errors.New("STUN response truncated")
+	}
+
+	attrs := msg[stunHeaderSize : stunHeaderSize+length]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+attrLen > len(attrs) {
+			break
+		}
+		value := attrs[4 : 4+attrLen]
+		if attrType == stunXorMappedAddr {
+			return parseXorMappedAddr(value, msg[4:stunHeaderSize])
+		}
+		// 属性按4字节对齐
+		next := 4 + (attrLen+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+	return nil, errors.New("STUN response has no XOR-MAPPED-ADDRESS")
+}
+
+// parseXorMappedAddr 解析 XOR-MAPPED-ADDRESS, key 为 magic cookie 和 transaction ID
+func parseXorMappedAddr(value, key []byte) (net.IP, error) {
+	if len(value) < 4 {
+		return nil, errors.New("invalid XOR-MAPPED-ADDRESS")
+	}
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil, fmt.Errorf("unknown XOR-MAPPED-ADDRESS family %d", value[1])
+	}
+	if len(value) < 4+size {
+		return nil, errors.New("invalid XOR-MAPPED-ADDRESS")
+	}
+	ip := make(net.IP, size)
+	for i := range ip {
+		ip[i] = value[4+i] ^ key[i]
+	}
+	return ip, nil
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// startStunServer 启动本地STUN服务器, 返回请求方的地址, drop 为丢弃的请求数
+func startStunServer(t *testing.T, network, addr string, drop int) string {
+	t.Helper()
+	conn, err := net.ListenPacket(network, addr)
+	if err != nil {
+		t.Skipf("listen %s: %v", network, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if drop > 0 {
+				drop--
+				continue
+			}
+			if n < stunHeaderSize || binary.BigEndian.Uint16(buf) != stunBindingRequest {
+				continue
+			}
+			conn.WriteTo(stunResponse(buf[8:stunHeaderSize], from.(*net.UDPAddr)), from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// stunResponse 构造包含 XOR-MAPPED-ADDRESS 的成功响应, 前面附带一个其他属性
+func stunResponse(txID []byte, from *net.UDPAddr) []byte {
+	ip, family := from.IP.To4(), byte(0x01)
+	if ip == nil {
+		ip, family = from.IP.To16(), 0x02
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint32(key, stunMagicCookie)
+	copy(key[4:], txID)
+
+	// SOFTWARE 属性, 长度需要补齐
+	attrs := []byte{0x80, 0x22, 0x00, 0x03, 'a', 'b', 'c', 0x00}
+	xor := []byte{0x00, 0x20, 0x00, byte(4 + len(ip)), 0x00, family, 0x00, 0x00}
+	binary.BigEndian.PutUint16(xor[6:], uint16(from.Port)^uint16(stunMagicCookie>>16))
+	for i := range ip {
+		xor = append(xor, ip[i]^key[i])
+	}
+	attrs = append(attrs, xor...)
+
+	msg := make([]byte, stunHeaderSize, stunHeaderSize+len(attrs))
+	binary.BigEndian.PutUint16(msg, stunBindingResponse)
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(attrs)))
+	binary.BigEndian.PutUint32(msg[4:], stunMagicCookie)
+	copy(msg[8:], txID)
+	return append(msg, attrs...)
+}
+
+func TestStunAddr(t *testing.T) {
+	defer func(d time.Duration) { stunRetransmit = d }(stunRetransmit)
+	stunRetransmit = 50 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		network string
+		listen  string
+		drop    int
+		want    string
+	}{
+		{"IPv4", "udp4", "127.0.0.1:0", 0, "127.0.0.1"},
+		{"IPv4 retransmit", "udp4", "127.0.0.1:0", 1, "127.0.0.1"},
+		{"IPv6", "udp6", "[::1]:0", 0, "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startStunServer(t, tt.network, tt.listen, tt.drop)
+			ip, err := StunAddr(context.Background(), tt.network, "", server)
+			if err != nil {
+				t.Fatalf("StunAddr() error: %v", err)
+			}
+			if ip.String() != tt.want {
+				t.Errorf("StunAddr() = %s, want %s", ip, tt.want)
+			}
+		})
+	}
+}
+
+func TestStunAddrTimeout(t *testing.T) {
+	defer func(d time.Duration) { StunTimeout = d }(StunTimeout)
+	StunTimeout = 200 * time.Millisecond
+
+	// 丢弃全部请求
+	server := startStunServer(t, "udp4", "127.0.0.1:0", 1<<30)
+	if _, err := StunAddr(context.Background(), "udp4", "", server); err == nil {
+		t.Error("StunAddr() expected timeout error")
+	}
+}
+
+func TestStunServerAddr(t *testing.T) {
+	tests := map[string]string{
+		"stun.example.com":            "stun.example.com:3478",
+		"stun:stun.example.com:19302": "stun.example.com:19302",
+		"2001:db8::1":                 "[2001:db8::1]:3478",
+		"[2001:db8::1]":               "[2001:db8::1]:3478",
+		"[2001:db8::1]:5349":          "[2001:db8::1]:5349",
+	}
+	for in, want := range tests {
+		if got := stunServerAddr(in); got != want {
+			t.Errorf("stunServerAddr(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ssdpAddr SSDP组播地址, 可在测试中替换
+var ssdpAddr = "239.255.255.250:1900"
+
+// igdServiceTypes 可获取WAN地址的服务, 按优先级排列
+var igdServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// find 在设备及其子设备中查找指定类型的服务
+func (d upnpDevice) find(serviceType string) (upnpService, bool) {
+	for _, s := range d.Services {
+		if s.ServiceType == serviceType {
+			return s, true
+		}
+	}
+	for _, child := range d.Devices {
+		if s, ok := child.find(serviceType); ok {
+			return s, true
+		}
+	}
+	return upnpService{}, false
+}
+
+// upnpExternalAddr 通过SSDP发现UPnP IGD并调用 GetExternalIPAddress
+// 返回的 gateway 为响应SSDP的设备地址, 失败时可用于 NAT-PMP/PCP
+func upnpExternalAddr(ctx context.Context, ifaceName string) (ip, gateway net.IP, err error) {
+	location, gateway, err := ssdpDiscover(ctx, ifaceName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := CreateBoundNoProxyHTTPClient("tcp4", ifaceName)
+	client.Timeout = RouterTimeout
+	serviceType, controlURL, err := upnpControlURL(ctx, client, location)
+	if err != nil {
+		return nil, gateway, err
+	}
+	ip, err = upnpGetExternalIPAddress(ctx, client, serviceType, controlURL)
+	return ip, gateway, err
+}
+
+// ssdpDiscover 发送 M-SEARCH, 返回第一个响应的设备描述地址
+func ssdpDiscover(ctx context.Context, ifaceName string) (location string, gateway net.IP, err error) {
+	ctx, cancel := context.WithTimeout(ctx, RouterTimeout)
+	defer cancel()
+
+	conn, err := listenUDP(ctx, "udp4", ifaceName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, st := range []string{
+		"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+		"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+	} {
+		msg := "M-SEARCH * HTTP/1.1\r\n" +
+			"HOST: 239.255.255.250:1900\r\n" +
+			"ST: " + st + "\r\n" +
+			"MAN: \"ssdp:discover\"\r\n" +
+			"MX: 2\r\n\r\n"
+		if _, err := conn.WriteTo([]byte(msg), dst); err != nil {
+			return "", nil, err
+		}
+	}
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, errors.New("no UPnP IGD found")
+			}
+			return "", nil, err
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if location := resp.Header.Get("Location"); location != "" {
+			return location, from.(*net.UDPAddr).IP, nil
+		}
+	}
+}
+
+// upnpControlURL 读取设备描述, 返回可获取WAN地址的服务及其控制地址
+func upnpControlURL(ctx context.Context, client *http.Client, location string) (serviceType, controlURL string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("UPnP description %s: %s", location, resp.Status)
+	}
+
+	var root upnpRoot
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1024000)).Decode(&root); err != nil {
+		return "", "", err
+	}
+	base := location
+	if root.URLBase != "" {
+		base = root.URLBase
+	}
+	for _, t := range igdServiceTypes {
+		if s, ok := root.Device.find(t); ok {
+			u, err := url.Parse(base)
+			if err != nil {
+				return "", "", err
+			}
+			ref, err := u.Parse(strings.TrimSpace(s.ControlURL))
+			if err != nil {
+				return "", "", err
+			}
+			return t, ref.String(), nil
+		}
+	}
+	return "", "", fmt.Errorf("UPnP device %s has no WANIPConnection service", location)
+}
+
+// upnpGetExternalIPAddress 调用 GetExternalIPAddress
+func upnpGetExternalIPAddress(ctx context.Context, client *http.Client, serviceType, controlURL string) (net.IP, error) {
+	args, err := soapCall(ctx, client, controlURL, serviceType, "GetExternalIPAddress", "", "")
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(args["NewExternalIPAddress"])
+	if ip == nil || ip.IsUnspecified() {
+		return nil, fmt.Errorf("GetExternalIPAddress returned %q", args["NewExternalIPAddress"])
+	}
+	return ip, nil
+}
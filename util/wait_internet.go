@@ -1,17 +1,18 @@
 package util
 
 import (
+	"context"
 	"strings"
 	"time"
 )
 
-// Wait blocks until the Internet is connected.
+// Wait blocks until the Internet is connected or ctx is canceled.
 //
 // See also:
 //
 //   - https://stackoverflow.com/a/50058255
 //   - https://github.com/ddev/ddev/blob/v1.22.7/pkg/globalconfig/global_config.go#L776
-func WaitInternet(addresses []string) {
+func WaitInternet(ctx context.Context, addresses []string) {
 	delay := time.Second * 5
 	retryTimes := 0
 	failed := false
@@ -39,7 +40,11 @@ func WaitInternet(addresses []string) {
 				retryTimes = retryTimes + 1
 			}
 
-			time.Sleep(delay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
 		}
 	}
 }
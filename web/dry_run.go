@@ -0,0 +1,17 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/jeessy2/ddns-go/v6/dns"
+)
+
+// DryRun 查询DNS服务商并返回计划, 不会修改任何记录
+func DryRun(writer http.ResponseWriter, request *http.Request) {
+	entries, err := dns.DryRun(request.Context())
+	if err != nil {
+		returnError(writer, err.Error())
+		return
+	}
+	returnOK(writer, "", entries)
+}
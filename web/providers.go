@@ -0,0 +1,25 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/jeessy2/ddns-go/v6/dns"
+)
+
+// providersJSON 服务商在 init 中注册, 运行期间不会改变, 只需序列化一次
+var providersJSON = sync.OnceValue(func() []byte {
+	byt, err := json.Marshal(dns.Providers())
+	if err != nil {
+		// 元数据只包含字符串和数字, 序列化失败说明注册的服务商有误
+		panic("web: marshal DNS providers: " + err.Error())
+	}
+	return byt
+})
+
+// Providers 返回所有DNS服务商的元数据
+func Providers(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(providersJSON())
+}
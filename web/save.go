@@ -1,8 +1,11 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/jeessy2/ddns-go/v6/config"
@@ -79,6 +82,12 @@ func checkAndSave(request *http.Request) string {
 		if v == empty {
 			continue
 		}
+		// 未知的DNS服务商直接拒绝保存
+		provider, ok := dns.GetProvider(v.DnsName)
+		if !ok {
+			return util.LogStr("第 %s 个配置的DNS服务商 %s 不存在", util.Ordinal(k+1, conf.Lang), v.DnsName)
+		}
+
 		dnsConf := config.DnsConfig{Name: v.Name, TTL: v.TTL}
 		// 覆盖以前的配置
 		dnsConf.DNS.Name = v.DnsName
@@ -93,18 +102,88 @@ func checkAndSave(request *http.Request) string {
 		dnsConf.Ipv4.Enable = v.Ipv4Enable
 		dnsConf.Ipv4.GetType = v.Ipv4GetType
 		dnsConf.Ipv4.URL = strings.TrimSpace(v.Ipv4Url)
+		dnsConf.Ipv4.URLQuorum = strings.TrimSpace(v.Ipv4UrlQuorum)
 		dnsConf.Ipv4.NetInterface = v.Ipv4NetInterface
 		dnsConf.Ipv4.Cmd = strings.TrimSpace(v.Ipv4Cmd)
+		dnsConf.Ipv4.Stun = strings.TrimSpace(v.Ipv4Stun)
+		dnsConf.Ipv4.DNSQuery = strings.TrimSpace(v.Ipv4DNSQuery)
 		dnsConf.Ipv4.Domains = util.SplitLines(v.Ipv4Domains)
+		dnsConf.Ipv4.Fallback = strings.TrimSpace(v.Ipv4Fallback)
+		dnsConf.Ipv4.MultiAddr = v.Ipv4MultiAddr
 
 		dnsConf.Ipv6.Enable = v.Ipv6Enable
 		dnsConf.Ipv6.GetType = v.Ipv6GetType
 		dnsConf.Ipv6.URL = strings.TrimSpace(v.Ipv6Url)
+		dnsConf.Ipv6.URLQuorum = strings.TrimSpace(v.Ipv6UrlQuorum)
 		dnsConf.Ipv6.NetInterface = v.Ipv6NetInterface
 		dnsConf.Ipv6.Cmd = strings.TrimSpace(v.Ipv6Cmd)
+		dnsConf.Ipv6.Stun = strings.TrimSpace(v.Ipv6Stun)
+		dnsConf.Ipv6.DNSQuery = strings.TrimSpace(v.Ipv6DNSQuery)
+		dnsConf.Fritzbox.URL = strings.TrimSpace(v.FritzboxURL)
+		dnsConf.Fritzbox.Username = strings.TrimSpace(v.FritzboxUsername)
+		dnsConf.Fritzbox.Password = v.FritzboxPassword
 		dnsConf.Ipv6.Ipv6Reg = strings.TrimSpace(v.Ipv6Reg)
+		dnsConf.Ipv6.Select = strings.TrimSpace(v.Ipv6Select)
 		dnsConf.Ipv6.Domains = util.SplitLines(v.Ipv6Domains)
+		dnsConf.Ipv6.Fallback = strings.TrimSpace(v.Ipv6Fallback)
+		dnsConf.Ipv6.MultiAddr = v.Ipv6MultiAddr
 		dnsConf.HttpInterface = strings.TrimSpace(v.HttpInterface)
+		dnsConf.Timeout = strings.TrimSpace(v.Timeout)
+		dnsConf.StaleTimes = strings.TrimSpace(v.StaleTimes)
+		dnsConf.StaleAction = v.StaleAction
+		dnsConf.StableTimes = strings.TrimSpace(v.StableTimes)
+		dnsConf.StableSeconds = strings.TrimSpace(v.StableSeconds)
+		dnsConf.Schedule = strings.TrimSpace(v.Schedule)
+		dnsConf.VerifyPublish = v.VerifyPublish
+		dnsConf.Precheck = v.Precheck
+		dnsConf.PrecheckResolver = strings.TrimSpace(v.PrecheckResolver)
+		dnsConf.AddrFilter.Private = v.AddrFilterPrivate
+		dnsConf.AddrFilter.CGNAT = v.AddrFilterCGNAT
+		dnsConf.AddrFilter.Allow = strings.TrimSpace(v.AddrFilterAllow)
+		dnsConf.AddrFilter.Deny = strings.TrimSpace(v.AddrFilterDeny)
+		if !provider.ManagesRecords() && (dnsConf.GetStaleTimes() > 0 || dnsConf.Ipv4.MultiAddr || dnsConf.Ipv6.MultiAddr) {
+			return util.LogStr("第 %s 个配置的DNS服务商 %s 不支持处理旧记录或多地址", util.Ordinal(k+1, conf.Lang), v.DnsName)
+		}
+		if dnsConf.GetStaleTimes() > 0 && dnsConf.StaleAction == config.StaleActionFallback {
+			if dnsConf.Ipv4.Enable && len(dnsConf.Ipv4.Domains) > 0 {
+				if err := checkFallback(util.Ordinal(k+1, conf.Lang), "IPv4", dnsConf.Ipv4.Fallback); err != "" {
+					return err
+				}
+			}
+			if dnsConf.Ipv6.Enable && len(dnsConf.Ipv6.Domains) > 0 {
+				if err := checkFallback(util.Ordinal(k+1, conf.Lang), "IPv6", dnsConf.Ipv6.Fallback); err != "" {
+					return err
+				}
+			}
+		}
+		if _, err := dnsConf.GetSchedule(); err != nil {
+			return util.LogStr("第 %s 个配置的更新计划无效: %s", util.Ordinal(k+1, conf.Lang), err)
+		}
+		if err := checkURLQuorum(util.Ordinal(k+1, conf.Lang), "IPv4", dnsConf.Ipv4.GetType, dnsConf.Ipv4.URL, dnsConf.Ipv4.URLQuorum); err != "" {
+			return err
+		}
+		if err := checkURLQuorum(util.Ordinal(k+1, conf.Lang), "IPv6", dnsConf.Ipv6.GetType, dnsConf.Ipv6.URL, dnsConf.Ipv6.URLQuorum); err != "" {
+			return err
+		}
+
+		if err := checkURLSources(util.Ordinal(k+1, conf.Lang), "IPv4", dnsConf.Ipv4.GetType, dnsConf.Ipv4.URL); err != "" {
+			return err
+		}
+		if err := checkURLSources(util.Ordinal(k+1, conf.Lang), "IPv6", dnsConf.Ipv6.GetType, dnsConf.Ipv6.URL); err != "" {
+			return err
+		}
+		if err := checkDNSQuery(util.Ordinal(k+1, conf.Lang), "IPv4", dnsConf.Ipv4.GetType, dnsConf.Ipv4.DNSQuery); err != "" {
+			return err
+		}
+		if err := checkDNSQuery(util.Ordinal(k+1, conf.Lang), "IPv6", dnsConf.Ipv6.GetType, dnsConf.Ipv6.DNSQuery); err != "" {
+			return err
+		}
+		if err := dnsConf.CheckAddrFilter(); err != nil {
+			return util.LogStr("第 %s 个配置的地址校验规则无效: %s", util.Ordinal(k+1, conf.Lang), err)
+		}
+		if err := config.CheckIpv6Select(dnsConf.Ipv6.Select); err != nil {
+			return util.LogStr("第 %s 个配置的IPv6选择策略无效: %s", util.Ordinal(k+1, conf.Lang), err)
+		}
 
 		if k < len(conf.DnsConf) {
 			c := &conf.DnsConf[k]
@@ -115,6 +194,9 @@ func checkAndSave(request *http.Request) string {
 			if dnsConf.DNS.Secret == secretHide {
 				dnsConf.DNS.Secret = c.DNS.Secret
 			}
+			if dnsConf.Fritzbox.Password != "" && dnsConf.Fritzbox.Password == hidePassword(c.Fritzbox.Password) {
+				dnsConf.Fritzbox.Password = c.Fritzbox.Password
+			}
 		}
 
 		dnsConfArray = append(dnsConfArray, dnsConf)
@@ -124,9 +206,10 @@ func checkAndSave(request *http.Request) string {
 	// 保存到用户目录
 	err = conf.SaveConfig()
 
-	// 只运行一次
+	// 取消正在进行的更新, 使用新配置只运行一次
 	util.ForceCompareGlobal = true
-	go dns.RunOnce()
+	dns.CancelRunning()
+	go dns.RunOnce(context.Background())
 
 	// 回写错误信息
 	if err != nil {
@@ -134,3 +217,51 @@ func checkAndSave(request *http.Request) string {
 	}
 	return "ok"
 }
+
+// checkURLSources 检查通过接口获取IP时接口地址中的选项
+func checkURLSources(ordinal, addrType, getType, urls string) string {
+	if getType != "url" {
+		return ""
+	}
+	if err := config.CheckURLSources(urls); err != nil {
+		return util.LogStr("第 %s 个配置的%s接口不正确: %s", ordinal, addrType, err)
+	}
+	return ""
+}
+
+// checkDNSQuery 检查通过DNS查询获取IP时的查询模板
+func checkDNSQuery(ordinal, addrType, getType, query string) string {
+	if getType != "dns" {
+		return ""
+	}
+	if err := util.CheckMyIPQuery(query); err != nil {
+		return util.LogStr("第 %s 个配置的%sDNS查询不正确: %s", ordinal, addrType, err)
+	}
+	return ""
+}
+
+// checkFallback 检查备用地址, 必须是对应地址族的IP
+func checkFallback(ordinal, addrType, fallback string) string {
+	if fallback == "" {
+		return util.LogStr("第 %s 个配置的%s备用地址不能为空", ordinal, addrType)
+	}
+	ip := net.ParseIP(fallback)
+	if ip == nil || (ip.To4() != nil) != (addrType == "IPv4") {
+		return util.LogStr("第 %s 个配置的%s备用地址 %s 无效", ordinal, addrType, fallback)
+	}
+	return ""
+}
+
+// checkURLQuorum 检查通过接口获取IP时的接口一致数量, 不能超过接口的数量
+func checkURLQuorum(ordinal, addrType, getType, urls, quorum string) string {
+	if getType != "url" || quorum == "" {
+		return ""
+	}
+	if n, err := strconv.Atoi(quorum); err != nil || n < 0 {
+		return util.LogStr("第 %s 个配置的%s接口一致数量 %s 无效", ordinal, addrType, quorum)
+	}
+	if n, count := config.GetURLQuorum(quorum), len(config.SplitURLSources(urls)); n > count {
+		return util.LogStr("第 %s 个配置的%s接口一致数量 %d 大于接口的数量 %d", ordinal, addrType, n, count)
+	}
+	return ""
+}
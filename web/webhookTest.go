@@ -50,5 +50,6 @@ func WebhookTest(writer http.ResponseWriter, request *http.Request) {
 		},
 	}
 
-	config.ExecWebhook(fakeDomains, fakeConfig)
+	// 测试时单独计算失败次数, 不影响正在运行的配置
+	config.ExecWebhook(fakeDomains, fakeConfig, "webhookTest")
 }
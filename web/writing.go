@@ -8,8 +8,10 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/jeessy2/ddns-go/v6/config"
+	"github.com/jeessy2/ddns-go/v6/dns"
 )
 
 //go:embed writing.html
@@ -19,26 +21,69 @@ const VersionEnv = "DDNS_GO_VERSION"
 
 // js中的dns配置
 type dnsConf4JS struct {
-	Name             string
-	DnsName          string
-	DnsID            string
-	DnsSecret        string
-	DnsExtParam      string
-	TTL              string
-	Ipv4Enable       bool
-	Ipv4GetType      string
-	Ipv4Url          string
-	Ipv4NetInterface string
-	Ipv4Cmd          string
-	Ipv4Domains      string
-	Ipv6Enable       bool
-	Ipv6GetType      string
-	Ipv6Url          string
-	Ipv6NetInterface string
-	Ipv6Cmd          string
-	Ipv6Reg          string
-	Ipv6Domains      string
-	HttpInterface    string
+	Name              string
+	DnsName           string
+	DnsID             string
+	DnsSecret         string
+	DnsExtParam       string
+	TTL               string
+	Ipv4Enable        bool
+	Ipv4GetType       string
+	Ipv4Url           string
+	Ipv4UrlQuorum     string
+	Ipv4NetInterface  string
+	Ipv4Cmd           string
+	Ipv4Stun          string
+	Ipv4DNSQuery      string
+	Ipv4Domains       string
+	Ipv4Fallback      string
+	Ipv4MultiAddr     bool
+	Ipv6Enable        bool
+	Ipv6GetType       string
+	Ipv6Url           string
+	Ipv6UrlQuorum     string
+	Ipv6NetInterface  string
+	Ipv6Cmd           string
+	Ipv6Stun          string
+	Ipv6DNSQuery      string
+	FritzboxURL       string
+	FritzboxUsername  string
+	FritzboxPassword  string
+	Ipv6Reg           string
+	Ipv6Select        string
+	Ipv6Domains       string
+	Ipv6Fallback      string
+	Ipv6MultiAddr     bool
+	HttpInterface     string
+	Timeout           string
+	StaleTimes        string
+	StaleAction       string
+	StableTimes       string
+	StableSeconds     string
+	Schedule          string
+	VerifyPublish     bool
+	Precheck          bool
+	PrecheckResolver  string
+	AddrFilterPrivate string
+	AddrFilterCGNAT   string
+	AddrFilterAllow   string
+	AddrFilterDeny    string
+	// NextRun 下次计划运行时间, 只用于显示
+	NextRun string
+	// PausedUntil 认证失败暂停更新的截止时间, 只用于显示
+	PausedUntil string
+	// AddrStats IPv4/IPv6地址变化的统计, 只用于显示, 没有变化时为零值
+	AddrStats [2]addrStats4JS
+}
+
+// addrStats4JS 最近 24 小时和 7 天内地址改变和抖动的次数
+type addrStats4JS struct {
+	Type       string
+	Changes24h int
+	Changes7d  int
+	Flaps24h   int
+	Flaps7d    int
+	LastChange string
 }
 
 // Writing 填写信息
@@ -104,32 +149,90 @@ func getDnsConfStr(dnsConf []config.DnsConfig) string {
 		// 已存在配置文件，隐藏真实的ID、Secret
 		idHide, secretHide := getHideIDSecret(&conf)
 		dnsConfArray = append(dnsConfArray, dnsConf4JS{
-			Name:             conf.Name,
-			DnsName:          conf.DNS.Name,
-			DnsID:            idHide,
-			DnsSecret:        secretHide,
-			DnsExtParam:      conf.DNS.ExtParam,
-			TTL:              conf.TTL,
-			Ipv4Enable:       conf.Ipv4.Enable,
-			Ipv4GetType:      conf.Ipv4.GetType,
-			Ipv4Url:          conf.Ipv4.URL,
-			Ipv4NetInterface: conf.Ipv4.NetInterface,
-			Ipv4Cmd:          conf.Ipv4.Cmd,
-			Ipv4Domains:      strings.Join(conf.Ipv4.Domains, "\r\n"),
-			Ipv6Enable:       conf.Ipv6.Enable,
-			Ipv6GetType:      conf.Ipv6.GetType,
-			Ipv6Url:          conf.Ipv6.URL,
-			Ipv6NetInterface: conf.Ipv6.NetInterface,
-			Ipv6Cmd:          conf.Ipv6.Cmd,
-			Ipv6Reg:          conf.Ipv6.Ipv6Reg,
-			Ipv6Domains:      strings.Join(conf.Ipv6.Domains, "\r\n"),
-			HttpInterface:    conf.HttpInterface,
+			Name:              conf.Name,
+			DnsName:           conf.DNS.Name,
+			DnsID:             idHide,
+			DnsSecret:         secretHide,
+			DnsExtParam:       conf.DNS.ExtParam,
+			TTL:               conf.TTL,
+			Ipv4Enable:        conf.Ipv4.Enable,
+			Ipv4GetType:       conf.Ipv4.GetType,
+			Ipv4Url:           conf.Ipv4.URL,
+			Ipv4UrlQuorum:     conf.Ipv4.URLQuorum,
+			Ipv4NetInterface:  conf.Ipv4.NetInterface,
+			Ipv4Cmd:           conf.Ipv4.Cmd,
+			Ipv4Stun:          conf.Ipv4.Stun,
+			Ipv4DNSQuery:      conf.Ipv4.DNSQuery,
+			Ipv4Domains:       strings.Join(conf.Ipv4.Domains, "\r\n"),
+			Ipv4Fallback:      conf.Ipv4.Fallback,
+			Ipv4MultiAddr:     conf.Ipv4.MultiAddr,
+			Ipv6Enable:        conf.Ipv6.Enable,
+			Ipv6GetType:       conf.Ipv6.GetType,
+			Ipv6Url:           conf.Ipv6.URL,
+			Ipv6UrlQuorum:     conf.Ipv6.URLQuorum,
+			Ipv6NetInterface:  conf.Ipv6.NetInterface,
+			Ipv6Cmd:           conf.Ipv6.Cmd,
+			Ipv6Stun:          conf.Ipv6.Stun,
+			Ipv6DNSQuery:      conf.Ipv6.DNSQuery,
+			FritzboxURL:       conf.Fritzbox.URL,
+			FritzboxUsername:  conf.Fritzbox.Username,
+			FritzboxPassword:  hidePassword(conf.Fritzbox.Password),
+			Ipv6Reg:           conf.Ipv6.Ipv6Reg,
+			Ipv6Select:        conf.Ipv6.Select,
+			Ipv6Domains:       strings.Join(conf.Ipv6.Domains, "\r\n"),
+			Ipv6Fallback:      conf.Ipv6.Fallback,
+			Ipv6MultiAddr:     conf.Ipv6.MultiAddr,
+			HttpInterface:     conf.HttpInterface,
+			Timeout:           conf.Timeout,
+			StaleTimes:        conf.StaleTimes,
+			StaleAction:       conf.StaleAction,
+			StableTimes:       conf.StableTimes,
+			StableSeconds:     conf.StableSeconds,
+			Schedule:          conf.Schedule,
+			VerifyPublish:     conf.VerifyPublish,
+			Precheck:          conf.Precheck,
+			PrecheckResolver:  conf.PrecheckResolver,
+			AddrFilterPrivate: conf.AddrFilter.Private,
+			AddrFilterCGNAT:   conf.AddrFilter.CGNAT,
+			AddrFilterAllow:   conf.AddrFilter.Allow,
+			AddrFilterDeny:    conf.AddrFilter.Deny,
+			NextRun:           formatTime(dns.NextRun(&conf)),
+			PausedUntil:       formatTime(dns.PausedUntil(&conf)),
+			AddrStats:         getAddrStats(&conf),
 		})
 	}
 	byt, _ := json.Marshal(dnsConfArray)
 	return string(byt)
 }
 
+// getAddrStats IPv4/IPv6地址变化的统计
+func getAddrStats(conf *config.DnsConfig) (result [2]addrStats4JS) {
+	stats := dns.GetAddrStats(conf, time.Now())
+	for i, typ := range [2]string{"IPv4", "IPv6"} {
+		s := stats[i]
+		if s.Changes7d == 0 && s.Flaps7d == 0 {
+			continue
+		}
+		result[i] = addrStats4JS{
+			Type:       typ,
+			Changes24h: s.Changes24h,
+			Changes7d:  s.Changes7d,
+			Flaps24h:   s.Flaps24h,
+			Flaps7d:    s.Flaps7d,
+			LastChange: formatTime(s.LastChange),
+		}
+	}
+	return
+}
+
+// formatTime 格式化时间, 零值时为空
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateTime)
+}
+
 // 显示的数量
 const displayCount int = 3
 
@@ -147,3 +250,8 @@ func getHideIDSecret(conf *config.DnsConfig) (idHide string, secretHide string)
 	}
 	return
 }
+
+// hidePassword 密码只显示为星号
+func hidePassword(password string) string {
+	return strings.Repeat("*", len(password))
+}